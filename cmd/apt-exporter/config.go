@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ncecere/apt-exporter/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// configUsage describes the "config" subcommands.
+const configUsage = `Usage:
+  apt-exporter config check [flags]
+  apt-exporter config dump [flags]
+
+check validates the configuration, exiting with status 1 if it is invalid.
+dump prints the effective configuration: the defaults merged with the
+configuration file, environment variables and flags, with secrets redacted.
+
+Both accept the same -config flag and configuration flags as the exporter.
+`
+
+// runConfig implements the "config" subcommand.
+func runConfig(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, configUsage)
+		return 2
+	}
+
+	switch args[0] {
+	case "check":
+		return runConfigCommand("config check", args[1:], func(cfg *config.Config, path string) int {
+			if path == "" {
+				fmt.Println("Configuration is valid (no configuration file)")
+			} else {
+				fmt.Printf("Configuration %s is valid\n", path)
+			}
+			return 0
+		})
+	case "dump":
+		return runConfigCommand("config dump", args[1:], func(cfg *config.Config, path string) int {
+			data, err := yaml.Marshal(cfg)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to format configuration: %v\n", err)
+				return 1
+			}
+			os.Stdout.Write(data)
+			return 0
+		})
+	case "-h", "-help", "--help", "help":
+		fmt.Fprint(os.Stdout, configUsage)
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown config command %q\n\n%s", args[0], configUsage)
+		return 2
+	}
+}
+
+// runConfigCommand loads the configuration like the exporter does and
+// passes it to run, along with the file it was read from, if any.
+func runConfigCommand(name string, args []string, run func(cfg *config.Config, path string) int) int {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	configPath := fs.String("config", defaultConfigPath, "Path to YAML configuration file (environment variable "+configPathEnv+")")
+	overrides := config.RegisterFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), configUsage+"\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+
+	path := resolveConfigPath(fs, *configPath)
+	cfg, err := config.LoadWithOverrides(path, os.Environ(), overrides)
+	if err != nil {
+		if path != "" {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+		} else {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+		}
+		return 1
+	}
+	return run(cfg, path)
+}
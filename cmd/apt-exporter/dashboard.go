@@ -0,0 +1,83 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ncecere/apt-exporter/internal/dashboard"
+)
+
+// dashboardUsage describes the "dashboard" subcommands.
+const dashboardUsage = `Usage:
+  apt-exporter dashboard generate [flags]
+
+generate writes a Grafana dashboard for the exporter's metrics, with job and
+instance variables and fleet tables such as the hosts by pending security
+updates. The metric names follow metric_prefix and naming from the
+configuration unless -prefix or -naming are given.
+`
+
+// runDashboard implements the "dashboard" subcommand.
+func runDashboard(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, dashboardUsage)
+		return 2
+	}
+
+	switch args[0] {
+	case "generate":
+		return runDashboardGenerate(args[1:])
+	case "-h", "-help", "--help", "help":
+		fmt.Fprint(os.Stdout, dashboardUsage)
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown dashboard command %q\n\n%s", args[0], dashboardUsage)
+		return 2
+	}
+}
+
+// runDashboardGenerate implements "dashboard generate".
+func runDashboardGenerate(args []string) int {
+	fs := flag.NewFlagSet("dashboard generate", flag.ContinueOnError)
+	configPath := fs.String("config", defaultConfigPath, "Path to YAML configuration file read for metric_prefix and naming (environment variable "+configPathEnv+")")
+	prefix := fs.String("prefix", "", "Metric prefix (default: metric_prefix from the configuration)")
+	naming := fs.String("naming", "", "Metric naming: v1, v2 or dual (default: naming from the configuration)")
+	title := fs.String("title", dashboard.DefaultTitle, "Title of the dashboard")
+	uid := fs.String("uid", dashboard.DefaultUID, "UID of the dashboard")
+	output := fs.String("output", "-", "File to write the dashboard to, - for stdout")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), dashboardUsage+"\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+
+	metricPrefix, metricNaming, err := resolveNaming(fs, *configPath, *prefix, *naming)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+
+	d := dashboard.Generate(dashboard.Options{
+		Prefix: metricPrefix,
+		Naming: metricNaming,
+		Title:  *title,
+		UID:    *uid,
+	})
+
+	err = writeOutput(*output, func(w io.Writer) error {
+		return dashboard.Write(w, d)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write dashboard: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ncecere/apt-exporter/internal/apt"
+	"github.com/ncecere/apt-exporter/internal/image"
+	"github.com/ncecere/apt-exporter/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+)
+
+// runImageScan implements the "image-scan" subcommand, which writes the
+// metrics of an image tarball: its packages and the updates it is behind.
+func runImageScan(args []string) int {
+	fs := flag.NewFlagSet("image-scan", flag.ContinueOnError)
+	configPath := fs.String("config", defaultConfigPath, "Path to YAML configuration file read for metric_prefix and naming (environment variable "+configPathEnv+")")
+	prefix := fs.String("prefix", "", "Metric prefix (default: metric_prefix from the configuration)")
+	naming := fs.String("naming", "", "Metric naming: v1, v2 or dual (default: naming from the configuration)")
+	listsDir := fs.String("lists-dir", "", "Directory of Packages indices to determine pending updates, e.g. /var/lib/apt/lists")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: apt-exporter image-scan [flags] <image.tar>\n\n"+
+			"Write the metrics of the installed packages of a docker save or OCI layout\n"+
+			"tarball in the Prometheus text format, named as the exporter names them.\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	metricPrefix, metricNaming, err := resolveNaming(fs, *configPath, *prefix, *naming)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+
+	img, err := image.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+
+	var idx *apt.Index
+	if *listsDir != "" {
+		idx, err = apt.ReadIndexDir(*listsDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read package indices: %v\n", err)
+			return 1
+		}
+	}
+
+	// Every series is labeled with the image it describes
+	name := img.Reference
+	if name == "" {
+		name = fs.Arg(0)
+	}
+	backend := metrics.NewPrometheusBackend()
+	m := metrics.NewMetricsWithNaming(metricPrefix, metricNaming, metrics.WithConstLabels(backend, map[string]string{"image": name}))
+	img.SetMetrics(m, idx)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(backend.Collectors()...)
+	if err := writeImageMetrics(registry, image.Metrics(metrics.NewDescribedMetrics(metricPrefix, metricNaming), idx != nil)); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write metrics: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// writeImageMetrics writes the given described metrics, as gathered from g,
+// to stdout in the Prometheus text format. Metrics that do not apply to an
+// image are left out rather than reported as 0.
+func writeImageMetrics(g prometheus.Gatherer, described []any) error {
+	names := make(map[string]bool)
+	for _, metric := range described {
+		for _, desc := range metrics.DescsOf(metric) {
+			names[desc.Name] = true
+		}
+	}
+
+	families, err := g.Gather()
+	if err != nil {
+		return err
+	}
+	for _, mf := range families {
+		if !names[mf.GetName()] {
+			continue
+		}
+		if _, err := expfmt.MetricFamilyToText(os.Stdout, mf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -2,22 +2,38 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
-	"log"
+	"io/fs"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/ncecere/apt-exporter/internal/collector"
 	"github.com/ncecere/apt-exporter/internal/config"
+	"github.com/ncecere/apt-exporter/internal/logging"
 	"github.com/ncecere/apt-exporter/internal/metrics"
+	"github.com/ncecere/apt-exporter/internal/otlp"
+	"github.com/ncecere/apt-exporter/internal/pushgateway"
+	"github.com/ncecere/apt-exporter/internal/remotewrite"
+	"github.com/ncecere/apt-exporter/internal/server"
+	"github.com/ncecere/apt-exporter/internal/systemd"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 )
 
+// defaultConfigPath is loaded if present when no configuration file is given.
+const defaultConfigPath = "config.yml"
+
+// configPathEnv names the environment variable giving the configuration file.
+const configPathEnv = config.EnvPrefix + "CONFIG"
+
 // Version information set by build flags
 var (
 	version = "dev"
@@ -26,10 +42,30 @@ var (
 )
 
 func main() {
-	// Parse command-line flags
-	configPath := flag.String("config", "config.yml", "Path to YAML configuration file")
+	// Run subcommands, which exit when done
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "sbom":
+			os.Exit(runSBOM(os.Args[2:]))
+		case "snapshot":
+			os.Exit(runSnapshot(os.Args[2:]))
+		case "image-scan":
+			os.Exit(runImageScan(os.Args[2:]))
+		case "config":
+			os.Exit(runConfig(os.Args[2:]))
+		case "rules":
+			os.Exit(runRules(os.Args[2:]))
+		case "dashboard":
+			os.Exit(runDashboard(os.Args[2:]))
+		}
+	}
+
+	// Parse command-line flags, including one for every configuration field
+	configPath := flag.String("config", defaultConfigPath, "Path to YAML configuration file (environment variable "+configPathEnv+")")
 	showVersion := flag.Bool("version", false, "Show version information")
 	skipPathValidation := flag.Bool("skip-path-validation", false, "Skip validation of file paths (useful for testing)")
+	rootDir := flag.String("root", "", "Inspect the root filesystem at this path instead of the host (overrides root_dir)")
+	overrides := config.RegisterFlags(flag.CommandLine)
 	flag.Parse()
 
 	// Show version information if requested
@@ -38,46 +74,91 @@ func main() {
 		os.Exit(0)
 	}
 
-	// Set up logging
-	logger := log.New(os.Stdout, "apt-exporter: ", log.LstdFlags)
-	logger.Printf("Starting APT exporter version %s", version)
+	// Log at info level until the configuration is loaded
+	logger := logging.New(os.Stdout, "info", "text")
+	logger.Info("Starting APT exporter", "version", version)
 
-	// Load configuration
-	cfg, err := config.Load(*configPath)
+	// Load configuration from the file, environment and flags
+	path := resolveConfigPath(flag.CommandLine, *configPath)
+	cfg, err := config.LoadWithOverrides(path, os.Environ(), overrides)
 	if err != nil {
-		logger.Fatalf("Failed to load configuration: %v", err)
+		fatal(logger, "Failed to load configuration", "path", path, "error", err)
+	}
+
+	// Switch to the configured log level and format
+	logger = logging.New(os.Stdout, cfg.LogLevel, cfg.LogFormat)
+	slog.SetDefault(logger)
+	if path != "" {
+		logger.Info("Configuration loaded", "path", path)
+	} else {
+		logger.Info("No configuration file found, using defaults, environment and flags")
+	}
+
+	// The command line takes precedence over the configured root filesystem
+	if *rootDir != "" {
+		abs, err := filepath.Abs(*rootDir)
+		if err != nil {
+			fatal(logger, "Failed to resolve root directory", "path", *rootDir, "error", err)
+		}
+		cfg.RootDir = abs
+	}
+	if cfg.Rebased() {
+		logger.Info("Inspecting another root filesystem; apt-check will not be run", "root_dir", cfg.RootDir)
 	}
-	logger.Printf("Configuration loaded from %s", *configPath)
 
 	// Validate file paths if not skipped
 	if !*skipPathValidation {
 		if err := cfg.ValidateFilePaths(); err != nil {
-			logger.Printf("Warning: %v", err)
-			logger.Println("Continuing anyway, but some metrics may not be collected correctly")
+			logger.Warn("Continuing anyway, but some metrics may not be collected correctly", "error", err)
 		}
 	}
 
-	// Configure logging level
-	configureLogging(cfg.LogLevel, logger)
+	labels, err := cfg.ConstLabels()
+	if err != nil {
+		logger.Warn("Some auto labels could not be determined", "error", err)
+	}
 
-	// Create a custom registry that doesn't include Go runtime metrics
+	// Create a custom registry that only includes the Go runtime and process
+	// metrics when they are enabled
 	registry := prometheus.NewRegistry()
+	if cfg.GoMetrics {
+		prometheus.WrapRegistererWith(labels, registry).MustRegister(collectors.NewGoCollector())
+	}
+	if cfg.ProcessMetrics {
+		prometheus.WrapRegistererWith(labels, registry).MustRegister(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	}
 
-	// Initialize metrics with the custom registry (false = don't register with default registry)
-	m := metrics.NewMetrics(cfg.MetricPrefix, false)
-	logger.Printf("Metrics initialized with prefix: %s", cfg.MetricPrefix)
+	// Initialize metrics in the configured backends; the Prometheus metrics are
+	// registered with the custom registry rather than the default one
+	var backends []metrics.Backend
+	if cfg.PrometheusEnabled() {
+		backends = append(backends, metrics.NewPrometheusBackend())
+	}
+	var store *metrics.Store
+	if cfg.OTLPEnabled() {
+		store = metrics.NewStore()
+		backends = append(backends, store)
+	}
+	m := metrics.NewMetricsWithNaming(cfg.MetricPrefix, metrics.Naming(cfg.Naming), metrics.WithConstLabels(metrics.Tee(backends...), labels))
+	m.SetBuildInfo(version, commit, date)
+	logger.Info("Metrics initialized", "prefix", cfg.MetricPrefix, "naming", cfg.Naming, "backend", cfg.MetricsBackend, "labels", labels)
 
 	// Register our metrics with the custom registry
 	for _, collector := range m.GetCollectors() {
-		registry.MustRegister(collector)
+		if err := registry.Register(collector); err != nil {
+			fatal(logger, "Failed to register metrics", "error", err)
+		}
 	}
 
 	// Create collector
-	c := collector.New(cfg, m)
+	c := collector.New(cfg, m, logger)
 
-	// Set up HTTP server for metrics endpoint with the custom registry
-	http.Handle(cfg.MetricsEndpoint, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
-	logger.Printf("Metrics endpoint registered at %s (without Go runtime metrics)", cfg.MetricsEndpoint)
+	// Set up HTTP handlers for the metrics endpoint (with the custom registry) and the API
+	build := server.BuildInfo{Version: version, Commit: commit, Date: date}
+	srv := server.New(cfg, c, registry, build, logger)
+	if cfg.PrometheusEnabled() {
+		logger.Info("Metrics endpoint registered", "path", cfg.MetricsEndpoint, "go_metrics", cfg.GoMetrics, "process_metrics", cfg.ProcessMetrics)
+	}
 
 	// Create a context that will be canceled on SIGINT or SIGTERM
 	ctx, cancel := context.WithCancel(context.Background())
@@ -88,55 +169,152 @@ func main() {
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
 		sig := <-sigChan
-		logger.Printf("Received signal: %v", sig)
+		logger.Info("Received signal", "signal", sig.String())
 		cancel()
 	}()
 
+	// Push metrics after each collection if a Pushgateway is configured
+	pushDone := make(chan struct{})
+	if cfg.Pushgateway.URL != "" {
+		pusher := pushgateway.New(cfg.Pushgateway, registry, logger)
+		c.OnCollect(func(collector.Status) { pusher.Trigger() })
+		go func() {
+			pusher.Run(ctx)
+			close(pushDone)
+		}()
+		logger.Info("Pushing metrics to Pushgateway", "url", cfg.Pushgateway.URL, "job", cfg.Pushgateway.Job)
+	} else {
+		close(pushDone)
+	}
+
+	// Send metrics after each collection if a remote-write endpoint is configured
+	if cfg.RemoteWrite.URL != "" {
+		// The request counters join the apt namespace with v2 names
+		prefix := cfg.MetricPrefix
+		if cfg.Naming == config.NamingV2 {
+			prefix = "apt"
+		}
+		sender, err := remotewrite.New(cfg.RemoteWrite, prefix, registry, logger)
+		if err != nil {
+			fatal(logger, "Failed to set up remote write", "error", err)
+		}
+		prometheus.WrapRegistererWith(labels, registry).MustRegister(sender.Collectors()...)
+		c.OnCollect(func(collector.Status) { sender.Trigger() })
+		go sender.Run(ctx)
+		logger.Info("Sending metrics to remote-write endpoint", "url", cfg.RemoteWrite.URL)
+	}
+
+	// Export metrics after each collection if the OTLP backend is enabled
+	if store != nil {
+		resource := otlp.Resource(cfg.OTLP, cfg.Path(cfg.OSReleasePath), version)
+		exporter := otlp.New(cfg.OTLP, store, resource, version, logger)
+		c.OnCollect(func(collector.Status) { exporter.Trigger() })
+		go exporter.Run(ctx)
+		logger.Info("Exporting metrics over OTLP", "endpoint", cfg.OTLP.Endpoint)
+	}
+
+	// Report readiness after the first collection and the outcome of each
+	// one when running as a Type=notify systemd service
+	var readyOnce sync.Once
+	c.OnCollect(func(status collector.Status) {
+		readyOnce.Do(func() { notify(logger, systemd.Ready) })
+		notify(logger, systemd.Status(status.Summary()))
+	})
+	if interval, ok := systemd.WatchdogInterval(); ok {
+		c.SetWatchdog(interval, func() { notify(logger, systemd.Watchdog) })
+		logger.Info("Pinging systemd watchdog", "interval", interval.String())
+	}
+
+	// Take over sockets passed by systemd socket activation, before any
+	// command is run that could inherit them
+	listeners, err := systemd.Listeners()
+	if err != nil {
+		fatal(logger, "Failed to use sockets passed by systemd", "error", err)
+	}
+
 	// Start metrics collection in a goroutine
 	go c.Start(ctx)
 
 	// Start HTTP server
-	server := &http.Server{
-		Addr: cfg.ListenAddress,
+	httpServer := &http.Server{
+		Addr:    cfg.ListenAddress,
+		Handler: srv,
 	}
 
-	// Start server in a goroutine
-	go func() {
-		logger.Printf("Starting metrics server on %s", cfg.ListenAddress)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.Fatalf("HTTP server error: %v", err)
+	// Start server in a goroutine, on the passed sockets if socket activated
+	if len(listeners) > 0 {
+		for _, l := range listeners {
+			go func() {
+				logger.Info("Starting metrics server on socket passed by systemd", "address", l.Addr().String())
+				if err := httpServer.Serve(l); err != nil && err != http.ErrServerClosed {
+					fatal(logger, "HTTP server error", "error", err)
+				}
+			}()
 		}
-	}()
+	} else {
+		go func() {
+			logger.Info("Starting metrics server", "address", cfg.ListenAddress)
+			if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				fatal(logger, "HTTP server error", "error", err)
+			}
+		}()
+	}
 
 	// Wait for context cancellation (from signal handler)
 	<-ctx.Done()
-	logger.Println("Shutting down...")
+	logger.Info("Shutting down...")
+	notify(logger, systemd.Stopping)
 
 	// Create a context with timeout for graceful shutdown
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer shutdownCancel()
 
 	// Attempt graceful shutdown
-	if err := server.Shutdown(shutdownCtx); err != nil {
-		logger.Printf("HTTP server shutdown error: %v", err)
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		logger.Error("HTTP server shutdown error", "error", err)
+	}
+
+	// Wait for the Pushgateway group to be deleted, if configured
+	select {
+	case <-pushDone:
+	case <-shutdownCtx.Done():
+		logger.Warn("Timed out waiting for the Pushgateway")
 	}
 
-	logger.Println("APT exporter stopped")
+	logger.Info("APT exporter stopped")
 }
 
-// configureLogging sets up the logger based on the configured log level
-func configureLogging(level string, logger *log.Logger) {
-	// This is a simple implementation that could be expanded
-	// with a more sophisticated logging library
-	switch level {
-	case "debug":
-		logger.SetFlags(log.LstdFlags | log.Lshortfile)
-		logger.Println("Debug logging enabled")
-	case "info":
-		logger.SetFlags(log.LstdFlags)
-	case "warn", "error":
-		// For these levels, we might want to filter out less severe messages
-		// but the standard log package doesn't support this directly
-		logger.SetFlags(log.LstdFlags)
+// resolveConfigPath returns the configuration file to load: the one given
+// by the -config flag of flags or the environment, which must exist, or
+// else the default file if it exists. An empty path means there is no file.
+func resolveConfigPath(flags *flag.FlagSet, flagPath string) string {
+	explicit := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "config" {
+			explicit = true
+		}
+	})
+	if explicit {
+		return flagPath
+	}
+	if path := os.Getenv(configPathEnv); path != "" {
+		return path
+	}
+	if _, err := os.Stat(defaultConfigPath); errors.Is(err, fs.ErrNotExist) {
+		return ""
 	}
+	return defaultConfigPath
+}
+
+// notify sends a state to systemd, if running under it as a notify service.
+func notify(logger *slog.Logger, state string) {
+	if _, err := systemd.Notify(state); err != nil {
+		logger.Warn("Failed to notify systemd", "error", err)
+	}
+}
+
+// fatal logs an error and exits.
+func fatal(logger *slog.Logger, msg string, args ...any) {
+	logger.Error(msg, args...)
+	os.Exit(1)
 }
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/ncecere/apt-exporter/internal/config"
+	"github.com/ncecere/apt-exporter/internal/metrics"
+	"github.com/ncecere/apt-exporter/internal/rules"
+	"github.com/prometheus/common/model"
+)
+
+// rulesUsage describes the "rules" subcommands.
+const rulesUsage = `Usage:
+  apt-exporter rules generate [flags]
+
+generate writes Prometheus recording and alerting rules for the exporter's
+metrics: security updates pending, stale package lists, reboot required,
+failing collections and exporters that are down or absent. The metric names follow
+metric_prefix and naming from the configuration unless -prefix or -naming
+are given.
+`
+
+// runRules implements the "rules" subcommand.
+func runRules(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, rulesUsage)
+		return 2
+	}
+
+	switch args[0] {
+	case "generate":
+		return runRulesGenerate(args[1:])
+	case "-h", "-help", "--help", "help":
+		fmt.Fprint(os.Stdout, rulesUsage)
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown rules command %q\n\n%s", args[0], rulesUsage)
+		return 2
+	}
+}
+
+// runRulesGenerate implements "rules generate".
+func runRulesGenerate(args []string) int {
+	fs := flag.NewFlagSet("rules generate", flag.ContinueOnError)
+	configPath := fs.String("config", defaultConfigPath, "Path to YAML configuration file read for metric_prefix and naming (environment variable "+configPathEnv+")")
+	prefix := fs.String("prefix", "", "Metric prefix (default: metric_prefix from the configuration)")
+	naming := fs.String("naming", "", "Metric naming: v1, v2 or dual (default: naming from the configuration)")
+	threshold := fs.Int("security-threshold", rules.DefaultSecurityThreshold, "Alert when more security updates than this are pending")
+	staleAfter := promDuration(fs, "stale-after", rules.DefaultStaleAfter, "Alert when apt update has not succeeded for this long, e.g. 7d")
+	rebootAfter := promDuration(fs, "reboot-after", rules.DefaultRebootAfter, "Alert when a reboot has been required for this long, e.g. 7d")
+	job := fs.String("job", rules.DefaultJob, "Prometheus job scraping the exporters, used to alert on exporters that are down")
+	format := fs.String("format", rules.FormatRules, "Output format: rules (Prometheus rule file) or prometheusrule (Prometheus Operator resource)")
+	name := fs.String("name", rules.DefaultName, "Name of the PrometheusRule resource")
+	output := fs.String("output", "-", "File to write the rules to, - for stdout")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), rulesUsage+"\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+
+	// Check the format before creating the output file
+	if err := rules.CheckFormat(*format); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 2
+	}
+
+	metricPrefix, metricNaming, err := resolveNaming(fs, *configPath, *prefix, *naming)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+
+	groups := rules.Generate(rules.Options{
+		Prefix:            metricPrefix,
+		Naming:            metricNaming,
+		SecurityThreshold: *threshold,
+		StaleAfter:        *staleAfter,
+		RebootAfter:       *rebootAfter,
+		Job:               *job,
+	})
+
+	err = writeOutput(*output, func(w io.Writer) error {
+		return rules.Write(w, groups, *format, *name)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write rules: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// writeOutput calls write with the file at path, created or truncated, or
+// with stdout if path is "-". Errors closing the file are returned, since
+// they can mean that the output was not written.
+func writeOutput(path string, write func(io.Writer) error) error {
+	if path == "-" {
+		return write(os.Stdout)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// promDuration defines a flag taking a Prometheus duration such as 7d.
+func promDuration(fs *flag.FlagSet, name string, value time.Duration, usage string) *time.Duration {
+	d := value
+	fs.Func(name, fmt.Sprintf("%s (default %s)", usage, model.Duration(value)), func(s string) error {
+		parsed, err := model.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		d = time.Duration(parsed)
+		return nil
+	})
+	return &d
+}
+
+// resolveNaming returns the metric prefix and naming given by the prefix
+// and naming flags, or else by the configuration, so that generated rules,
+// dashboards and image metrics use the names the exporter exposes.
+func resolveNaming(fs *flag.FlagSet, configPath, prefix, naming string) (string, metrics.Naming, error) {
+	path := resolveConfigPath(fs, configPath)
+	cfg, err := config.LoadWithOverrides(path, os.Environ(), nil)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if prefix == "" {
+		prefix = cfg.MetricPrefix
+	}
+	if naming == "" {
+		naming = cfg.Naming
+	}
+	switch naming {
+	case config.NamingV1, config.NamingV2, config.NamingDual:
+		return prefix, metrics.Naming(naming), nil
+	default:
+		return "", "", fmt.Errorf("invalid naming %q (must be one of: v1, v2, dual)", naming)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ncecere/apt-exporter/internal/config"
+	"github.com/ncecere/apt-exporter/internal/rootfs"
+	"github.com/ncecere/apt-exporter/internal/sbom"
+)
+
+// runSBOM implements the "sbom" subcommand, which writes a bill of materials
+// of the installed packages and exits.
+func runSBOM(args []string) int {
+	fs := flag.NewFlagSet("sbom", flag.ContinueOnError)
+	format := fs.String("format", sbom.FormatCycloneDX, "Output format: cyclonedx or spdx")
+	output := fs.String("output", "-", "File to write the SBOM to, - for stdout")
+	statusPath := fs.String("dpkg-status", config.DefaultDpkgStatusPath, "Path to the dpkg status database")
+	osReleasePath := fs.String("os-release", config.DefaultOSReleasePath, "Path to the os-release file")
+	docDir := fs.String("doc-dir", sbom.DefaultDocDir, "Directory holding the packages' copyright files")
+	root := fs.String("root", "", "Root filesystem the paths above are relative to, e.g. an unpacked container image")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: apt-exporter sbom [flags]\n\nWrite a software bill of materials of the installed packages.\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	bom, err := sbom.FromSystem(rootfs.Join(*root, *statusPath), rootfs.Join(*root, *osReleasePath), rootfs.Join(*root, *docDir), version)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to generate SBOM: %v\n", err)
+		return 1
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "-" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to create output file: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err := bom.Write(w, *format); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write SBOM: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ncecere/apt-exporter/internal/config"
+	"github.com/ncecere/apt-exporter/internal/rootfs"
+	"github.com/ncecere/apt-exporter/internal/snapshot"
+)
+
+// snapshotUsage describes the "snapshot" subcommands.
+const snapshotUsage = `Usage:
+  apt-exporter snapshot save [flags] <file>
+  apt-exporter snapshot diff [flags] <a> [<b>]
+
+save writes the installed packages and their versions to file, - for stdout.
+diff lists the packages added, removed, upgraded and downgraded going from
+snapshot a to snapshot b, or to the live system if b is omitted.
+`
+
+// runSnapshot implements the "snapshot" subcommand.
+func runSnapshot(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, snapshotUsage)
+		return 2
+	}
+
+	switch args[0] {
+	case "save":
+		return runSnapshotSave(args[1:])
+	case "diff":
+		return runSnapshotDiff(args[1:])
+	case "-h", "-help", "--help", "help":
+		fmt.Fprint(os.Stdout, snapshotUsage)
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown snapshot command %q\n\n%s", args[0], snapshotUsage)
+		return 2
+	}
+}
+
+// runSnapshotSave writes a snapshot of the live system.
+func runSnapshotSave(args []string) int {
+	fs := flag.NewFlagSet("snapshot save", flag.ContinueOnError)
+	statusPath := fs.String("dpkg-status", config.DefaultDpkgStatusPath, "Path to the dpkg status database")
+	root := fs.String("root", "", "Root filesystem the dpkg status path is relative to")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), snapshotUsage+"\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	s, err := snapshot.FromSystem(rootfs.Join(*root, *statusPath))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create snapshot: %v\n", err)
+		return 1
+	}
+
+	var w io.Writer = os.Stdout
+	if path := fs.Arg(0); path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to create snapshot file: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err := s.Write(w); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write snapshot: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runSnapshotDiff compares two snapshots, or a snapshot and the live system.
+func runSnapshotDiff(args []string) int {
+	fs := flag.NewFlagSet("snapshot diff", flag.ContinueOnError)
+	format := fs.String("format", "text", "Output format: text or json")
+	statusPath := fs.String("dpkg-status", config.DefaultDpkgStatusPath, "Path to the dpkg status database, used when <b> is omitted")
+	root := fs.String("root", "", "Root filesystem the dpkg status path is relative to")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), snapshotUsage+"\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		return 2
+	}
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(os.Stderr, "Unsupported format %q\n", *format)
+		return 2
+	}
+
+	a, err := snapshot.Load(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	var b *snapshot.Snapshot
+	if fs.NArg() == 2 {
+		b, err = snapshot.Load(fs.Arg(1))
+	} else {
+		b, err = snapshot.FromSystem(rootfs.Join(*root, *statusPath))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+
+	changes := snapshot.Diff(a, b)
+	if *format == "json" {
+		if changes == nil {
+			changes = []snapshot.Change{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(changes)
+	} else {
+		err = snapshot.WriteText(os.Stdout, changes)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write diff: %v\n", err)
+		return 1
+	}
+	return 0
+}
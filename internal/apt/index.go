@@ -0,0 +1,172 @@
+// Package apt determines pending updates from the APT package indices, without
+// running apt itself. This works on any root filesystem, e.g. a chroot or an
+// unpacked container image.
+package apt
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ncecere/apt-exporter/internal/dpkg"
+)
+
+// Update is a pending update of an installed package.
+type Update struct {
+	Package          string `json:"package"`
+	Architecture     string `json:"architecture"`
+	InstalledVersion string `json:"installed_version"`
+	CandidateVersion string `json:"candidate_version"`
+	Security         bool   `json:"security"`
+}
+
+// available tracks the newest versions of a package found in the indices.
+type available struct {
+	newest         string
+	newestSecurity string
+}
+
+// Index holds the newest available version of every package in a set of
+// Packages indices. Pinning and phased updates are not taken into account, so
+// the candidate is always the highest version available.
+type Index struct {
+	packages map[string]*available // keyed by name and architecture
+}
+
+// NewIndex creates an empty index.
+func NewIndex() *Index {
+	return &Index{packages: make(map[string]*available)}
+}
+
+// Add records the packages of a Packages index. Packages from security
+// archives are tracked separately so that updates can be classified.
+func (idx *Index) Add(r io.Reader, security bool) error {
+	return dpkg.ReadParagraphs(r, func(p dpkg.Paragraph) error {
+		name, version, arch := p["Package"], p["Version"], p["Architecture"]
+		if name == "" || version == "" {
+			return nil
+		}
+
+		key := name + ":" + arch
+		a, ok := idx.packages[key]
+		if !ok {
+			a = &available{}
+			idx.packages[key] = a
+		}
+		if a.newest == "" || dpkg.CompareVersions(version, a.newest) > 0 {
+			a.newest = version
+		}
+		if security && (a.newestSecurity == "" || dpkg.CompareVersions(version, a.newestSecurity) > 0) {
+			a.newestSecurity = version
+		}
+		return nil
+	})
+}
+
+// Len returns the number of distinct packages in the index.
+func (idx *Index) Len() int {
+	return len(idx.packages)
+}
+
+// PendingUpdates returns the installed packages for which a newer version is
+// available, sorted by package name. An update is a security update if a
+// newer version is available from a security archive, even if the candidate
+// itself comes from another archive.
+func (idx *Index) PendingUpdates(installed []dpkg.Package) []Update {
+	var updates []Update
+	for _, pkg := range installed {
+		a, ok := idx.packages[pkg.Name+":"+pkg.Architecture]
+		if !ok || dpkg.CompareVersions(a.newest, pkg.Version) <= 0 {
+			continue
+		}
+
+		updates = append(updates, Update{
+			Package:          pkg.Name,
+			Architecture:     pkg.Architecture,
+			InstalledVersion: pkg.Version,
+			CandidateVersion: a.newest,
+			Security:         a.newestSecurity != "" && dpkg.CompareVersions(a.newestSecurity, pkg.Version) > 0,
+		})
+	}
+
+	sort.Slice(updates, func(i, j int) bool {
+		if updates[i].Package != updates[j].Package {
+			return updates[i].Package < updates[j].Package
+		}
+		return updates[i].Architecture < updates[j].Architecture
+	})
+	return updates
+}
+
+// IndexFiles returns the Packages indices in an APT lists directory, e.g.
+// /var/lib/apt/lists/archive.ubuntu.com_ubuntu_dists_jammy_main_binary-amd64_Packages.
+// Uncompressed and gzip compressed indices are supported.
+func IndexFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read APT lists directory: %w", err)
+	}
+
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.Type().IsRegular() && (strings.HasSuffix(name, "_Packages") || strings.HasSuffix(name, "_Packages.gz")) {
+			files = append(files, filepath.Join(dir, name))
+		}
+	}
+	return files, nil
+}
+
+// IsSecurityIndex reports whether an index file belongs to a security archive,
+// based on its name, e.g. security.ubuntu.com_ubuntu_dists_jammy-security_main_binary-amd64_Packages
+// or deb.debian.org_debian-security_dists_bookworm-security_main_binary-amd64_Packages.
+func IsSecurityIndex(path string) bool {
+	name := filepath.Base(path)
+	return strings.Contains(name, "-security_") ||
+		strings.Contains(name, "security.debian.org") ||
+		strings.Contains(name, "security.ubuntu.com")
+}
+
+// ReadIndexDir builds an index from all Packages indices in an APT lists directory.
+func ReadIndexDir(dir string) (*Index, error) {
+	files, err := IndexFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	idx := NewIndex()
+	for _, file := range files {
+		if err := idx.addFile(file); err != nil {
+			return nil, err
+		}
+	}
+	return idx, nil
+}
+
+// addFile records the packages of an index file, decompressing it if needed.
+func (idx *Index) addFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open package index: %w", err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("failed to decompress package index %s: %w", path, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	if err := idx.Add(r, IsSecurityIndex(path)); err != nil {
+		return fmt.Errorf("failed to parse package index %s: %w", path, err)
+	}
+	return nil
+}
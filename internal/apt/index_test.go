@@ -0,0 +1,120 @@
+package apt
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ncecere/apt-exporter/internal/dpkg"
+)
+
+const (
+	testMainIndex = `Package: openssl
+Architecture: amd64
+Version: 3.0.2-0ubuntu1
+
+Package: bash
+Architecture: amd64
+Version: 5.1-6ubuntu1
+
+Package: tzdata
+Architecture: all
+Version: 2024a-0ubuntu0.22.04
+`
+	testUpdatesIndex = `Package: openssl
+Architecture: amd64
+Version: 3.0.2-0ubuntu1.15
+
+Package: tzdata
+Architecture: all
+Version: 2024a-0ubuntu0.22.04.1
+`
+	testSecurityIndex = `Package: openssl
+Architecture: amd64
+Version: 3.0.2-0ubuntu1.14
+`
+)
+
+// writeTestLists creates an APT lists directory with main, updates and a gzip compressed security index.
+func writeTestLists(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	plain := map[string]string{
+		"archive.ubuntu.com_ubuntu_dists_jammy_main_binary-amd64_Packages":         testMainIndex,
+		"archive.ubuntu.com_ubuntu_dists_jammy-updates_main_binary-amd64_Packages": testUpdatesIndex,
+		"archive.ubuntu.com_ubuntu_dists_jammy_InRelease":                          "not an index",
+	}
+	for name, content := range plain {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	f, err := os.Create(filepath.Join(dir, "security.ubuntu.com_ubuntu_dists_jammy-security_main_binary-amd64_Packages.gz"))
+	if err != nil {
+		t.Fatalf("Failed to create security index: %v", err)
+	}
+	gz := gzip.NewWriter(f)
+	if _, err := gz.Write([]byte(testSecurityIndex)); err != nil {
+		t.Fatalf("Failed to write security index: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("Failed to close security index: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Failed to close security index: %v", err)
+	}
+
+	return dir
+}
+
+func TestPendingUpdates(t *testing.T) {
+	idx, err := ReadIndexDir(writeTestLists(t))
+	if err != nil {
+		t.Fatalf("ReadIndexDir() returned error: %v", err)
+	}
+	if idx.Len() != 3 {
+		t.Errorf("Expected 3 packages in index, got %d", idx.Len())
+	}
+
+	installed := []dpkg.Package{
+		{Name: "openssl", Architecture: "amd64", Version: "3.0.2-0ubuntu1.10"},
+		{Name: "bash", Architecture: "amd64", Version: "5.1-6ubuntu1"},
+		{Name: "tzdata", Architecture: "all", Version: "2024a-0ubuntu0.22.04"},
+		{Name: "local-only", Architecture: "amd64", Version: "1.0"},
+	}
+
+	updates := idx.PendingUpdates(installed)
+	want := []Update{
+		// The candidate comes from -updates, but a newer version is available from -security
+		{Package: "openssl", Architecture: "amd64", InstalledVersion: "3.0.2-0ubuntu1.10", CandidateVersion: "3.0.2-0ubuntu1.15", Security: true},
+		{Package: "tzdata", Architecture: "all", InstalledVersion: "2024a-0ubuntu0.22.04", CandidateVersion: "2024a-0ubuntu0.22.04.1", Security: false},
+	}
+	if len(updates) != len(want) {
+		t.Fatalf("Expected %d updates, got %d: %+v", len(want), len(updates), updates)
+	}
+	for i := range want {
+		if updates[i] != want[i] {
+			t.Errorf("Update %d = %+v, want %+v", i, updates[i], want[i])
+		}
+	}
+}
+
+func TestIsSecurityIndex(t *testing.T) {
+	tests := map[string]bool{
+		"security.ubuntu.com_ubuntu_dists_jammy-security_main_binary-amd64_Packages":        true,
+		"deb.debian.org_debian-security_dists_bookworm-security_main_binary-amd64_Packages": true,
+		"mirror.example.com_ubuntu_dists_jammy-security_universe_binary-arm64_Packages.gz":  true,
+		"security.debian.org_dists_bullseye_updates_main_binary-amd64_Packages":             true,
+		"archive.ubuntu.com_ubuntu_dists_jammy-updates_main_binary-amd64_Packages":          false,
+		"deb.debian.org_debian_dists_bookworm_main_binary-amd64_Packages":                   false,
+	}
+
+	for name, want := range tests {
+		if got := IsSecurityIndex(name); got != want {
+			t.Errorf("IsSecurityIndex(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
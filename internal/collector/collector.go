@@ -4,37 +4,85 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"log"
+	"io/fs"
+	"log/slog"
 	"os"
 	"os/exec"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/ncecere/apt-exporter/internal/apt"
 	"github.com/ncecere/apt-exporter/internal/config"
 	"github.com/ncecere/apt-exporter/internal/metrics"
+	"github.com/ncecere/apt-exporter/internal/vuln"
 )
 
 // Collector handles the collection of APT metrics.
 type Collector struct {
 	cfg     *config.Config
 	metrics *metrics.Metrics
-	logger  *log.Logger
-}
+	logger  *slog.Logger
+
+	// mu serializes collection cycles
+	mu sync.Mutex
+	// result is the check currently being run, used to record its values
+	result *CheckResult
+
+	// statusMu guards lastStatus, lastSuccess and hooks
+	statusMu    sync.RWMutex
+	lastStatus  *Status
+	lastSuccess time.Time
+	hooks       []func(Status)
+
+	// securityFeed caches the parsed security feed until it changes on disk
+	securityFeed        *vuln.Database
+	securityFeedModTime time.Time
+
+	// packageIndex caches the APT lists index until the lists change
+	packageIndex          *apt.Index
+	packageIndexSignature string
+	// pending holds the first-seen timestamps of pending updates
+	pending *pendingState
 
-// New creates a new Collector instance.
-func New(cfg *config.Config, metrics *metrics.Metrics) *Collector {
-	// Create a logger with appropriate prefix
-	logger := log.New(os.Stdout, "apt-collector: ", log.LstdFlags)
+	// procPath is the procfs mount used to inspect lock holders
+	procPath string
+	// lockSeries, vulnerabilitySeries and inventorySeries hold the series set
+	// by the last checks
+	lockSeries          *lockSeries
+	vulnerabilitySeries *vulnerabilitySeries
+	inventorySeries     *inventorySeries
 
+	// watchdog is pinged from the collection loop every watchdogInterval
+	watchdog         func()
+	watchdogInterval time.Duration
+}
+
+// New creates a new Collector instance logging to logger.
+func New(cfg *config.Config, metrics *metrics.Metrics, logger *slog.Logger) *Collector {
 	return &Collector{
-		cfg:     cfg,
-		metrics: metrics,
-		logger:  logger,
+		cfg:                 cfg,
+		metrics:             metrics,
+		logger:              logger.With("component", "collector"),
+		procPath:            "/proc",
+		lockSeries:          newLockSeries(metrics),
+		vulnerabilitySeries: newVulnerabilitySeries(metrics),
+		inventorySeries:     newInventorySeries(metrics),
 	}
 }
 
+// SetWatchdog makes Start call ping every interval from its collection
+// loop, so that the pings stop while a collection hangs. It must be called
+// before Start.
+func (c *Collector) SetWatchdog(interval time.Duration, ping func()) {
+	c.watchdogInterval = interval
+	c.watchdog = ping
+}
+
 // Start begins periodic collection of metrics.
+// Unless disabled, changes to the watched APT and dpkg state files trigger an
+// additional debounced collection, with the ticker kept as a fallback.
 func (c *Collector) Start(ctx context.Context) {
 	// Collect metrics immediately on startup
 	c.collect(ctx)
@@ -43,53 +91,138 @@ func (c *Collector) Start(ctx context.Context) {
 	ticker := time.NewTicker(time.Duration(c.cfg.CheckIntervalSeconds) * time.Second)
 	defer ticker.Stop()
 
+	// Set up file watching for change-triggered collection
+	var changes <-chan struct{}
+	if !c.cfg.DisableFileWatch {
+		w, err := c.newFileWatcher()
+		if err != nil {
+			c.logger.Warn("File watching disabled", "error", err)
+		} else {
+			defer w.Close()
+			changes = w.Events()
+		}
+	}
+
+	debounce := time.Duration(c.cfg.WatchDebounceSeconds) * time.Second
+	debounceTimer := time.NewTimer(debounce)
+	debounceTimer.Stop()
+	defer debounceTimer.Stop()
+
+	var watchdogTick <-chan time.Time
+	if c.watchdog != nil {
+		watchdogTicker := time.NewTicker(c.watchdogInterval)
+		defer watchdogTicker.Stop()
+		watchdogTick = watchdogTicker.C
+		c.watchdog()
+	}
+
 	for {
 		select {
 		case <-ticker.C:
 			c.collect(ctx)
+		case <-watchdogTick:
+			c.watchdog()
+		case <-changes:
+			// Restart the debounce period so a burst of changes results in one
+			// collection; without a debounce period the timer fires at once
+			debounceTimer.Reset(debounce)
+		case <-debounceTimer.C:
+			c.logger.Info("Watched files changed, collecting APT metrics")
+			c.collect(ctx)
 		case <-ctx.Done():
-			c.logger.Println("Stopping metrics collection")
+			c.logger.Info("Stopping metrics collection")
 			return
 		}
 	}
 }
 
+// newFileWatcher watches the files whose changes affect the collected metrics.
+// Paths that cannot be watched are logged and skipped.
+func (c *Collector) newFileWatcher() (*watcher, error) {
+	w, err := newWatcher(c.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range []string{c.cfg.UpdateStampPath, c.cfg.RebootRequiredFile, c.cfg.DpkgStatusPath} {
+		if err := w.AddFile(c.cfg.Path(path)); err != nil {
+			c.logger.Warn("Cannot watch file", "path", c.cfg.Path(path), "error", err)
+		}
+	}
+	if err := w.AddDir(c.cfg.Path(c.cfg.AptListsDir)); err != nil {
+		c.logger.Warn("Cannot watch directory", "path", c.cfg.Path(c.cfg.AptListsDir), "error", err)
+	}
+
+	return w, nil
+}
+
+// check is a single named step of a collection cycle.
+type check struct {
+	name        string
+	description string
+	run         func() error
+}
+
 // collect gathers all metrics and updates the Prometheus gauges.
-func (c *Collector) collect(ctx context.Context) {
-	c.logger.Println("Collecting APT metrics")
+// Cycles are serialized, so it is safe to call from several goroutines.
+func (c *Collector) collect(ctx context.Context) Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.logger.Info("Collecting APT metrics")
 	startTime := time.Now()
 
 	// Create a context with timeout for running external commands
 	cmdCtx, cancel := context.WithTimeout(ctx, time.Duration(c.cfg.CommandTimeoutSeconds)*time.Second)
 	defer cancel()
 
-	// Track collection success
-	success := true
-
-	// Collect metrics and track success
-	if err := c.checkUpdates(cmdCtx); err != nil {
-		c.logger.Printf("Error checking updates: %v", err)
-		success = false
+	checks := []check{
+		{"updates", "updates", func() error { return c.checkUpdates(cmdCtx) }},
+		{"last_update", "last update time", c.checkLastUpdateTime},
+		{"reboot_required", "reboot required", c.checkRebootRequired},
+		{"dpkg_locks", "dpkg locks", c.checkDpkgLocks},
+		{"inventory", "package inventory", c.checkInventory},
 	}
-
-	if err := c.checkLastUpdateTime(); err != nil {
-		c.logger.Printf("Error checking last update time: %v", err)
-		success = false
+	if c.cfg.SecurityFeed.Path != "" {
+		checks = append(checks, check{"vulnerabilities", "vulnerabilities", c.checkVulnerabilities})
+	}
+	if c.cfg.StateDir != "" {
+		checks = append(checks, check{"pending_updates", "pending update ages", c.checkPendingUpdates})
 	}
 
-	if err := c.checkRebootRequired(); err != nil {
-		c.logger.Printf("Error checking reboot required: %v", err)
-		success = false
+	// Collect metrics and track success
+	status := Status{Timestamp: startTime, Success: true}
+	for _, check := range checks {
+		// Every check's error counter is exposed, starting at 0
+		errCount := c.metrics.CollectionErrors.WithLabelValues(check.name)
+		result, err := c.runCheck(check.name, check.run)
+		if result.Unsupported {
+			c.logger.Info("Skipping "+check.description+" check", "check", check.name, "reason", result.Error)
+		} else if !result.Success {
+			c.logger.Error("Error checking "+check.description, append([]any{"check", check.name, "error", err}, errorFields(err)...)...)
+			errCount.Inc()
+			status.Success = false
+		}
+		status.Checks = append(status.Checks, result)
 	}
+	status.DurationSeconds = time.Since(startTime).Seconds()
 
 	// Update collection metrics
-	c.metrics.CollectionSuccess.Set(boolToFloat64(success))
-	c.metrics.CollectionDurationSeconds.Set(time.Since(startTime).Seconds())
+	c.metrics.CollectionSuccess.Set(boolToFloat64(status.Success))
+	c.metrics.CollectionDurationSeconds.Observe(status.DurationSeconds)
 	c.metrics.LastCollectionTimestamp.Set(float64(time.Now().Unix()))
+
+	c.setLastStatus(status)
+	return status
 }
 
 // checkUpdates collects information about available updates.
+// apt-check inspects the system it runs on, so it is unsupported for other root filesystems.
 func (c *Collector) checkUpdates(ctx context.Context) error {
+	if c.cfg.Rebased() {
+		return fmt.Errorf("%w: apt-check cannot inspect root_dir %s", errUnsupported, c.cfg.RootDir)
+	}
+
 	if _, err := os.Stat(c.cfg.AptCheckPath); err != nil {
 		c.metrics.UpdatesAvailable.Set(0)
 		c.metrics.SecurityUpdatesAvailable.Set(0)
@@ -113,24 +246,28 @@ func (c *Collector) checkUpdates(ctx context.Context) error {
 	if err != nil && len(out) == 0 {
 		c.metrics.UpdatesAvailable.Set(0)
 		c.metrics.SecurityUpdatesAvailable.Set(0)
-		return fmt.Errorf("error running apt-check: %w", err)
+		return fmt.Errorf("error running apt-check: %w", &fs.PathError{Op: "run", Path: c.cfg.AptCheckPath, Err: err})
 	}
 
 	// Trim any whitespace and check if output is empty
 	trimmedOutput := strings.TrimSpace(string(out))
 	if trimmedOutput == "" {
-		c.logger.Println("apt-check returned empty output, assuming 0 updates")
+		c.logger.Warn("apt-check returned empty output, assuming 0 updates", "path", c.cfg.AptCheckPath)
 		c.metrics.UpdatesAvailable.Set(0)
 		c.metrics.SecurityUpdatesAvailable.Set(0)
+		c.record("updates_available", 0)
+		c.record("security_updates_available", 0)
 		return nil
 	}
 
 	// Split by semicolon and validate format
 	parts := strings.Split(trimmedOutput, ";")
 	if len(parts) < 2 {
-		c.logger.Printf("apt-check returned unexpected format: %q, assuming 0 updates", trimmedOutput)
+		c.logger.Warn("apt-check returned unexpected format, assuming 0 updates", "path", c.cfg.AptCheckPath, "output", trimmedOutput)
 		c.metrics.UpdatesAvailable.Set(0)
 		c.metrics.SecurityUpdatesAvailable.Set(0)
+		c.record("updates_available", 0)
+		c.record("security_updates_available", 0)
 		return nil
 	}
 
@@ -141,6 +278,7 @@ func (c *Collector) checkUpdates(ctx context.Context) error {
 		return fmt.Errorf("failed to parse update count: %w", err)
 	}
 	c.metrics.UpdatesAvailable.Set(float64(regularUpdates))
+	c.record("updates_available", float64(regularUpdates))
 
 	// Parse security updates
 	securityUpdates, err := strconv.Atoi(parts[1])
@@ -149,31 +287,37 @@ func (c *Collector) checkUpdates(ctx context.Context) error {
 		return fmt.Errorf("failed to parse security update count: %w", err)
 	}
 	c.metrics.SecurityUpdatesAvailable.Set(float64(securityUpdates))
+	c.record("security_updates_available", float64(securityUpdates))
 
 	return nil
 }
 
 // checkLastUpdateTime checks when the last update was performed.
 func (c *Collector) checkLastUpdateTime() error {
-	info, err := os.Stat(c.cfg.UpdateStampPath)
+	info, err := os.Stat(c.cfg.Path(c.cfg.UpdateStampPath))
 	if err != nil {
 		c.metrics.SecondsSinceLastUpdate.Set(0)
+		c.metrics.LastUpdateTimestamp.Set(0)
 		return fmt.Errorf("failed to stat update stamp file: %w", err)
 	}
 
 	seconds := time.Since(info.ModTime()).Seconds()
 	c.metrics.SecondsSinceLastUpdate.Set(seconds)
+	c.metrics.LastUpdateTimestamp.Set(float64(info.ModTime().Unix()))
+	c.record("seconds_since_last_update", seconds)
 	return nil
 }
 
 // checkRebootRequired checks if a reboot is required.
 func (c *Collector) checkRebootRequired() error {
-	_, err := os.Stat(c.cfg.RebootRequiredFile)
+	_, err := os.Stat(c.cfg.Path(c.cfg.RebootRequiredFile))
 	if err == nil {
 		c.metrics.RebootRequired.Set(1)
+		c.record("reboot_required", 1)
 		return nil
 	} else if os.IsNotExist(err) {
 		c.metrics.RebootRequired.Set(0)
+		c.record("reboot_required", 0)
 		return nil
 	}
 
@@ -2,6 +2,7 @@ package collector
 
 import (
 	"context"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"testing"
@@ -19,6 +20,7 @@ func TestCollector(t *testing.T) {
 	aptCheckPath := filepath.Join(tmpDir, "apt-check")
 	updateStampPath := filepath.Join(tmpDir, "update-success-stamp")
 	rebootRequiredFile := filepath.Join(tmpDir, "reboot-required")
+	dpkgStatusPath := filepath.Join(tmpDir, "status")
 
 	// Create a mock apt-check script that outputs "5;2" to stderr (like the real apt-check)
 	mockAptCheckContent := `#!/bin/sh
@@ -42,6 +44,11 @@ echo "5;2" >&2
 		t.Fatalf("Failed to create mock reboot required file: %v", err)
 	}
 
+	// Create a mock dpkg status database
+	if err := os.WriteFile(dpkgStatusPath, []byte("Package: bash\nStatus: install ok installed\nArchitecture: amd64\nVersion: 5.1-6ubuntu1\n"), 0644); err != nil {
+		t.Fatalf("Failed to create mock dpkg status: %v", err)
+	}
+
 	// Create a test configuration
 	cfg := &config.Config{
 		CheckIntervalSeconds:  300,
@@ -49,6 +56,7 @@ echo "5;2" >&2
 		AptCheckPath:          aptCheckPath,
 		UpdateStampPath:       updateStampPath,
 		RebootRequiredFile:    rebootRequiredFile,
+		DpkgStatusPath:        dpkgStatusPath,
 		LogLevel:              "info",
 		CommandTimeoutSeconds: 10,
 		MetricsEndpoint:       "/metrics",
@@ -59,7 +67,7 @@ echo "5;2" >&2
 	m := metrics.NewTestMetrics()
 
 	// Create collector
-	c := New(cfg, m)
+	c := New(cfg, m, slog.New(slog.DiscardHandler))
 
 	// Create a context with a short timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
@@ -85,6 +93,9 @@ echo "5;2" >&2
 	if secondsSinceUpdate < 86000 || secondsSinceUpdate > 87000 {
 		t.Errorf("Expected SecondsSinceLastUpdate to be around 86400, got %f", secondsSinceUpdate)
 	}
+	if got := m.LastUpdateTimestamp.(*metrics.TestGauge).Get(); got != float64(updateTime.Unix()) {
+		t.Errorf("Expected LastUpdateTimestamp %d, got %f", updateTime.Unix(), got)
+	}
 
 	rebootRequired := m.RebootRequired.(*metrics.TestGauge).Get()
 	if rebootRequired != 1 {
@@ -133,3 +144,82 @@ echo "invalid" >&2
 		t.Errorf("Expected UpdatesAvailable to be 0 after invalid apt-check, got %f", updatesAvailable)
 	}
 }
+
+func TestCollectCountsErrors(t *testing.T) {
+	tmpDir := t.TempDir()
+	aptCheckPath := filepath.Join(tmpDir, "apt-check")
+	if err := os.WriteFile(aptCheckPath, []byte("#!/bin/sh\necho \"5;2\" >&2\n"), 0755); err != nil {
+		t.Fatalf("Failed to create mock apt-check: %v", err)
+	}
+
+	cfg := &config.Config{
+		AptCheckPath:          aptCheckPath,
+		UpdateStampPath:       filepath.Join(tmpDir, "missing-stamp"),
+		RebootRequiredFile:    filepath.Join(tmpDir, "reboot-required"),
+		CommandTimeoutSeconds: 10,
+	}
+	m := metrics.NewTestMetrics()
+	c := New(cfg, m, slog.New(slog.DiscardHandler))
+	c.collect(context.Background())
+	c.collect(context.Background())
+
+	// Only the failing check is counted, once per collection
+	errors := m.CollectionErrors.(*metrics.TestCounterVec)
+	if got := errors.Get("last_update"); got != 2 {
+		t.Errorf("Expected 2 last_update errors, got %v", got)
+	}
+	if got := errors.Get("updates"); got != 0 {
+		t.Errorf("Expected no updates errors, got %v", got)
+	}
+
+	// Every collection is observed in the duration histogram
+	if got := m.CollectionDurationSeconds.(*metrics.TestHistogram).Get(); len(got) != 2 {
+		t.Errorf("Expected 2 observed durations, got %v", got)
+	}
+}
+
+func TestStartPingsWatchdog(t *testing.T) {
+	tmpDir := t.TempDir()
+	aptCheckPath := filepath.Join(tmpDir, "apt-check")
+	if err := os.WriteFile(aptCheckPath, []byte("#!/bin/sh\necho \"0;0\" >&2\n"), 0755); err != nil {
+		t.Fatalf("Failed to create mock apt-check: %v", err)
+	}
+
+	cfg := &config.Config{
+		CheckIntervalSeconds:  300,
+		AptCheckPath:          aptCheckPath,
+		UpdateStampPath:       filepath.Join(tmpDir, "update-success-stamp"),
+		RebootRequiredFile:    filepath.Join(tmpDir, "reboot-required"),
+		CommandTimeoutSeconds: 10,
+		DisableFileWatch:      true,
+	}
+	c := New(cfg, metrics.NewTestMetrics(), slog.New(slog.DiscardHandler))
+
+	pings := make(chan struct{}, 10)
+	c.SetWatchdog(10*time.Millisecond, func() {
+		select {
+		case pings <- struct{}{}:
+		default:
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Start(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// Expect the initial ping and at least one from the ticker
+	for i := 0; i < 2; i++ {
+		select {
+		case <-pings:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Expected watchdog ping %d, got none", i+1)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package collector
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+
+	"github.com/ncecere/apt-exporter/internal/dpkg"
+	"github.com/ncecere/apt-exporter/internal/metrics"
+)
+
+// inventorySeries holds the series of the package inventory metrics.
+type inventorySeries struct {
+	packagesInstalled, packageSetHashInfo gaugeSeries
+}
+
+// newInventorySeries returns the series of the package inventory metrics of m.
+func newInventorySeries(m *metrics.Metrics) *inventorySeries {
+	return &inventorySeries{
+		packagesInstalled:  gaugeSeries{vec: m.PackagesInstalled},
+		packageSetHashInfo: gaugeSeries{vec: m.PackageSetHashInfo},
+	}
+}
+
+// checkInventory reports the installed packages by architecture, section and
+// priority, along with a hash identifying the exact set of installed packages.
+// If the installed packages cannot be read, the series keep their last values.
+func (c *Collector) checkInventory() error {
+	installed, err := dpkg.ReadStatus(c.cfg.Path(c.cfg.DpkgStatusPath))
+	if err != nil {
+		return err
+	}
+
+	series := c.inventorySeries
+	for g, n := range packageGroups(installed) {
+		series.packagesInstalled.set(float64(n), g.architecture, g.section, g.priority)
+	}
+	series.packageSetHashInfo.set(1, packageSetHash(installed))
+	series.packagesInstalled.commit()
+	series.packageSetHashInfo.commit()
+	c.record("packages_installed", float64(len(installed)))
+
+	return nil
+}
+
+// SetInventory sets the package inventory metrics of m for the installed
+// packages, also for packages read from elsewhere than a host, such as an image.
+func SetInventory(m *metrics.Metrics, installed []dpkg.Package) {
+	for g, n := range packageGroups(installed) {
+		m.PackagesInstalled.WithLabelValues(g.architecture, g.section, g.priority).Set(float64(n))
+	}
+	m.PackageSetHashInfo.WithLabelValues(packageSetHash(installed)).Set(1)
+}
+
+// packageGroup is the architecture, section and priority packages are counted by.
+type packageGroup struct{ architecture, section, priority string }
+
+// packageGroups counts the packages by architecture, section and priority.
+func packageGroups(packages []dpkg.Package) map[packageGroup]int {
+	counts := make(map[packageGroup]int)
+	for _, pkg := range packages {
+		counts[packageGroup{labelOrUnknown(pkg.Architecture), labelOrUnknown(pkg.Section), labelOrUnknown(pkg.Priority)}]++
+	}
+	return counts
+}
+
+// packageSetHash returns the hex-encoded SHA-256 of the sorted "name=version"
+// lines of the packages, so hosts with the same packages share the same hash
+// regardless of the order of their status files.
+func packageSetHash(packages []dpkg.Package) string {
+	lines := make([]string, 0, len(packages))
+	for _, pkg := range packages {
+		lines = append(lines, pkg.Name+"="+pkg.Version)
+	}
+	sort.Strings(lines)
+
+	h := sha256.New()
+	for _, line := range lines {
+		h.Write([]byte(line))
+		h.Write([]byte("\n"))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// labelOrUnknown substitutes "unknown" for fields missing from the status file.
+func labelOrUnknown(value string) string {
+	if value == "" {
+		return "unknown"
+	}
+	return value
+}
@@ -0,0 +1,92 @@
+package collector
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ncecere/apt-exporter/internal/config"
+	"github.com/ncecere/apt-exporter/internal/dpkg"
+	"github.com/ncecere/apt-exporter/internal/metrics"
+)
+
+func TestCheckInventory(t *testing.T) {
+	statusPath := filepath.Join(t.TempDir(), "status")
+	status := "Package: bash\nStatus: install ok installed\nPriority: required\nSection: shells\nArchitecture: amd64\nVersion: 5.1-6ubuntu1\n\n" +
+		"Package: coreutils\nStatus: install ok installed\nPriority: required\nSection: utils\nArchitecture: amd64\nVersion: 8.32-4.1ubuntu1\n\n" +
+		"Package: tzdata\nStatus: install ok installed\nPriority: required\nSection: localization\nArchitecture: all\nVersion: 2024a-1\n\n" +
+		"Package: grep\nStatus: install ok installed\nPriority: required\nSection: utils\nArchitecture: amd64\nVersion: 3.7-1build1\n\n" +
+		"Package: local-tool\nStatus: install ok installed\nArchitecture: amd64\nVersion: 1.0\n\n" +
+		"Package: old-kernel\nStatus: deinstall ok config-files\nPriority: optional\nSection: kernel\nArchitecture: amd64\nVersion: 5.15.0-1\n"
+	if err := os.WriteFile(statusPath, []byte(status), 0644); err != nil {
+		t.Fatalf("Failed to write status file: %v", err)
+	}
+
+	m := metrics.NewTestMetrics()
+	c := New(&config.Config{DpkgStatusPath: statusPath}, m, slog.New(slog.DiscardHandler))
+
+	if err := c.checkInventory(); err != nil {
+		t.Fatalf("checkInventory() returned error: %v", err)
+	}
+
+	installed := m.PackagesInstalled.(*metrics.TestGaugeVec)
+	tests := []struct {
+		labels   []string
+		expected float64
+	}{
+		{[]string{"amd64", "utils", "required"}, 2},
+		{[]string{"amd64", "shells", "required"}, 1},
+		{[]string{"all", "localization", "required"}, 1},
+		{[]string{"amd64", "unknown", "unknown"}, 1},
+	}
+	for _, tt := range tests {
+		if v, _ := installed.Get(tt.labels...); v != tt.expected {
+			t.Errorf("Expected PackagesInstalled%v to be %f, got %f", tt.labels, tt.expected, v)
+		}
+	}
+	if installed.Len() != len(tests) {
+		t.Errorf("Expected %d label combinations, got %d", len(tests), installed.Len())
+	}
+
+	hashVec := m.PackageSetHashInfo.(*metrics.TestGaugeVec)
+	packages, err := dpkg.ReadStatus(statusPath)
+	if err != nil {
+		t.Fatalf("ReadStatus() returned error: %v", err)
+	}
+	if v, _ := hashVec.Get(packageSetHash(packages)); v != 1 || hashVec.Len() != 1 {
+		t.Errorf("Expected a single package set hash, got %d", hashVec.Len())
+	}
+
+	// A status file that cannot be read keeps the last series
+	if err := os.Remove(statusPath); err != nil {
+		t.Fatalf("Failed to remove status file: %v", err)
+	}
+	if err := c.checkInventory(); err == nil {
+		t.Fatal("Expected checkInventory() to fail for a missing status file")
+	}
+	if v, _ := installed.Get("amd64", "utils", "required"); v != 2 || installed.Len() != len(tests) {
+		t.Errorf("Expected PackagesInstalled to keep its series, got %d", installed.Len())
+	}
+	if v, _ := hashVec.Get(packageSetHash(packages)); v != 1 || hashVec.Len() != 1 {
+		t.Errorf("Expected the package set hash to be kept, got %d series", hashVec.Len())
+	}
+}
+
+func TestPackageSetHash(t *testing.T) {
+	a := []dpkg.Package{{Name: "bash", Version: "5.1-6ubuntu1"}, {Name: "grep", Version: "3.7-1build1"}}
+	b := []dpkg.Package{{Name: "grep", Version: "3.7-1build1"}, {Name: "bash", Version: "5.1-6ubuntu1"}}
+	c := []dpkg.Package{{Name: "bash", Version: "5.1-6ubuntu1.1"}, {Name: "grep", Version: "3.7-1build1"}}
+
+	// Matches: printf 'bash=5.1-6ubuntu1\ngrep=3.7-1build1\n' | sha256sum
+	expected := "5f1308d73449861b18c236082da0baa96bda490d2cf0b3fd21fe4748fe40c4ea"
+	if got := packageSetHash(a); got != expected {
+		t.Errorf("Expected hash %s, got %s", expected, got)
+	}
+	if packageSetHash(a) != packageSetHash(b) {
+		t.Error("Expected the hash to be independent of package order")
+	}
+	if packageSetHash(a) == packageSetHash(c) {
+		t.Error("Expected a version change to change the hash")
+	}
+}
@@ -0,0 +1,102 @@
+package collector
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ncecere/apt-exporter/internal/metrics"
+	"github.com/prometheus/procfs"
+)
+
+// lockSeries holds the series of the lock metrics.
+type lockSeries struct {
+	held, heldSeconds, holderInfo gaugeSeries
+}
+
+// newLockSeries returns the series of the lock metrics of m.
+func newLockSeries(m *metrics.Metrics) *lockSeries {
+	return &lockSeries{
+		held:        gaugeSeries{vec: m.DpkgLockHeld},
+		heldSeconds: gaugeSeries{vec: m.DpkgLockHeldSeconds},
+		holderInfo:  gaugeSeries{vec: m.DpkgLockHolderInfo},
+	}
+}
+
+// checkDpkgLocks reports which dpkg/apt lock files are held and by whom.
+// Locks are inspected through /proc/locks so the exporter never takes them itself.
+func (c *Collector) checkDpkgLocks() error {
+	series := c.lockSeries
+	defer func() {
+		series.held.commit()
+		series.heldSeconds.commit()
+		series.holderInfo.commit()
+	}()
+
+	locks, err := readProcLocks(filepath.Join(c.procPath, "locks"))
+	if err != nil {
+		return fmt.Errorf("failed to read kernel lock table: %w", err)
+	}
+
+	var errs []string
+	for _, path := range c.cfg.DpkgLockFiles {
+		holder, held, err := findLockHolder(c.cfg.Path(path), locks)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+
+		series.held.set(boolToFloat64(held), path)
+		if !held {
+			series.heldSeconds.set(0, path)
+			continue
+		}
+
+		// OFD locks are not associated with a process, so there is no holder to report
+		if holder <= 0 {
+			continue
+		}
+
+		proc, err := c.proc(holder)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("failed to inspect lock holder %d of %s: %v", holder, path, err))
+			continue
+		}
+
+		command, err := proc.Comm()
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("failed to read command of lock holder %d: %v", holder, err))
+			continue
+		}
+		series.holderInfo.set(1, path, strconv.Itoa(holder), command)
+
+		stat, err := proc.Stat()
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("failed to read stat of lock holder %d: %v", holder, err))
+			continue
+		}
+		startTime, err := stat.StartTime()
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("failed to determine start time of lock holder %d: %v", holder, err))
+			continue
+		}
+		heldSince := time.Unix(0, int64(startTime*float64(time.Second)))
+		series.heldSeconds.set(time.Since(heldSince).Seconds(), path)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// proc returns a handle to the given process in the configured procfs mount.
+func (c *Collector) proc(pid int) (procfs.Proc, error) {
+	fs, err := procfs.NewFS(c.procPath)
+	if err != nil {
+		return procfs.Proc{}, err
+	}
+	return fs.Proc(pid)
+}
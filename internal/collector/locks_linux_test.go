@@ -0,0 +1,133 @@
+package collector
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/ncecere/apt-exporter/internal/config"
+	"github.com/ncecere/apt-exporter/internal/metrics"
+	"golang.org/x/sys/unix"
+)
+
+func TestCheckDpkgLocks(t *testing.T) {
+	tmpDir := t.TempDir()
+	procDir := filepath.Join(tmpDir, "proc")
+
+	// Create lock files, only the first of which will be held
+	heldLock := filepath.Join(tmpDir, "lock-frontend")
+	freeLock := filepath.Join(tmpDir, "lock")
+	missingLock := filepath.Join(tmpDir, "does-not-exist")
+	for _, path := range []string{heldLock, freeLock} {
+		if err := os.WriteFile(path, []byte(""), 0640); err != nil {
+			t.Fatalf("Failed to create lock file: %v", err)
+		}
+	}
+
+	info, err := os.Stat(heldLock)
+	if err != nil {
+		t.Fatalf("Failed to stat lock file: %v", err)
+	}
+	st := info.Sys().(*syscall.Stat_t)
+	dev := uint64(st.Dev)
+	major, minor := unix.Major(dev), unix.Minor(dev)
+
+	// Boot one hour ago; the holder started 10 seconds after boot
+	bootTime := time.Now().Add(-time.Hour).Unix()
+	startTicks := 10 * 100
+	procLocks := fmt.Sprintf("1: POSIX  ADVISORY  WRITE 4242 %02x:%02x:%d 0 EOF\n", major, minor, st.Ino) +
+		fmt.Sprintf("1: -> POSIX  ADVISORY  WRITE 5555 %02x:%02x:%d 0 EOF\n", major, minor, st.Ino) +
+		"2: FLOCK  ADVISORY  WRITE 1 00:19:1 0 EOF\n"
+
+	statFields := make([]string, 52)
+	for i := range statFields {
+		statFields[i] = "0"
+	}
+	statFields[0] = "4242"
+	statFields[1] = "(apt-get)"
+	statFields[2] = "S"
+	statFields[21] = fmt.Sprint(startTicks)
+
+	files := map[string]string{
+		"locks":     procLocks,
+		"stat":      fmt.Sprintf("btime %d\n", bootTime),
+		"4242/comm": "apt-get\n",
+		"4242/stat": strings.Join(statFields, " ") + "\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(procDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create proc directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	cfg := &config.Config{
+		DpkgLockFiles: []string{heldLock, freeLock, missingLock},
+	}
+	m := metrics.NewTestMetrics()
+	c := New(cfg, m, slog.New(slog.DiscardHandler))
+	c.procPath = procDir
+
+	if err := c.checkDpkgLocks(); err != nil {
+		t.Fatalf("checkDpkgLocks() returned error: %v", err)
+	}
+
+	heldVec := m.DpkgLockHeld.(*metrics.TestGaugeVec)
+	if v, _ := heldVec.Get(heldLock); v != 1 {
+		t.Errorf("Expected DpkgLockHeld for %s to be 1, got %f", heldLock, v)
+	}
+	if v, ok := heldVec.Get(freeLock); !ok || v != 0 {
+		t.Errorf("Expected DpkgLockHeld for %s to be 0, got %f (present: %v)", freeLock, v, ok)
+	}
+	if v, ok := heldVec.Get(missingLock); !ok || v != 0 {
+		t.Errorf("Expected DpkgLockHeld for %s to be 0, got %f (present: %v)", missingLock, v, ok)
+	}
+
+	infoVec := m.DpkgLockHolderInfo.(*metrics.TestGaugeVec)
+	if v, _ := infoVec.Get(heldLock, "4242", "apt-get"); v != 1 {
+		t.Errorf("Expected DpkgLockHolderInfo for pid 4242 to be 1, got %f", v)
+	}
+	if infoVec.Len() != 1 {
+		t.Errorf("Expected exactly one lock holder, got %d", infoVec.Len())
+	}
+
+	// The holder started 10 seconds after boot, so it has held the lock for just under an hour
+	secondsVec := m.DpkgLockHeldSeconds.(*metrics.TestGaugeVec)
+	held, _ := secondsVec.Get(heldLock)
+	if held < 3580 || held > 3600 {
+		t.Errorf("Expected DpkgLockHeldSeconds to be around 3590, got %f", held)
+	}
+
+	// Releasing the lock clears the holder information
+	if err := os.WriteFile(filepath.Join(procDir, "locks"), []byte(""), 0644); err != nil {
+		t.Fatalf("Failed to clear proc locks: %v", err)
+	}
+	if err := c.checkDpkgLocks(); err != nil {
+		t.Fatalf("checkDpkgLocks() returned error: %v", err)
+	}
+	if v, _ := heldVec.Get(heldLock); v != 0 {
+		t.Errorf("Expected DpkgLockHeld to be 0 after release, got %f", v)
+	}
+	if infoVec.Len() != 0 {
+		t.Errorf("Expected no lock holders after release, got %d", infoVec.Len())
+	}
+}
+
+func TestReadProcLocksInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "locks")
+	if err := os.WriteFile(path, []byte("1: POSIX  ADVISORY  WRITE abc 08:01:1 0 EOF\n"), 0644); err != nil {
+		t.Fatalf("Failed to write locks file: %v", err)
+	}
+
+	if _, err := readProcLocks(path); err == nil {
+		t.Error("Expected error for invalid pid, got nil")
+	}
+}
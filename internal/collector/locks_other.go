@@ -0,0 +1,18 @@
+//go:build !linux
+
+package collector
+
+import "fmt"
+
+// fileLock is an entry of the kernel lock table, which is not available on this platform.
+type fileLock struct{}
+
+// readProcLocks reports that the kernel lock table cannot be inspected.
+func readProcLocks(path string) ([]fileLock, error) {
+	return nil, fmt.Errorf("%w: lock inspection is only supported on Linux", errUnsupported)
+}
+
+// findLockHolder reports that lock holders cannot be determined.
+func findLockHolder(path string, locks []fileLock) (int, bool, error) {
+	return 0, false, fmt.Errorf("%w: lock inspection is only supported on Linux", errUnsupported)
+}
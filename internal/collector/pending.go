@@ -0,0 +1,157 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ncecere/apt-exporter/internal/apt"
+	"github.com/ncecere/apt-exporter/internal/dpkg"
+)
+
+// pendingStateFile is the name of the state file within state_dir.
+const pendingStateFile = "pending-updates.json"
+
+// pendingState records when each pending update was first seen.
+type pendingState struct {
+	// FirstSeen maps "package:arch=candidate version" to a Unix timestamp
+	FirstSeen map[string]int64 `json:"first_seen"`
+}
+
+// checkPendingUpdates tracks how long each pending update has been available.
+// First-seen timestamps are persisted in state_dir, so ages survive restarts,
+// and are pruned once a package is upgraded or its candidate changes.
+func (c *Collector) checkPendingUpdates() error {
+	installed, err := dpkg.ReadStatus(c.cfg.Path(c.cfg.DpkgStatusPath))
+	if err != nil {
+		return err
+	}
+
+	idx, err := c.loadPackageIndex()
+	if err != nil {
+		return err
+	}
+	updates := idx.PendingUpdates(installed)
+
+	if c.pending == nil {
+		state, err := loadPendingState(filepath.Join(c.cfg.StateDir, pendingStateFile))
+		if err != nil {
+			return err
+		}
+		c.pending = state
+	}
+
+	now := time.Now()
+	changed := false
+	current := make(map[string]bool, len(updates))
+	ages := make([]float64, 0, len(updates))
+	oldestSecurity := 0.0
+	for _, u := range updates {
+		key := u.Package + ":" + u.Architecture + "=" + u.CandidateVersion
+		current[key] = true
+
+		firstSeen, ok := c.pending.FirstSeen[key]
+		if !ok {
+			firstSeen = now.Unix()
+			c.pending.FirstSeen[key] = firstSeen
+			changed = true
+		}
+
+		age := now.Sub(time.Unix(firstSeen, 0)).Seconds()
+		ages = append(ages, age)
+		if u.Security && age > oldestSecurity {
+			oldestSecurity = age
+		}
+	}
+
+	// Forget updates that were installed or superseded
+	for key := range c.pending.FirstSeen {
+		if !current[key] {
+			delete(c.pending.FirstSeen, key)
+			changed = true
+		}
+	}
+
+	c.metrics.OldestPendingSecurityUpdateAgeSeconds.Set(oldestSecurity)
+	c.metrics.PendingUpdateAgeSeconds.Set(ages)
+	c.record("pending_updates", float64(len(updates)))
+	c.record("oldest_pending_security_update_age_seconds", oldestSecurity)
+
+	if changed {
+		return savePendingState(filepath.Join(c.cfg.StateDir, pendingStateFile), c.pending)
+	}
+	return nil
+}
+
+// loadPackageIndex returns the index of the APT lists, rebuilding it only
+// when the set of index files or their modification times change.
+func (c *Collector) loadPackageIndex() (*apt.Index, error) {
+	files, err := apt.IndexFiles(c.cfg.Path(c.cfg.AptListsDir))
+	if err != nil {
+		return nil, err
+	}
+
+	var signature strings.Builder
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat package index: %w", err)
+		}
+		fmt.Fprintf(&signature, "%s:%d:%d\n", file, info.Size(), info.ModTime().UnixNano())
+	}
+	if c.packageIndex != nil && signature.String() == c.packageIndexSignature {
+		return c.packageIndex, nil
+	}
+
+	idx, err := apt.ReadIndexDir(c.cfg.Path(c.cfg.AptListsDir))
+	if err != nil {
+		return nil, err
+	}
+	c.packageIndex = idx
+	c.packageIndexSignature = signature.String()
+	return idx, nil
+}
+
+// loadPendingState reads the pending update state, starting empty if it does not exist yet.
+func loadPendingState(path string) (*pendingState, error) {
+	state := &pendingState{FirstSeen: make(map[string]int64)}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %w", path, err)
+	}
+	if state.FirstSeen == nil {
+		state.FirstSeen = make(map[string]int64)
+	}
+	return state, nil
+}
+
+// savePendingState atomically replaces the pending update state.
+func savePendingState(path string, state *pendingState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to replace state file: %w", err)
+	}
+	return nil
+}
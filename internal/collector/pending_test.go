@@ -0,0 +1,118 @@
+package collector
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ncecere/apt-exporter/internal/config"
+	"github.com/ncecere/apt-exporter/internal/metrics"
+)
+
+func TestCheckPendingUpdates(t *testing.T) {
+	tmpDir := t.TempDir()
+	listsDir := filepath.Join(tmpDir, "lists")
+	stateDir := filepath.Join(tmpDir, "state")
+	statusPath := filepath.Join(tmpDir, "status")
+	if err := os.Mkdir(listsDir, 0755); err != nil {
+		t.Fatalf("Failed to create lists directory: %v", err)
+	}
+
+	lists := map[string]string{
+		"archive.ubuntu.com_ubuntu_dists_jammy-updates_main_binary-amd64_Packages":   "Package: tzdata\nArchitecture: all\nVersion: 2024b-1\n",
+		"security.ubuntu.com_ubuntu_dists_jammy-security_main_binary-amd64_Packages": "Package: openssl\nArchitecture: amd64\nVersion: 3.0.2-2\n",
+	}
+	for name, content := range lists {
+		if err := os.WriteFile(filepath.Join(listsDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	writeStatus := func(opensslVersion string) {
+		t.Helper()
+		status := "Package: openssl\nStatus: install ok installed\nArchitecture: amd64\nVersion: " + opensslVersion + "\n\n" +
+			"Package: tzdata\nStatus: install ok installed\nArchitecture: all\nVersion: 2024a-1\n"
+		if err := os.WriteFile(statusPath, []byte(status), 0644); err != nil {
+			t.Fatalf("Failed to write status file: %v", err)
+		}
+	}
+	writeStatus("3.0.2-1")
+
+	// The openssl update was first seen two days ago by a previous run
+	statePath := filepath.Join(stateDir, pendingStateFile)
+	twoDaysAgo := time.Now().Add(-48 * time.Hour).Unix()
+	state := `{"first_seen": {"openssl:amd64=3.0.2-2": ` + jsonInt(twoDaysAgo) + `, "curl:amd64=7.81.0-2": 1}}`
+	if err := os.MkdirAll(stateDir, 0755); err != nil {
+		t.Fatalf("Failed to create state directory: %v", err)
+	}
+	if err := os.WriteFile(statePath, []byte(state), 0644); err != nil {
+		t.Fatalf("Failed to write state file: %v", err)
+	}
+
+	cfg := &config.Config{
+		DpkgStatusPath: statusPath,
+		AptListsDir:    listsDir,
+		StateDir:       stateDir,
+	}
+	m := metrics.NewTestMetrics()
+	c := New(cfg, m, slog.New(slog.DiscardHandler))
+
+	if err := c.checkPendingUpdates(); err != nil {
+		t.Fatalf("checkPendingUpdates() returned error: %v", err)
+	}
+
+	oldest := m.OldestPendingSecurityUpdateAgeSeconds.(*metrics.TestGauge).Get()
+	if oldest < 48*3600-60 || oldest > 48*3600+60 {
+		t.Errorf("Expected oldest pending security update age to be around 172800, got %f", oldest)
+	}
+	if ages := m.PendingUpdateAgeSeconds.(*metrics.TestDistribution).Get(); len(ages) != 2 {
+		t.Errorf("Expected 2 pending update ages, got %v", ages)
+	}
+
+	// The state keeps the persisted timestamp, adds the new update and prunes the stale curl entry
+	saved := readPendingState(t, statePath)
+	if len(saved.FirstSeen) != 2 {
+		t.Errorf("Expected 2 entries in state, got %v", saved.FirstSeen)
+	}
+	if saved.FirstSeen["openssl:amd64=3.0.2-2"] != twoDaysAgo {
+		t.Errorf("Expected openssl first seen timestamp to be kept, got %v", saved.FirstSeen)
+	}
+	if _, ok := saved.FirstSeen["tzdata:all=2024b-1"]; !ok {
+		t.Errorf("Expected tzdata update to be tracked, got %v", saved.FirstSeen)
+	}
+
+	// Upgrading openssl removes it from the state and resets the oldest security age
+	writeStatus("3.0.2-2")
+	if err := c.checkPendingUpdates(); err != nil {
+		t.Fatalf("checkPendingUpdates() returned error: %v", err)
+	}
+	if v := m.OldestPendingSecurityUpdateAgeSeconds.(*metrics.TestGauge).Get(); v != 0 {
+		t.Errorf("Expected oldest pending security update age to be 0 after upgrade, got %f", v)
+	}
+	saved = readPendingState(t, statePath)
+	if _, ok := saved.FirstSeen["openssl:amd64=3.0.2-2"]; ok || len(saved.FirstSeen) != 1 {
+		t.Errorf("Expected only tzdata to remain in state, got %v", saved.FirstSeen)
+	}
+}
+
+func readPendingState(t *testing.T, path string) pendingState {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read state file: %v", err)
+	}
+	var state pendingState
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatalf("Failed to parse state file: %v", err)
+	}
+	return state
+}
+
+func jsonInt(v int64) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
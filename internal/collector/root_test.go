@@ -0,0 +1,77 @@
+package collector
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ncecere/apt-exporter/internal/config"
+	"github.com/ncecere/apt-exporter/internal/metrics"
+)
+
+func TestCollectRootDir(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"var/lib/dpkg/status":                       "Package: bash\nStatus: install ok installed\nArchitecture: amd64\nVersion: 5.2.15-2+b2\n",
+		"var/lib/apt/periodic/update-success-stamp": "",
+		"run/reboot-required":                       "*** System restart required ***\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	// As on Debian, /var/run points to /run; it must resolve within the root
+	if err := os.Symlink("/run", filepath.Join(root, "var/run")); err != nil {
+		t.Fatalf("Failed to create /var/run symlink: %v", err)
+	}
+
+	// apt-check exists on the host, but must not be run against another root
+	aptCheckPath := filepath.Join(t.TempDir(), "apt-check")
+	if err := os.WriteFile(aptCheckPath, []byte("#!/bin/sh\necho \"5;2\" >&2\n"), 0755); err != nil {
+		t.Fatalf("Failed to create mock apt-check: %v", err)
+	}
+
+	cfg := &config.Config{
+		RootDir:               root,
+		AptCheckPath:          aptCheckPath,
+		UpdateStampPath:       "/var/lib/apt/periodic/update-success-stamp",
+		RebootRequiredFile:    "/var/run/reboot-required",
+		DpkgStatusPath:        "/var/lib/dpkg/status",
+		CommandTimeoutSeconds: 10,
+	}
+	m := metrics.NewTestMetrics()
+	c := New(cfg, m, slog.New(slog.DiscardHandler))
+	c.procPath = t.TempDir()
+	if err := os.WriteFile(filepath.Join(c.procPath, "locks"), nil, 0644); err != nil {
+		t.Fatalf("Failed to write proc locks: %v", err)
+	}
+
+	status := c.collect(context.Background())
+	if !status.Success {
+		t.Errorf("Expected collection to succeed with an unsupported check, got %+v", status.Checks)
+	}
+
+	results := make(map[string]CheckResult)
+	for _, result := range status.Checks {
+		results[result.Name] = result
+	}
+	if updates := results["updates"]; !updates.Unsupported || !updates.Success || updates.Error == "" {
+		t.Errorf("Expected updates check to be reported as unsupported, got %+v", updates)
+	}
+	if v := m.UpdatesAvailable.(*metrics.TestGauge).Get(); v != 0 {
+		t.Errorf("Expected apt-check not to be run, got UpdatesAvailable %f", v)
+	}
+	if v := m.RebootRequired.(*metrics.TestGauge).Get(); v != 1 {
+		t.Errorf("Expected RebootRequired to be read from the root filesystem, got %f", v)
+	}
+	if v, _ := m.PackagesInstalled.(*metrics.TestGaugeVec).Get("amd64", "unknown", "unknown"); v != 1 {
+		t.Errorf("Expected the package inventory to be read from the root filesystem, got %f", v)
+	}
+}
@@ -0,0 +1,35 @@
+package collector
+
+import (
+	"strings"
+
+	"github.com/ncecere/apt-exporter/internal/metrics"
+)
+
+// gaugeSeries updates the series of a gauge vector without resetting it, so
+// that a concurrent scrape never sees the vector empty. Series set since the
+// last commit replace the ones set before it.
+type gaugeSeries struct {
+	vec  metrics.GaugeVec
+	prev map[string][]string
+	next map[string][]string
+}
+
+// set sets the series with the given label values.
+func (s *gaugeSeries) set(value float64, lvs ...string) {
+	if s.next == nil {
+		s.next = make(map[string][]string)
+	}
+	s.vec.WithLabelValues(lvs...).Set(value)
+	s.next[strings.Join(lvs, "\xff")] = lvs
+}
+
+// commit deletes the series that were set before the last commit but not since.
+func (s *gaugeSeries) commit() {
+	for key, lvs := range s.prev {
+		if _, ok := s.next[key]; !ok {
+			s.vec.DeleteLabelValues(lvs...)
+		}
+	}
+	s.prev, s.next = s.next, nil
+}
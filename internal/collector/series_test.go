@@ -0,0 +1,36 @@
+package collector
+
+import (
+	"testing"
+
+	"github.com/ncecere/apt-exporter/internal/metrics"
+)
+
+func TestGaugeSeries(t *testing.T) {
+	vec := &metrics.TestGaugeVec{}
+	s := gaugeSeries{vec: vec}
+
+	s.set(1, "a")
+	s.set(2, "b")
+	s.commit()
+
+	// Series set again keep their value until the commit, which deletes the rest
+	s.set(3, "b")
+	if v, ok := vec.Get("a"); !ok || v != 1 {
+		t.Errorf("Expected series a to be 1 before commit, got %f (present: %v)", v, ok)
+	}
+	s.commit()
+
+	if _, ok := vec.Get("a"); ok {
+		t.Error("Expected series a to be deleted after commit")
+	}
+	if v, _ := vec.Get("b"); v != 3 {
+		t.Errorf("Expected series b to be 3, got %f", v)
+	}
+
+	// A commit with nothing set deletes every series
+	s.commit()
+	if vec.Len() != 0 {
+		t.Errorf("Expected no series after empty commit, got %d", vec.Len())
+	}
+}
@@ -0,0 +1,151 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// errUnsupported is wrapped by checks that cannot run in the current setup,
+// such as shell-based checks when inspecting another root filesystem.
+var errUnsupported = errors.New("unsupported")
+
+// CheckResult is the outcome of a single check within a collection cycle.
+// Unsupported checks do not fail the cycle; Error explains why they were skipped.
+type CheckResult struct {
+	Name            string             `json:"name"`
+	Success         bool               `json:"success"`
+	Unsupported     bool               `json:"unsupported,omitempty"`
+	Error           string             `json:"error,omitempty"`
+	DurationSeconds float64            `json:"duration_seconds"`
+	Values          map[string]float64 `json:"values,omitempty"`
+}
+
+// Status summarizes a complete collection cycle.
+type Status struct {
+	Timestamp       time.Time     `json:"timestamp"`
+	DurationSeconds float64       `json:"duration_seconds"`
+	Success         bool          `json:"success"`
+	Checks          []CheckResult `json:"checks"`
+}
+
+// Summary describes the cycle in one line, naming any failed checks.
+func (s Status) Summary() string {
+	var failed []string
+	for _, result := range s.Checks {
+		if !result.Success {
+			failed = append(failed, result.Name)
+		}
+	}
+
+	summary := fmt.Sprintf("Last collection at %s took %.2fs", s.Timestamp.UTC().Format(time.RFC3339), s.DurationSeconds)
+	if len(failed) > 0 {
+		return summary + ", failed checks: " + strings.Join(failed, ", ")
+	}
+	return summary + ", all checks succeeded"
+}
+
+// Collect runs a collection cycle synchronously and returns its result.
+// If a cycle is already running, Collect waits for it to finish first.
+func (c *Collector) Collect(ctx context.Context) Status {
+	return c.collect(ctx)
+}
+
+// LastStatus returns the result of the most recent collection cycle.
+// The boolean is false if no cycle has completed yet.
+func (c *Collector) LastStatus() (Status, bool) {
+	c.statusMu.RLock()
+	defer c.statusMu.RUnlock()
+
+	if c.lastStatus == nil {
+		return Status{}, false
+	}
+	return *c.lastStatus, true
+}
+
+// LastSuccess returns the start time of the most recent successful collection cycle.
+// The boolean is false if no cycle has succeeded yet.
+func (c *Collector) LastSuccess() (time.Time, bool) {
+	c.statusMu.RLock()
+	defer c.statusMu.RUnlock()
+
+	return c.lastSuccess, !c.lastSuccess.IsZero()
+}
+
+// OnCollect registers a function that is called after every collection
+// cycle, once the metrics have been updated. It must not block for long.
+func (c *Collector) OnCollect(fn func(Status)) {
+	c.statusMu.Lock()
+	defer c.statusMu.Unlock()
+
+	c.hooks = append(c.hooks, fn)
+}
+
+// setLastStatus records the result of a completed collection cycle and
+// notifies the registered hooks.
+func (c *Collector) setLastStatus(status Status) {
+	c.statusMu.Lock()
+	c.lastStatus = &status
+	if status.Success {
+		c.lastSuccess = status.Timestamp
+	}
+	hooks := c.hooks
+	c.statusMu.Unlock()
+
+	for _, fn := range hooks {
+		fn(status)
+	}
+}
+
+// runCheck runs a single check, timing it and capturing its error and recorded values.
+// The error is also returned, for logging.
+func (c *Collector) runCheck(name string, check func() error) (CheckResult, error) {
+	result := CheckResult{Name: name, Success: true}
+	c.result = &result
+	defer func() { c.result = nil }()
+
+	start := time.Now()
+	err := check()
+	if errors.Is(err, errUnsupported) {
+		result.Unsupported = true
+		result.Error = err.Error()
+	} else if err != nil {
+		result.Success = false
+		result.Error = err.Error()
+	}
+	result.DurationSeconds = time.Since(start).Seconds()
+
+	return result, err
+}
+
+// errorFields returns structured log fields for the path and exit code
+// involved in a check failure, when the error carries them.
+func errorFields(err error) []any {
+	var fields []any
+
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		fields = append(fields, "path", pathErr.Path)
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		fields = append(fields, "exit_code", exitErr.ExitCode())
+	}
+
+	return fields
+}
+
+// record attaches a value to the result of the check currently being run.
+func (c *Collector) record(name string, value float64) {
+	if c.result == nil {
+		return
+	}
+	if c.result.Values == nil {
+		c.result.Values = make(map[string]float64)
+	}
+	c.result.Values[name] = value
+}
@@ -0,0 +1,143 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ncecere/apt-exporter/internal/config"
+	"github.com/ncecere/apt-exporter/internal/metrics"
+)
+
+func TestCollectStatus(t *testing.T) {
+	tmpDir := t.TempDir()
+	aptCheckPath := filepath.Join(tmpDir, "apt-check")
+	if err := os.WriteFile(aptCheckPath, []byte("#!/bin/sh\necho \"3;1\" >&2\n"), 0755); err != nil {
+		t.Fatalf("Failed to create mock apt-check: %v", err)
+	}
+
+	cfg := &config.Config{
+		AptCheckPath:          aptCheckPath,
+		UpdateStampPath:       filepath.Join(tmpDir, "missing-stamp"),
+		RebootRequiredFile:    filepath.Join(tmpDir, "reboot-required"),
+		CommandTimeoutSeconds: 10,
+	}
+	c := New(cfg, metrics.NewTestMetrics(), slog.New(slog.DiscardHandler))
+
+	if _, ok := c.LastStatus(); ok {
+		t.Fatal("Expected no status before the first collection")
+	}
+
+	var notified []Status
+	c.OnCollect(func(s Status) { notified = append(notified, s) })
+
+	status := c.Collect(context.Background())
+	if len(notified) != 1 || !notified[0].Timestamp.Equal(status.Timestamp) {
+		t.Errorf("Expected OnCollect hook to be called once with the status, got %+v", notified)
+	}
+
+	// The missing update stamp fails its check and therefore the cycle
+	if status.Success {
+		t.Error("Expected collection to fail because of the missing update stamp")
+	}
+
+	results := make(map[string]CheckResult)
+	for _, result := range status.Checks {
+		results[result.Name] = result
+	}
+
+	updates := results["updates"]
+	if !updates.Success {
+		t.Errorf("Expected updates check to succeed, got error %q", updates.Error)
+	}
+	if updates.Values["updates_available"] != 3 || updates.Values["security_updates_available"] != 1 {
+		t.Errorf("Expected updates check to record 3 and 1, got %v", updates.Values)
+	}
+
+	lastUpdate := results["last_update"]
+	if lastUpdate.Success || lastUpdate.Error == "" {
+		t.Errorf("Expected last_update check to fail with an error, got %+v", lastUpdate)
+	}
+
+	if v, ok := results["reboot_required"].Values["reboot_required"]; !ok || v != 0 {
+		t.Errorf("Expected reboot_required check to record 0, got %v", results["reboot_required"].Values)
+	}
+
+	last, ok := c.LastStatus()
+	if !ok {
+		t.Fatal("Expected a status after collection")
+	}
+	if !last.Timestamp.Equal(status.Timestamp) || len(last.Checks) != len(status.Checks) {
+		t.Errorf("Expected last status to match the returned status, got %+v", last)
+	}
+}
+
+func TestCollectLogsErrorFields(t *testing.T) {
+	tmpDir := t.TempDir()
+	aptCheckPath := filepath.Join(tmpDir, "apt-check")
+	if err := os.WriteFile(aptCheckPath, []byte("#!/bin/sh\nexit 2\n"), 0755); err != nil {
+		t.Fatalf("Failed to create mock apt-check: %v", err)
+	}
+	stampPath := filepath.Join(tmpDir, "missing-stamp")
+
+	cfg := &config.Config{
+		AptCheckPath:          aptCheckPath,
+		UpdateStampPath:       stampPath,
+		RebootRequiredFile:    filepath.Join(tmpDir, "reboot-required"),
+		CommandTimeoutSeconds: 10,
+	}
+	var buf bytes.Buffer
+	c := New(cfg, metrics.NewTestMetrics(), slog.New(slog.NewJSONHandler(&buf, nil)))
+	c.Collect(context.Background())
+
+	// Index the logged check errors by check name
+	logged := make(map[string]map[string]any)
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		var entry map[string]any
+		if err := json.Unmarshal(line, &entry); err != nil {
+			t.Fatalf("Failed to parse log entry %q: %v", line, err)
+		}
+		if check, ok := entry["check"].(string); ok && entry["level"] == "ERROR" {
+			logged[check] = entry
+		}
+	}
+
+	updates := logged["updates"]
+	if updates["path"] != aptCheckPath || updates["exit_code"] != float64(2) {
+		t.Errorf("Expected apt-check path and exit code 2 in the updates error, got %v", updates)
+	}
+	if lastUpdate := logged["last_update"]; lastUpdate["path"] != stampPath {
+		t.Errorf("Expected the update stamp path in the last update error, got %v", lastUpdate)
+	}
+	if updates["component"] != "collector" {
+		t.Errorf("Expected the collector component, got %v", updates["component"])
+	}
+}
+
+func TestStatusSummary(t *testing.T) {
+	status := Status{
+		Timestamp:       time.Date(2025, 3, 2, 12, 0, 0, 0, time.UTC),
+		DurationSeconds: 0.123,
+		Checks: []CheckResult{
+			{Name: "updates", Success: true},
+			{Name: "last_update", Success: false},
+			{Name: "reboot_required", Success: false},
+		},
+	}
+
+	want := "Last collection at 2025-03-02T12:00:00Z took 0.12s, failed checks: last_update, reboot_required"
+	if got := status.Summary(); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	status.Checks = status.Checks[:1]
+	want = "Last collection at 2025-03-02T12:00:00Z took 0.12s, all checks succeeded"
+	if got := status.Summary(); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
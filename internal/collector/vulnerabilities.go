@@ -0,0 +1,153 @@
+package collector
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ncecere/apt-exporter/internal/dpkg"
+	"github.com/ncecere/apt-exporter/internal/metrics"
+	"github.com/ncecere/apt-exporter/internal/osrelease"
+	"github.com/ncecere/apt-exporter/internal/vuln"
+)
+
+// vulnerabilitySeries holds the series of the vulnerability metrics.
+type vulnerabilitySeries struct {
+	vulnerabilities, packageCVE gaugeSeries
+}
+
+// newVulnerabilitySeries returns the series of the vulnerability metrics of m.
+func newVulnerabilitySeries(m *metrics.Metrics) *vulnerabilitySeries {
+	return &vulnerabilitySeries{
+		vulnerabilities: gaugeSeries{vec: m.Vulnerabilities},
+		packageCVE:      gaugeSeries{vec: m.PackageCVE},
+	}
+}
+
+// checkVulnerabilities matches the installed packages against the configured
+// security feed. If the feed or the installed packages cannot be read, the
+// series keep their last values.
+func (c *Collector) checkVulnerabilities() error {
+	db, err := c.loadSecurityFeed()
+	if err != nil {
+		return err
+	}
+
+	packages, err := dpkg.ReadStatus(c.cfg.Path(c.cfg.DpkgStatusPath))
+	if err != nil {
+		return err
+	}
+
+	// Always expose every severity so that absent series mean "not collected"
+	counts := make(map[string]int)
+	for _, severity := range vuln.Severities {
+		counts[severity] = 0
+	}
+
+	series := c.vulnerabilitySeries
+	findings := db.Match(packages)
+	for _, f := range findings {
+		counts[f.Severity]++
+		if c.cfg.SecurityFeed.PackageCVEs {
+			series.packageCVE.set(1, f.Package, f.ID, f.Severity)
+		}
+	}
+	for severity, count := range counts {
+		series.vulnerabilities.set(float64(count), severity)
+	}
+	series.vulnerabilities.commit()
+	series.packageCVE.commit()
+	c.record("vulnerabilities", float64(len(findings)))
+
+	return nil
+}
+
+// loadSecurityFeed returns the parsed security feed, reloading it whenever
+// its modification time changes.
+func (c *Collector) loadSecurityFeed() (*vuln.Database, error) {
+	feed := c.cfg.SecurityFeed
+
+	modTime, err := securityFeedModTime(feed.Path)
+	if err != nil {
+		return nil, fmt.Errorf("security feed %s is not accessible: %w", feed.Path, err)
+	}
+	if c.securityFeed != nil && modTime.Equal(c.securityFeedModTime) {
+		return c.securityFeed, nil
+	}
+
+	release, err := c.securityFeedRelease()
+	if err != nil {
+		return nil, err
+	}
+
+	var db *vuln.Database
+	switch feed.Format {
+	case "debian":
+		db, err = vuln.LoadDebianTracker(feed.Path, release)
+	case "osv":
+		db, err = vuln.LoadOSV(feed.Path, release)
+	default:
+		err = fmt.Errorf("unsupported security feed format: %s", feed.Format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c.logger.Info("Loaded security feed", "path", feed.Path, "release", release, "advisories", db.Len())
+	c.securityFeed = db
+	c.securityFeedModTime = modTime
+	return db, nil
+}
+
+// securityFeedModTime returns the modification time of the security feed at
+// path. For a directory, such as an OSV feed, it is the newest modification
+// time of the directory and everything in it, since rewriting a file in place
+// leaves the directory's own time unchanged.
+func securityFeedModTime(path string) (time.Time, error) {
+	var newest time.Time
+	err := filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(newest) {
+			newest = info.ModTime()
+		}
+		return nil
+	})
+	return newest, err
+}
+
+// securityFeedRelease returns the configured release of the security feed,
+// deriving it from os-release if it is not set: the codename for the Debian
+// tracker (e.g. "bookworm"), the ecosystem for OSV (e.g. "Ubuntu:22.04").
+func (c *Collector) securityFeedRelease() (string, error) {
+	if c.cfg.SecurityFeed.Release != "" {
+		return c.cfg.SecurityFeed.Release, nil
+	}
+
+	info, err := osrelease.Read(c.cfg.Path(c.cfg.OSReleasePath))
+	if err != nil {
+		return "", fmt.Errorf("failed to determine security feed release: %w", err)
+	}
+
+	var release string
+	switch c.cfg.SecurityFeed.Format {
+	case "debian":
+		release = info.Codename()
+	case "osv":
+		if id := info.ID(); id != "" && info.VersionID() != "" {
+			release = strings.ToUpper(id[:1]) + id[1:] + ":" + info.VersionID()
+		}
+	}
+	if release == "" {
+		return "", fmt.Errorf("failed to determine security feed release from %s, set security_feed.release", c.cfg.Path(c.cfg.OSReleasePath))
+	}
+
+	return release, nil
+}
@@ -0,0 +1,168 @@
+package collector
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ncecere/apt-exporter/internal/config"
+	"github.com/ncecere/apt-exporter/internal/metrics"
+)
+
+func TestCheckVulnerabilities(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	files := map[string]string{
+		"status": `Package: libssl3
+Status: install ok installed
+Architecture: amd64
+Source: openssl
+Version: 3.0.8-1
+
+Package: bash
+Status: install ok installed
+Architecture: amd64
+Version: 5.2.15-2+b2
+`,
+		"os-release": "ID=debian\nVERSION_ID=\"12\"\nVERSION_CODENAME=bookworm\n",
+		"debian.json": `{
+  "openssl": {
+    "CVE-2023-0001": {"releases": {"bookworm": {"status": "resolved", "fixed_version": "3.0.9-1", "urgency": "high"}}},
+    "CVE-2023-0002": {"releases": {"bookworm": {"status": "resolved", "fixed_version": "3.0.8-1", "urgency": "low"}}},
+    "CVE-2023-0003": {"releases": {"bookworm": {"status": "resolved", "fixed_version": "3.0.10-1", "urgency": "not yet assigned"}}}
+  },
+  "bash": {
+    "CVE-2023-0004": {"releases": {"bullseye": {"status": "resolved", "fixed_version": "5.3-1", "urgency": "high"}}}
+  }
+}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	cfg := &config.Config{
+		DpkgStatusPath: filepath.Join(tmpDir, "status"),
+		OSReleasePath:  filepath.Join(tmpDir, "os-release"),
+		SecurityFeed: config.SecurityFeedConfig{
+			Format:      "debian",
+			Path:        filepath.Join(tmpDir, "debian.json"),
+			PackageCVEs: true,
+		},
+	}
+	m := metrics.NewTestMetrics()
+	c := New(cfg, m, slog.New(slog.DiscardHandler))
+
+	if err := c.checkVulnerabilities(); err != nil {
+		t.Fatalf("checkVulnerabilities() returned error: %v", err)
+	}
+
+	vulnerabilities := m.Vulnerabilities.(*metrics.TestGaugeVec)
+	expected := map[string]float64{"high": 1, "unknown": 1, "low": 0, "medium": 0, "critical": 0, "negligible": 0}
+	for severity, want := range expected {
+		if got, ok := vulnerabilities.Get(severity); !ok || got != want {
+			t.Errorf("Expected %s vulnerabilities to be %f, got %f (present: %v)", severity, want, got, ok)
+		}
+	}
+
+	packageCVE := m.PackageCVE.(*metrics.TestGaugeVec)
+	if packageCVE.Len() != 2 {
+		t.Errorf("Expected 2 package CVE series, got %d", packageCVE.Len())
+	}
+	if v, _ := packageCVE.Get("openssl", "CVE-2023-0001", "high"); v != 1 {
+		t.Errorf("Expected openssl CVE-2023-0001 series to be 1, got %f", v)
+	}
+
+	// Without the opt-in, no per-package series are exposed
+	cfg.SecurityFeed.PackageCVEs = false
+	if err := c.checkVulnerabilities(); err != nil {
+		t.Fatalf("checkVulnerabilities() returned error: %v", err)
+	}
+	if packageCVE.Len() != 0 {
+		t.Errorf("Expected no package CVE series without opt-in, got %d", packageCVE.Len())
+	}
+
+	// A missing feed is reported as an error, keeping the last values
+	cfg.SecurityFeed.Path = filepath.Join(tmpDir, "missing.json")
+	if err := c.checkVulnerabilities(); err == nil {
+		t.Error("Expected error for missing security feed, got nil")
+	}
+	if v, ok := vulnerabilities.Get("high"); !ok || v != 1 {
+		t.Errorf("Expected high vulnerabilities to keep their value 1, got %f (present: %v)", v, ok)
+	}
+}
+
+func TestSecurityFeedReloadOSV(t *testing.T) {
+	tmpDir := t.TempDir()
+	feedDir := filepath.Join(tmpDir, "osv")
+	if err := os.Mkdir(feedDir, 0755); err != nil {
+		t.Fatalf("Failed to create feed directory: %v", err)
+	}
+
+	status := "Package: openssl\nStatus: install ok installed\nArchitecture: amd64\nVersion: 3.0.2-0ubuntu1.8\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, "status"), []byte(status), 0644); err != nil {
+		t.Fatalf("Failed to write status: %v", err)
+	}
+
+	recordPath := filepath.Join(feedDir, "CVE-2023-0286.json")
+	writeRecord := func(fixed string, modTime time.Time) {
+		t.Helper()
+		record := `{
+  "id": "CVE-2023-0286",
+  "affected": [
+    {
+      "package": {"ecosystem": "Ubuntu:22.04:LTS", "name": "openssl"},
+      "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "` + fixed + `"}]}]
+    }
+  ],
+  "database_specific": {"severity": "HIGH"}
+}`
+		if err := os.WriteFile(recordPath, []byte(record), 0644); err != nil {
+			t.Fatalf("Failed to write OSV record: %v", err)
+		}
+		if err := os.Chtimes(recordPath, modTime, modTime); err != nil {
+			t.Fatalf("Failed to set record time: %v", err)
+		}
+	}
+
+	// The directory keeps its time throughout, as when files are rewritten in place
+	dirTime := time.Now().Add(-time.Hour)
+	writeRecord("3.0.2-0ubuntu1.9", dirTime)
+	if err := os.Chtimes(feedDir, dirTime, dirTime); err != nil {
+		t.Fatalf("Failed to set directory time: %v", err)
+	}
+
+	cfg := &config.Config{
+		DpkgStatusPath: filepath.Join(tmpDir, "status"),
+		SecurityFeed: config.SecurityFeedConfig{
+			Format:  "osv",
+			Path:    feedDir,
+			Release: "Ubuntu:22.04",
+		},
+	}
+	m := metrics.NewTestMetrics()
+	c := New(cfg, m, slog.New(slog.DiscardHandler))
+
+	if err := c.checkVulnerabilities(); err != nil {
+		t.Fatalf("checkVulnerabilities() returned error: %v", err)
+	}
+	vulnerabilities := m.Vulnerabilities.(*metrics.TestGaugeVec)
+	if v, _ := vulnerabilities.Get("high"); v != 1 {
+		t.Errorf("Expected 1 high vulnerability, got %f", v)
+	}
+
+	// A record fixed in the installed version is picked up on the next check
+	writeRecord("3.0.2-0ubuntu1.8", dirTime.Add(time.Minute))
+	if err := os.Chtimes(feedDir, dirTime, dirTime); err != nil {
+		t.Fatalf("Failed to set directory time: %v", err)
+	}
+	if err := c.checkVulnerabilities(); err != nil {
+		t.Fatalf("checkVulnerabilities() returned error: %v", err)
+	}
+	if v, _ := vulnerabilities.Get("high"); v != 0 {
+		t.Errorf("Expected no high vulnerabilities after the feed changed, got %f", v)
+	}
+}
@@ -0,0 +1,147 @@
+package collector
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// watchMask selects the inotify events that indicate a watched file changed.
+const watchMask = unix.IN_CREATE | unix.IN_DELETE | unix.IN_MODIFY | unix.IN_CLOSE_WRITE |
+	unix.IN_MOVED_TO | unix.IN_MOVED_FROM | unix.IN_ATTRIB
+
+// watcher reports changes to files and directories using inotify.
+// Files are watched through their parent directory so that files which are
+// created, removed or atomically replaced (as dpkg does with its status file)
+// keep being tracked.
+type watcher struct {
+	fd     int
+	file   *os.File
+	events chan struct{}
+	logger *slog.Logger
+
+	mu    sync.Mutex
+	names map[int]map[string]bool // watch descriptor -> base names of interest, nil for any
+}
+
+// newWatcher creates an inotify instance with no watches. Read errors are
+// logged to logger.
+func newWatcher(logger *slog.Logger) (*watcher, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize inotify: %w", err)
+	}
+
+	w := &watcher{
+		fd: fd,
+		// A non-blocking descriptor is registered with the runtime poller,
+		// which allows Close to interrupt a pending Read.
+		file:   os.NewFile(uintptr(fd), "inotify"),
+		events: make(chan struct{}, 1),
+		logger: logger,
+		names:  make(map[int]map[string]bool),
+	}
+	go w.readEvents()
+
+	return w, nil
+}
+
+// AddFile watches a single file, which does not need to exist yet.
+func (w *watcher) AddFile(path string) error {
+	return w.add(filepath.Dir(path), filepath.Base(path))
+}
+
+// AddDir watches every entry of a directory.
+func (w *watcher) AddDir(path string) error {
+	return w.add(path, "")
+}
+
+// add registers a watch on dir, restricted to name unless name is empty.
+func (w *watcher) add(dir, name string) error {
+	wd, err := unix.InotifyAddWatch(w.fd, dir, watchMask)
+	if err != nil {
+		return fmt.Errorf("failed to watch %s: %w", dir, err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	names, exists := w.names[wd]
+	switch {
+	case name == "":
+		w.names[wd] = nil
+	case exists && names == nil:
+		// Already watching the whole directory
+	case exists:
+		names[name] = true
+	default:
+		w.names[wd] = map[string]bool{name: true}
+	}
+
+	return nil
+}
+
+// Events returns a channel that receives a value when a watched path changes.
+// Changes that happen while a previous notification is pending are coalesced.
+func (w *watcher) Events() <-chan struct{} {
+	return w.events
+}
+
+// Close stops watching and releases the inotify instance.
+func (w *watcher) Close() error {
+	return w.file.Close()
+}
+
+// readEvents reads inotify events until the watcher is closed. Reading
+// stops on any other error, leaving collection to the ticker, since retrying
+// a descriptor that keeps failing would spin.
+func (w *watcher) readEvents() {
+	buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			if !errors.Is(err, os.ErrClosed) {
+				w.logger.Warn("File watching stopped, relying on periodic collection", "error", err)
+			}
+			return
+		}
+
+		for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+			event := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameBytes := buf[offset+unix.SizeofInotifyEvent : offset+unix.SizeofInotifyEvent+int(event.Len)]
+			name := string(bytes.TrimRight(nameBytes, "\x00"))
+			offset += unix.SizeofInotifyEvent + int(event.Len)
+
+			if w.matches(int(event.Wd), name) {
+				w.notify()
+			}
+		}
+	}
+}
+
+// matches reports whether an event for name on watch descriptor wd is of interest.
+func (w *watcher) matches(wd int, name string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	names, ok := w.names[wd]
+	if !ok {
+		return false
+	}
+	return names == nil || names[name]
+}
+
+// notify signals a change without blocking.
+func (w *watcher) notify() {
+	select {
+	case w.events <- struct{}{}:
+	default:
+	}
+}
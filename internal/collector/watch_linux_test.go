@@ -0,0 +1,78 @@
+package collector
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatcher(t *testing.T) {
+	tmpDir := t.TempDir()
+	listsDir := filepath.Join(tmpDir, "lists")
+	if err := os.Mkdir(listsDir, 0755); err != nil {
+		t.Fatalf("Failed to create lists directory: %v", err)
+	}
+	rebootRequiredFile := filepath.Join(tmpDir, "reboot-required")
+
+	w, err := newWatcher(slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	defer w.Close()
+
+	if err := w.AddFile(rebootRequiredFile); err != nil {
+		t.Fatalf("Failed to watch file: %v", err)
+	}
+	if err := w.AddDir(listsDir); err != nil {
+		t.Fatalf("Failed to watch directory: %v", err)
+	}
+	if err := w.AddDir(filepath.Join(tmpDir, "missing")); err == nil {
+		t.Error("Expected error watching a missing directory, got nil")
+	}
+
+	// Unrelated files next to a watched file are ignored
+	if err := os.WriteFile(filepath.Join(tmpDir, "unrelated"), []byte("x"), 0644); err != nil {
+		t.Fatalf("Failed to write unrelated file: %v", err)
+	}
+	expectNoEvent(t, w)
+
+	// Creating a watched file that did not exist yet is reported
+	if err := os.WriteFile(rebootRequiredFile, []byte(""), 0644); err != nil {
+		t.Fatalf("Failed to create reboot required file: %v", err)
+	}
+	expectEvent(t, w)
+
+	// Any change inside a watched directory is reported
+	if err := os.WriteFile(filepath.Join(listsDir, "Packages"), []byte("x"), 0644); err != nil {
+		t.Fatalf("Failed to write lists file: %v", err)
+	}
+	expectEvent(t, w)
+}
+
+func expectEvent(t *testing.T, w *watcher) {
+	t.Helper()
+	select {
+	case <-w.Events():
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected a change event, got none")
+	}
+	// Drain events coalesced from the same change
+	for {
+		select {
+		case <-w.Events():
+		case <-time.After(50 * time.Millisecond):
+			return
+		}
+	}
+}
+
+func expectNoEvent(t *testing.T, w *watcher) {
+	t.Helper()
+	select {
+	case <-w.Events():
+		t.Fatal("Expected no change event, got one")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,28 @@
+//go:build !linux
+
+package collector
+
+import (
+	"errors"
+	"log/slog"
+)
+
+// watcher is not available on this platform; collection relies on the ticker alone.
+type watcher struct{}
+
+// newWatcher reports that file watching is unsupported.
+func newWatcher(logger *slog.Logger) (*watcher, error) {
+	return nil, errors.New("file watching is only supported on Linux")
+}
+
+// AddFile is a no-op on this platform.
+func (w *watcher) AddFile(path string) error { return nil }
+
+// AddDir is a no-op on this platform.
+func (w *watcher) AddDir(path string) error { return nil }
+
+// Events returns a channel that never receives.
+func (w *watcher) Events() <-chan struct{} { return nil }
+
+// Close is a no-op on this platform.
+func (w *watcher) Close() error { return nil }
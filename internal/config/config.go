@@ -1,54 +1,499 @@
 package config
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 
+	"github.com/ncecere/apt-exporter/internal/metrics"
+	"github.com/ncecere/apt-exporter/internal/osrelease"
+	"github.com/ncecere/apt-exporter/internal/rootfs"
 	"gopkg.in/yaml.v3"
 )
 
 // Config holds configuration parameters for the APT exporter.
 type Config struct {
-	CheckIntervalSeconds  int    `yaml:"check_interval_seconds"`
-	ListenAddress         string `yaml:"listen_address"`          // e.g. ":9100"
-	AptCheckPath          string `yaml:"apt_check_path"`          // e.g. "/usr/lib/update-notifier/apt-check"
-	UpdateStampPath       string `yaml:"update_stamp_path"`       // e.g. "/var/lib/apt/periodic/update-success-stamp"
-	RebootRequiredFile    string `yaml:"reboot_required_file"`    // e.g. "/var/run/reboot-required"
-	LogLevel              string `yaml:"log_level"`               // e.g. "info", "debug"
-	CommandTimeoutSeconds int    `yaml:"command_timeout_seconds"` // e.g. 10
-	MetricsEndpoint       string `yaml:"metrics_endpoint"`        // e.g. "/metrics"
-	MetricPrefix          string `yaml:"metric_prefix"`           // e.g. "ubuntu"
+	CheckIntervalSeconds  int      `yaml:"check_interval_seconds"`
+	CheckInterval         Duration `yaml:"check_interval,omitempty"`  // e.g. "5m", instead of check_interval_seconds
+	ListenAddress         string   `yaml:"listen_address"`            // e.g. ":9100"
+	AptCheckPath          string   `yaml:"apt_check_path"`            // e.g. "/usr/lib/update-notifier/apt-check"
+	UpdateStampPath       string   `yaml:"update_stamp_path"`         // e.g. "/var/lib/apt/periodic/update-success-stamp"
+	RebootRequiredFile    string   `yaml:"reboot_required_file"`      // e.g. "/var/run/reboot-required"
+	LogLevel              string   `yaml:"log_level"`                 // e.g. "info", "debug"
+	LogFormat             string   `yaml:"log_format"`                // e.g. "text", "json"
+	CommandTimeoutSeconds int      `yaml:"command_timeout_seconds"`   // e.g. 10
+	CommandTimeout        Duration `yaml:"command_timeout,omitempty"` // e.g. "10s"
+	MetricsEndpoint       string   `yaml:"metrics_endpoint"`          // e.g. "/metrics"
+	MetricPrefix          string   `yaml:"metric_prefix"`             // e.g. "ubuntu"
+
+	// Naming selects the metric names: "v1" names start with metric_prefix,
+	// "v2" names follow the Prometheus conventions in the apt namespace, e.g.
+	// apt_upgrades_pending, and "dual" exposes both while dashboards migrate.
+	Naming string `yaml:"naming"` // e.g. "v1"
+
+	// Labels are added to every metric, e.g. to tell environments apart when
+	// federating, without renaming the metrics like metric_prefix does.
+	Labels map[string]string `yaml:"labels"` // e.g. {env: prod, role: db}
+
+	// AutoLabels adds labels derived from the inspected system to every
+	// metric: "hostname" adds hostname, "os_release" adds os_id and
+	// os_version_id. Labels configured in Labels take precedence.
+	AutoLabels []string `yaml:"auto_labels"` // e.g. ["hostname", "os_release"]
+
+	// GoMetrics and ProcessMetrics expose the go_* runtime and process_*
+	// metrics of the exporter itself on the metrics endpoint.
+	GoMetrics      bool `yaml:"go_metrics"`
+	ProcessMetrics bool `yaml:"process_metrics"`
+
+	// DpkgLockFiles lists the lock files inspected for contention.
+	// Defaults to DefaultDpkgLockFiles when empty.
+	DpkgLockFiles []string `yaml:"dpkg_lock_files"`
+
+	DpkgStatusPath string `yaml:"dpkg_status_path"` // e.g. "/var/lib/dpkg/status"
+	AptListsDir    string `yaml:"apt_lists_dir"`    // e.g. "/var/lib/apt/lists"
+
+	// DisableFileWatch turns off inotify-triggered collection, leaving only the ticker.
+	DisableFileWatch     bool     `yaml:"disable_file_watch"`
+	WatchDebounceSeconds int      `yaml:"watch_debounce_seconds"`   // e.g. 5
+	WatchDebounce        Duration `yaml:"watch_debounce,omitempty"` // e.g. "5s"
+
+	// ManualCollectMinIntervalSeconds rate limits collections triggered over HTTP;
+	// 0 disables the limit.
+	ManualCollectMinIntervalSeconds int      `yaml:"manual_collect_min_interval_seconds"`   // e.g. 10
+	ManualCollectMinInterval        Duration `yaml:"manual_collect_min_interval,omitempty"` // e.g. "10s"
+
+	// ReadyMaxIntervals is how many check intervals may pass without a successful
+	// collection before the exporter reports itself as not ready.
+	ReadyMaxIntervals int `yaml:"ready_max_intervals"` // e.g. 3
+
+	OSReleasePath string `yaml:"os_release_path"` // e.g. "/etc/os-release"
+
+	// StateDir holds persistent state such as when pending updates were first
+	// seen. Pending update age tracking is enabled when it is set.
+	StateDir string `yaml:"state_dir"` // e.g. "/var/lib/apt-exporter"
+
+	// SecurityFeed enables offline vulnerability matching when its path is set.
+	SecurityFeed SecurityFeedConfig `yaml:"security_feed"`
+
+	// RootDir rebases the paths of the inspected system onto another root
+	// filesystem, such as a chroot, an unpacked container image or a mounted disk.
+	// The exporter's own files (state_dir, security_feed.path) are not rebased.
+	RootDir string `yaml:"root_dir"` // e.g. "/mnt/image"
+
+	// Targets are root filesystems of guests, such as containers, that can be
+	// probed through /probe?target=<name>.
+	Targets []Target `yaml:"targets"`
+
+	// Pushgateway pushes the metrics after each collection when its URL is set,
+	// for hosts that Prometheus cannot scrape.
+	Pushgateway PushgatewayConfig `yaml:"pushgateway"`
+
+	// RemoteWrite sends the metrics to a Prometheus remote-write receiver
+	// after each collection when its URL is set.
+	RemoteWrite RemoteWriteConfig `yaml:"remote_write"`
+
+	// MetricsBackend selects where the metrics go: "prometheus" serves them on
+	// the metrics endpoint, "otlp" exports them to an OpenTelemetry collector
+	// and "both" does both.
+	MetricsBackend string `yaml:"metrics_backend"` // e.g. "prometheus"
+
+	// OTLP configures the OpenTelemetry export used by the "otlp" and "both" backends.
+	OTLP OTLPConfig `yaml:"otlp"`
+}
+
+// Target is a named root filesystem inspected on demand.
+type Target struct {
+	Name    string `yaml:"name"`     // e.g. "web-1"
+	RootDir string `yaml:"root_dir"` // e.g. "/var/lib/machines/web-1"
+}
+
+// SecurityFeedConfig describes locally synced security tracker data.
+type SecurityFeedConfig struct {
+	Format string `yaml:"format"` // "debian" (security tracker JSON) or "osv" (directory of OSV files)
+	Path   string `yaml:"path"`   // e.g. "/var/lib/apt-exporter/debian-security.json"
+
+	// Release selects the data for this system: a codename such as "bookworm"
+	// for the Debian tracker, an ecosystem such as "Ubuntu:22.04" for OSV.
+	// Derived from os-release when empty.
+	Release string `yaml:"release"`
+
+	// PackageCVEs exposes one series per vulnerable package and CVE.
+	PackageCVEs bool `yaml:"package_cves"`
+}
+
+// PushgatewayConfig describes where and how metrics are pushed.
+type PushgatewayConfig struct {
+	URL string `yaml:"url"` // e.g. "http://pushgateway:9091"
+	Job string `yaml:"job"` // e.g. "apt_exporter"
+
+	// Grouping labels identify this host's group on the Pushgateway.
+	// Defaults to the hostname as the instance label.
+	Grouping map[string]string `yaml:"grouping"`
+
+	Username string `yaml:"username"`
+	Password Secret `yaml:"password"`
+
+	// IntervalSeconds also pushes periodically between collections; 0 only
+	// pushes after each collection.
+	IntervalSeconds int      `yaml:"interval_seconds"`   // e.g. 60
+	Interval        Duration `yaml:"interval,omitempty"` // e.g. "1m"
+
+	// DeleteOnShutdown deletes the group from the Pushgateway on shutdown.
+	DeleteOnShutdown bool `yaml:"delete_on_shutdown"`
+}
+
+// RemoteWriteConfig describes a Prometheus remote-write endpoint.
+type RemoteWriteConfig struct {
+	URL string `yaml:"url"` // e.g. "https://mimir.example.com/api/v1/push"
+
+	// Headers are added to every request, e.g. X-Scope-OrgID for multi-tenant
+	// receivers. Their values are redacted like passwords, since they often
+	// carry credentials.
+	Headers map[string]Secret `yaml:"headers"`
+
+	Username    string `yaml:"username"`
+	Password    Secret `yaml:"password"`
+	BearerToken Secret `yaml:"bearer_token"`
+
+	// ExternalLabels are added to every series. Since nothing scrapes the
+	// exporter, job and instance default to "apt_exporter" and the hostname.
+	ExternalLabels map[string]string `yaml:"external_labels"`
+
+	TimeoutSeconds int      `yaml:"timeout_seconds"`   // e.g. 30
+	Timeout        Duration `yaml:"timeout,omitempty"` // e.g. "30s"
+	MaxRetries     int      `yaml:"max_retries"`       // e.g. 3
+
+	// BufferDir keeps requests that could not be delivered on disk, to be
+	// resent once the receiver is reachable again. Disabled when empty.
+	BufferDir string `yaml:"buffer_dir"` // e.g. "/var/lib/apt-exporter/remote-write"
+	// BufferMaxRequests bounds the buffer; the oldest requests are dropped first.
+	BufferMaxRequests int `yaml:"buffer_max_requests"` // e.g. 1000
+}
+
+// OTLPConfig describes an OpenTelemetry collector receiving metrics over OTLP/HTTP.
+type OTLPConfig struct {
+	Endpoint string `yaml:"endpoint"` // e.g. "http://otel-collector:4318/v1/metrics"
+
+	// Headers are added to every request, e.g. for authentication.
+	Headers map[string]Secret `yaml:"headers"`
+
+	// ResourceAttributes are added to the detected host and OS attributes,
+	// taking precedence over them.
+	ResourceAttributes map[string]string `yaml:"resource_attributes"`
+
+	TimeoutSeconds int      `yaml:"timeout_seconds"`   // e.g. 10
+	Timeout        Duration `yaml:"timeout,omitempty"` // e.g. "10s"
+}
+
+// Duration is a length of time written like "90s" or "5m". Each duration
+// option is an alternative to the option of the same name in seconds and
+// takes precedence over it; validation converts it to seconds.
+type Duration time.Duration
+
+// UnmarshalYAML parses a duration such as "5m".
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	var s string
+	if err := value.Decode(&s); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("line %d: invalid duration %q", value.Line, s)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalYAML formats the duration like "5m0s".
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return time.Duration(d).String(), nil
+}
+
+// Secret is a configuration value that is not revealed when the
+// configuration is displayed.
+type Secret string
+
+// MarshalYAML redacts the secret.
+func (s Secret) MarshalYAML() (interface{}, error) {
+	if s == "" {
+		return "", nil
+	}
+	return "<secret>", nil
+}
+
+// Defaults of the settings every configuration needs.
+const (
+	DefaultCheckIntervalSeconds  = 300
+	DefaultListenAddress         = ":9100"
+	DefaultAptCheckPath          = "/usr/lib/update-notifier/apt-check"
+	DefaultUpdateStampPath       = "/var/lib/apt/periodic/update-success-stamp"
+	DefaultRebootRequiredFile    = "/var/run/reboot-required"
+	DefaultLogLevel              = "info"
+	DefaultCommandTimeoutSeconds = 10
+	DefaultMetricsEndpoint       = "/metrics"
+	DefaultMetricPrefix          = "ubuntu"
+)
+
+// Defaults for optional settings.
+const (
+	DefaultLogFormat            = "text"
+	DefaultDpkgStatusPath       = "/var/lib/dpkg/status"
+	DefaultAptListsDir          = "/var/lib/apt/lists"
+	DefaultWatchDebounceSeconds = 5
+
+	DefaultManualCollectMinIntervalSeconds = 10
+	DefaultReadyMaxIntervals               = 3
+	DefaultOSReleasePath                   = "/etc/os-release"
+
+	DefaultPushgatewayJob = "apt_exporter"
+
+	DefaultRemoteWriteJob               = "apt_exporter"
+	DefaultRemoteWriteTimeoutSeconds    = 30
+	DefaultRemoteWriteMaxRetries        = 3
+	DefaultRemoteWriteBufferMaxRequests = 1000
+
+	DefaultNaming = NamingV1
+
+	DefaultMetricsBackend     = MetricsBackendPrometheus
+	DefaultOTLPEndpoint       = "http://localhost:4318/v1/metrics"
+	DefaultOTLPTimeoutSeconds = 10
+)
+
+// Sources of auto labels.
+const (
+	AutoLabelHostname  = "hostname"
+	AutoLabelOSRelease = "os_release"
+)
+
+// labelNameRE matches valid Prometheus label names.
+var labelNameRE = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Metric naming schemes.
+const (
+	NamingV1   = "v1"
+	NamingV2   = "v2"
+	NamingDual = "dual"
+)
+
+// Metrics backends.
+const (
+	MetricsBackendPrometheus = "prometheus"
+	MetricsBackendOTLP       = "otlp"
+	MetricsBackendBoth       = "both"
+)
+
+// DefaultDpkgLockFiles are the lock files taken by dpkg and apt frontends.
+var DefaultDpkgLockFiles = []string{
+	"/var/lib/dpkg/lock-frontend",
+	"/var/lib/dpkg/lock",
+	"/var/lib/apt/lists/lock",
+	"/var/cache/apt/archives/lock",
+}
+
+// Default returns the configuration used for settings that are not
+// configured. Optional settings are filled in when it is validated, except
+// those for which 0 is meaningful.
+func Default() *Config {
+	return &Config{
+		CheckIntervalSeconds:  DefaultCheckIntervalSeconds,
+		ListenAddress:         DefaultListenAddress,
+		AptCheckPath:          DefaultAptCheckPath,
+		UpdateStampPath:       DefaultUpdateStampPath,
+		RebootRequiredFile:    DefaultRebootRequiredFile,
+		LogLevel:              DefaultLogLevel,
+		CommandTimeoutSeconds: DefaultCommandTimeoutSeconds,
+		MetricsEndpoint:       DefaultMetricsEndpoint,
+		MetricPrefix:          DefaultMetricPrefix,
+		WatchDebounceSeconds:  DefaultWatchDebounceSeconds,
+
+		ManualCollectMinIntervalSeconds: DefaultManualCollectMinIntervalSeconds,
+	}
 }
 
 // Load reads a YAML configuration file and returns a Config struct.
+// Settings missing from the file take their defaults.
 func Load(path string) (*Config, error) {
+	conf := Default()
+	if err := conf.readFile(path); err != nil {
+		return nil, err
+	}
+
+	// Validate configuration
+	if err := conf.validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return conf, nil
+}
+
+// readFile reads a YAML configuration file over c.
+func (c *Config) readFile(path string) error {
 	// Resolve absolute path
 	absPath, err := filepath.Abs(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to resolve config path: %w", err)
+		return fmt.Errorf("failed to resolve config path: %w", err)
 	}
 
 	data, err := os.ReadFile(absPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+		return fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	var conf Config
-	if err := yaml.Unmarshal(data, &conf); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	// Reject unknown keys, which are most likely misspelled options
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse config file: %w", err)
 	}
 
-	// Validate configuration
-	if err := conf.validate(); err != nil {
-		return nil, fmt.Errorf("invalid configuration: %w", err)
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse config file: %w", err)
 	}
+	given := make(map[string]bool)
+	yamlPaths(&doc, "", given)
+	return checkDurationPairs(c, "the config file", given)
+}
+
+// yamlPaths records the paths of the options set in a YAML node, joining
+// the keys of nested mappings with dots, e.g. "remote_write.timeout".
+func yamlPaths(node *yaml.Node, prefix string, paths map[string]bool) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			yamlPaths(child, prefix, paths)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			path := prefix + node.Content[i].Value
+			paths[path] = true
+			yamlPaths(node.Content[i+1], path+".", paths)
+		}
+	}
+}
 
-	return &conf, nil
+// Path returns the location of a path of the inspected system, rebased onto
+// root_dir when one is configured.
+func (c *Config) Path(path string) string {
+	return rootfs.Join(c.RootDir, path)
+}
+
+// Rebased reports whether the inspected system is not the host root filesystem.
+func (c *Config) Rebased() bool {
+	return !rootfs.IsHost(c.RootDir)
+}
+
+// hostnamePath holds the hostname of an alternate root filesystem.
+const hostnamePath = "/etc/hostname"
+
+// Hostname returns the hostname of the inspected system: the host's own, or
+// the one in /etc/hostname of an alternate root filesystem.
+func (c *Config) Hostname() (string, error) {
+	if !c.Rebased() {
+		return os.Hostname()
+	}
+	data, err := os.ReadFile(c.Path(hostnamePath))
+	if err != nil {
+		return "", err
+	}
+	hostname := strings.TrimSpace(string(data))
+	if hostname == "" {
+		return "", fmt.Errorf("%s is empty", c.Path(hostnamePath))
+	}
+	return hostname, nil
+}
+
+// PrometheusEnabled reports whether the metrics are served on the metrics endpoint.
+func (c *Config) PrometheusEnabled() bool {
+	return c.MetricsBackend != MetricsBackendOTLP
+}
+
+// OTLPEnabled reports whether the metrics are exported over OTLP.
+func (c *Config) OTLPEnabled() bool {
+	return c.MetricsBackend == MetricsBackendOTLP || c.MetricsBackend == MetricsBackendBoth
+}
+
+// ConstLabels returns the labels added to every metric: the auto labels,
+// overridden by the configured labels. If an auto label source cannot be
+// read, the labels that could be determined are returned with an error.
+func (c *Config) ConstLabels() (map[string]string, error) {
+	labels := make(map[string]string)
+	var errs []error
+	for _, source := range c.AutoLabels {
+		switch source {
+		case AutoLabelHostname:
+			hostname, err := c.Hostname()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to determine hostname label: %w", err))
+				continue
+			}
+			labels["hostname"] = hostname
+		case AutoLabelOSRelease:
+			info, err := osrelease.Read(c.Path(c.OSReleasePath))
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to determine os-release labels: %w", err))
+				continue
+			}
+			if id := info.ID(); id != "" {
+				labels["os_id"] = id
+			}
+			if versionID := info.VersionID(); versionID != "" {
+				labels["os_version_id"] = versionID
+			}
+		}
+	}
+	for name, value := range c.Labels {
+		labels[name] = value
+	}
+	return labels, errors.Join(errs...)
+}
+
+// Target returns the probe target with the given name.
+func (c *Config) Target(name string) (Target, bool) {
+	for _, target := range c.Targets {
+		if target.Name == name {
+			return target, true
+		}
+	}
+	return Target{}, false
 }
 
 // validate checks if the configuration is valid.
 func (c *Config) validate() error {
+	// Convert durations to the options in seconds they replace
+	durations := []struct {
+		name     string
+		duration *Duration
+		seconds  *int
+	}{
+		{"check_interval", &c.CheckInterval, &c.CheckIntervalSeconds},
+		{"command_timeout", &c.CommandTimeout, &c.CommandTimeoutSeconds},
+		{"watch_debounce", &c.WatchDebounce, &c.WatchDebounceSeconds},
+		{"manual_collect_min_interval", &c.ManualCollectMinInterval, &c.ManualCollectMinIntervalSeconds},
+		{"pushgateway.interval", &c.Pushgateway.Interval, &c.Pushgateway.IntervalSeconds},
+		{"remote_write.timeout", &c.RemoteWrite.Timeout, &c.RemoteWrite.TimeoutSeconds},
+		{"otlp.timeout", &c.OTLP.Timeout, &c.OTLP.TimeoutSeconds},
+	}
+	for _, d := range durations {
+		if *d.duration == 0 {
+			continue
+		}
+		if *d.duration < 0 {
+			return fmt.Errorf("%s cannot be negative", d.name)
+		}
+		if time.Duration(*d.duration)%time.Second != 0 {
+			return fmt.Errorf("%s must be a whole number of seconds", d.name)
+		}
+		*d.seconds = int(time.Duration(*d.duration) / time.Second)
+		*d.duration = 0
+	}
+
 	// Validate numeric values
 	if c.CheckIntervalSeconds <= 0 {
 		return fmt.Errorf("check_interval_seconds must be positive")
@@ -56,6 +501,15 @@ func (c *Config) validate() error {
 	if c.CommandTimeoutSeconds <= 0 {
 		return fmt.Errorf("command_timeout_seconds must be positive")
 	}
+	if c.WatchDebounceSeconds < 0 {
+		return fmt.Errorf("watch_debounce_seconds cannot be negative")
+	}
+	if c.ManualCollectMinIntervalSeconds < 0 {
+		return fmt.Errorf("manual_collect_min_interval_seconds cannot be negative")
+	}
+	if c.ReadyMaxIntervals < 0 {
+		return fmt.Errorf("ready_max_intervals cannot be negative")
+	}
 
 	// Validate string values
 	if c.ListenAddress == "" {
@@ -67,6 +521,52 @@ func (c *Config) validate() error {
 	if c.MetricPrefix == "" {
 		return fmt.Errorf("metric_prefix cannot be empty")
 	}
+	switch c.Naming {
+	case "":
+		c.Naming = DefaultNaming
+	case NamingV1, NamingV2, NamingDual:
+		// Valid naming schemes
+	default:
+		return fmt.Errorf("invalid naming: %s (must be one of: v1, v2, dual)", c.Naming)
+	}
+
+	// Validate labels, which probe targets share, against the labels of the
+	// metrics they are added to
+	metricLabels := metrics.LabelNames()
+	for name := range c.Labels {
+		if !labelNameRE.MatchString(name) || strings.HasPrefix(name, "__") {
+			return fmt.Errorf("invalid label name in labels: %s", name)
+		}
+		if metricLabels[name] {
+			return fmt.Errorf("label %s in labels clashes with a label of the exporter's metrics", name)
+		}
+	}
+	for _, source := range c.AutoLabels {
+		var names []string
+		switch source {
+		case AutoLabelHostname:
+			names = []string{"hostname"}
+		case AutoLabelOSRelease:
+			names = []string{"os_id", "os_version_id"}
+		default:
+			return fmt.Errorf("invalid auto_labels entry: %s (must be one of: hostname, os_release)", source)
+		}
+		for _, name := range names {
+			if metricLabels[name] {
+				return fmt.Errorf("auto label %s clashes with a label of the exporter's metrics", name)
+			}
+		}
+	}
+
+	// Validate security feed
+	if c.SecurityFeed.Path != "" {
+		switch c.SecurityFeed.Format {
+		case "debian", "osv":
+			// Valid formats
+		default:
+			return fmt.Errorf("invalid security_feed.format: %s (must be one of: debian, osv)", c.SecurityFeed.Format)
+		}
+	}
 
 	// Validate log level
 	switch c.LogLevel {
@@ -76,30 +576,183 @@ func (c *Config) validate() error {
 		return fmt.Errorf("invalid log_level: %s (must be one of: debug, info, warn, error)", c.LogLevel)
 	}
 
+	// Validate log format
+	switch c.LogFormat {
+	case "":
+		c.LogFormat = DefaultLogFormat
+	case "text", "json":
+		// Valid log formats
+	default:
+		return fmt.Errorf("invalid log_format: %s (must be one of: text, json)", c.LogFormat)
+	}
+
 	// Ensure metrics endpoint starts with a slash
 	if c.MetricsEndpoint[0] != '/' {
 		c.MetricsEndpoint = "/" + c.MetricsEndpoint
 	}
 
+	// Fall back to the standard lock files if none are configured
+	if len(c.DpkgLockFiles) == 0 {
+		c.DpkgLockFiles = append([]string(nil), DefaultDpkgLockFiles...)
+	}
+
+	// Fill in optional paths and settings
+	if c.DpkgStatusPath == "" {
+		c.DpkgStatusPath = DefaultDpkgStatusPath
+	}
+	if c.AptListsDir == "" {
+		c.AptListsDir = DefaultAptListsDir
+	}
+	if c.ReadyMaxIntervals == 0 {
+		c.ReadyMaxIntervals = DefaultReadyMaxIntervals
+	}
+	if c.OSReleasePath == "" {
+		c.OSReleasePath = DefaultOSReleasePath
+	}
+	if c.RootDir != "" {
+		rootDir, err := filepath.Abs(c.RootDir)
+		if err != nil {
+			return fmt.Errorf("failed to resolve root_dir: %w", err)
+		}
+		c.RootDir = rootDir
+	}
+
+	// Validate pushgateway
+	if c.Pushgateway.URL != "" {
+		if c.Pushgateway.IntervalSeconds < 0 {
+			return fmt.Errorf("pushgateway.interval_seconds cannot be negative")
+		}
+		if c.Pushgateway.Job == "" {
+			c.Pushgateway.Job = DefaultPushgatewayJob
+		}
+		if len(c.Pushgateway.Grouping) == 0 {
+			hostname, err := os.Hostname()
+			if err != nil {
+				return fmt.Errorf("failed to determine hostname for pushgateway.grouping: %w", err)
+			}
+			c.Pushgateway.Grouping = map[string]string{"instance": hostname}
+		}
+	}
+
+	// Validate remote write
+	if c.RemoteWrite.URL != "" {
+		if err := c.RemoteWrite.validate(); err != nil {
+			return err
+		}
+	}
+
+	// Validate metrics backend
+	switch c.MetricsBackend {
+	case "":
+		c.MetricsBackend = DefaultMetricsBackend
+	case MetricsBackendPrometheus, MetricsBackendOTLP, MetricsBackendBoth:
+		// Valid backends
+	default:
+		return fmt.Errorf("invalid metrics_backend: %s (must be one of: prometheus, otlp, both)", c.MetricsBackend)
+	}
+	if c.OTLPEnabled() {
+		if c.OTLP.TimeoutSeconds < 0 {
+			return fmt.Errorf("otlp.timeout_seconds cannot be negative")
+		}
+		if c.OTLP.Endpoint == "" {
+			c.OTLP.Endpoint = DefaultOTLPEndpoint
+		}
+		if c.OTLP.TimeoutSeconds == 0 {
+			c.OTLP.TimeoutSeconds = DefaultOTLPTimeoutSeconds
+		}
+	}
+
+	// Validate probe targets
+	names := make(map[string]bool, len(c.Targets))
+	for i, target := range c.Targets {
+		if target.Name == "" {
+			return fmt.Errorf("targets[%d].name cannot be empty", i)
+		}
+		if names[target.Name] {
+			return fmt.Errorf("duplicate target name: %s", target.Name)
+		}
+		names[target.Name] = true
+		if target.RootDir == "" {
+			return fmt.Errorf("targets[%d].root_dir cannot be empty", i)
+		}
+		rootDir, err := filepath.Abs(target.RootDir)
+		if err != nil {
+			return fmt.Errorf("failed to resolve root_dir of target %s: %w", target.Name, err)
+		}
+		c.Targets[i].RootDir = rootDir
+	}
+
+	return nil
+}
+
+// validate checks the remote write settings and fills in defaults.
+func (c *RemoteWriteConfig) validate() error {
+	if c.TimeoutSeconds < 0 {
+		return fmt.Errorf("remote_write.timeout_seconds cannot be negative")
+	}
+	if c.MaxRetries < 0 {
+		return fmt.Errorf("remote_write.max_retries cannot be negative")
+	}
+	if c.BufferMaxRequests < 0 {
+		return fmt.Errorf("remote_write.buffer_max_requests cannot be negative")
+	}
+	if c.Username != "" && c.BearerToken != "" {
+		return fmt.Errorf("remote_write.username and remote_write.bearer_token are mutually exclusive")
+	}
+
+	if c.TimeoutSeconds == 0 {
+		c.TimeoutSeconds = DefaultRemoteWriteTimeoutSeconds
+	}
+	if c.MaxRetries == 0 {
+		c.MaxRetries = DefaultRemoteWriteMaxRetries
+	}
+	if c.BufferMaxRequests == 0 {
+		c.BufferMaxRequests = DefaultRemoteWriteBufferMaxRequests
+	}
+
+	if c.ExternalLabels == nil {
+		c.ExternalLabels = make(map[string]string)
+	}
+	if _, ok := c.ExternalLabels["job"]; !ok {
+		c.ExternalLabels["job"] = DefaultRemoteWriteJob
+	}
+	if _, ok := c.ExternalLabels["instance"]; !ok {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return fmt.Errorf("failed to determine hostname for remote_write.external_labels: %w", err)
+		}
+		c.ExternalLabels["instance"] = hostname
+	}
+
 	return nil
 }
 
 // ValidateFilePaths checks if the file paths in the configuration exist.
 // This is separate from validate() because we may want to skip this check in tests.
+// Paths are checked within root_dir when one is configured.
 func (c *Config) ValidateFilePaths() error {
-	// Check if apt-check exists
-	if _, err := os.Stat(c.AptCheckPath); err != nil {
-		return fmt.Errorf("apt_check_path %s is not accessible: %w", c.AptCheckPath, err)
+	if c.Rebased() {
+		// Check if the root filesystem exists
+		if info, err := os.Stat(c.RootDir); err != nil {
+			return fmt.Errorf("root_dir %s is not accessible: %w", c.RootDir, err)
+		} else if !info.IsDir() {
+			return fmt.Errorf("root_dir %s is not a directory", c.RootDir)
+		}
+	} else {
+		// Check if apt-check exists; it is not run for other root filesystems
+		if _, err := os.Stat(c.AptCheckPath); err != nil {
+			return fmt.Errorf("apt_check_path %s is not accessible: %w", c.AptCheckPath, err)
+		}
 	}
 
 	// Check if update stamp directory exists (the file itself may not exist yet)
-	updateStampDir := filepath.Dir(c.UpdateStampPath)
+	updateStampDir := filepath.Dir(c.Path(c.UpdateStampPath))
 	if _, err := os.Stat(updateStampDir); err != nil {
 		return fmt.Errorf("update_stamp_path directory %s is not accessible: %w", updateStampDir, err)
 	}
 
 	// Check if reboot required directory exists (the file itself may not exist yet)
-	rebootRequiredDir := filepath.Dir(c.RebootRequiredFile)
+	rebootRequiredDir := filepath.Dir(c.Path(c.RebootRequiredFile))
 	if _, err := os.Stat(rebootRequiredDir); err != nil {
 		return fmt.Errorf("reboot_required_file directory %s is not accessible: %w", rebootRequiredDir, err)
 	}
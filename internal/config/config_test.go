@@ -3,7 +3,12 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 func TestLoad(t *testing.T) {
@@ -47,6 +52,120 @@ metric_prefix: "ubuntu"
 	}
 }
 
+func TestLoadDefaults(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(configPath, []byte("metric_prefix: \"debian\"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.CheckIntervalSeconds != DefaultCheckIntervalSeconds || cfg.CommandTimeoutSeconds != DefaultCommandTimeoutSeconds {
+		t.Errorf("Expected default interval and timeout, got %d and %d", cfg.CheckIntervalSeconds, cfg.CommandTimeoutSeconds)
+	}
+	if cfg.MetricPrefix != "debian" {
+		t.Errorf("Expected MetricPrefix from the file, got %s", cfg.MetricPrefix)
+	}
+	if cfg.WatchDebounceSeconds != DefaultWatchDebounceSeconds {
+		t.Errorf("Expected default WatchDebounceSeconds, got %d", cfg.WatchDebounceSeconds)
+	}
+	if cfg.ManualCollectMinIntervalSeconds != DefaultManualCollectMinIntervalSeconds {
+		t.Errorf("Expected default ManualCollectMinIntervalSeconds, got %d", cfg.ManualCollectMinIntervalSeconds)
+	}
+
+	// 0 disables the debounce period and rate limit rather than selecting the default
+	if err := os.WriteFile(configPath, []byte("watch_debounce_seconds: 0\nmanual_collect_min_interval_seconds: 0\n"), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+	cfg, err = Load(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.WatchDebounceSeconds != 0 {
+		t.Errorf("Expected WatchDebounceSeconds 0, got %d", cfg.WatchDebounceSeconds)
+	}
+	if cfg.ManualCollectMinIntervalSeconds != 0 {
+		t.Errorf("Expected ManualCollectMinIntervalSeconds 0, got %d", cfg.ManualCollectMinIntervalSeconds)
+	}
+}
+
+func TestLoadUnknownKey(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yml")
+	configContent := `check_interval_seconds: 300
+remote_write:
+  url: "https://mimir.example.com/api/v1/push"
+  timeout_secs: 10
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+
+	_, err := Load(configPath)
+	if err == nil || !strings.Contains(err.Error(), "line 4") || !strings.Contains(err.Error(), "timeout_secs") {
+		t.Errorf("Expected error naming the unknown key and its line, got %v", err)
+	}
+}
+
+func TestLoadDurations(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yml")
+	configContent := `check_interval: 5m
+watch_debounce: 1m30s
+pushgateway:
+  url: "http://pushgateway:9091"
+  interval: 1m
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Durations are converted to the options in seconds
+	if cfg.CheckIntervalSeconds != 300 {
+		t.Errorf("Expected CheckIntervalSeconds 300, got %d", cfg.CheckIntervalSeconds)
+	}
+	if cfg.WatchDebounceSeconds != 90 {
+		t.Errorf("Expected WatchDebounceSeconds 90, got %d", cfg.WatchDebounceSeconds)
+	}
+	if cfg.Pushgateway.IntervalSeconds != 60 {
+		t.Errorf("Expected Pushgateway IntervalSeconds 60, got %d", cfg.Pushgateway.IntervalSeconds)
+	}
+	if cfg.CheckInterval != 0 {
+		t.Errorf("Expected CheckInterval to be converted to seconds, got %v", cfg.CheckInterval)
+	}
+
+	// A duration and its option in seconds cannot both be set
+	for _, content := range []string{
+		"check_interval: 5m\ncheck_interval_seconds: 60\n",
+		"remote_write:\n  url: \"http://mimir:9009/api/v1/push\"\n  timeout: 10s\n  timeout_seconds: 10\n",
+	} {
+		if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write test config file: %v", err)
+		}
+		if _, err := Load(configPath); err == nil || !strings.Contains(err.Error(), "both set") {
+			t.Errorf("Expected error for both forms of a duration in\n%s\ngot %v", content, err)
+		}
+	}
+
+	if err := os.WriteFile(configPath, []byte("check_interval: soon\n"), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+	if _, err := Load(configPath); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("Expected invalid duration error with its line, got %v", err)
+	}
+}
+
+func TestLoadExampleConfig(t *testing.T) {
+	if _, err := Load("../../config.yml"); err != nil {
+		t.Errorf("Failed to load the example configuration: %v", err)
+	}
+}
+
 func TestValidate(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -137,6 +256,202 @@ func TestValidate(t *testing.T) {
 			},
 			expectError: true,
 		},
+		{
+			name: "Valid targets",
+			config: Config{
+				CheckIntervalSeconds:  300,
+				ListenAddress:         ":9100",
+				CommandTimeoutSeconds: 10,
+				MetricsEndpoint:       "/metrics",
+				MetricPrefix:          "ubuntu",
+				LogLevel:              "info",
+				Targets: []Target{
+					{Name: "web-1", RootDir: "/var/lib/machines/web-1"},
+					{Name: "web-2", RootDir: "/var/lib/machines/web-2"},
+				},
+			},
+			expectError: false,
+		},
+		{
+			name: "Duplicate target name",
+			config: Config{
+				CheckIntervalSeconds:  300,
+				ListenAddress:         ":9100",
+				CommandTimeoutSeconds: 10,
+				MetricsEndpoint:       "/metrics",
+				MetricPrefix:          "ubuntu",
+				LogLevel:              "info",
+				Targets: []Target{
+					{Name: "web-1", RootDir: "/var/lib/machines/web-1"},
+					{Name: "web-1", RootDir: "/var/lib/machines/web-2"},
+				},
+			},
+			expectError: true,
+		},
+		{
+			name: "Target without root directory",
+			config: Config{
+				CheckIntervalSeconds:  300,
+				ListenAddress:         ":9100",
+				CommandTimeoutSeconds: 10,
+				MetricsEndpoint:       "/metrics",
+				MetricPrefix:          "ubuntu",
+				LogLevel:              "info",
+				Targets:               []Target{{Name: "web-1"}},
+			},
+			expectError: true,
+		},
+		{
+			name: "Remote write with basic auth and bearer token",
+			config: Config{
+				CheckIntervalSeconds:  300,
+				ListenAddress:         ":9100",
+				CommandTimeoutSeconds: 10,
+				MetricsEndpoint:       "/metrics",
+				MetricPrefix:          "ubuntu",
+				LogLevel:              "info",
+				RemoteWrite: RemoteWriteConfig{
+					URL:         "https://mimir.example.com/api/v1/push",
+					Username:    "apt",
+					BearerToken: "token",
+				},
+			},
+			expectError: true,
+		},
+		{
+			name: "Negative remote write retries",
+			config: Config{
+				CheckIntervalSeconds:  300,
+				ListenAddress:         ":9100",
+				CommandTimeoutSeconds: 10,
+				MetricsEndpoint:       "/metrics",
+				MetricPrefix:          "ubuntu",
+				LogLevel:              "info",
+				RemoteWrite: RemoteWriteConfig{
+					URL:        "https://mimir.example.com/api/v1/push",
+					MaxRetries: -1,
+				},
+			},
+			expectError: true,
+		},
+		{
+			name: "Invalid metrics backend",
+			config: Config{
+				CheckIntervalSeconds:  300,
+				ListenAddress:         ":9100",
+				CommandTimeoutSeconds: 10,
+				MetricsEndpoint:       "/metrics",
+				MetricPrefix:          "ubuntu",
+				LogLevel:              "info",
+				MetricsBackend:        "graphite",
+			},
+			expectError: true,
+		},
+		{
+			name: "Invalid naming",
+			config: Config{
+				CheckIntervalSeconds:  300,
+				ListenAddress:         ":9100",
+				CommandTimeoutSeconds: 10,
+				MetricsEndpoint:       "/metrics",
+				MetricPrefix:          "ubuntu",
+				LogLevel:              "info",
+				Naming:                "v3",
+			},
+			expectError: true,
+		},
+		{
+			name: "Fractional duration",
+			config: Config{
+				CheckIntervalSeconds:  300,
+				CheckInterval:         Duration(1500 * time.Millisecond),
+				ListenAddress:         ":9100",
+				CommandTimeoutSeconds: 10,
+				MetricsEndpoint:       "/metrics",
+				MetricPrefix:          "ubuntu",
+				LogLevel:              "info",
+			},
+			expectError: true,
+		},
+		{
+			name: "Invalid label name",
+			config: Config{
+				CheckIntervalSeconds:  300,
+				ListenAddress:         ":9100",
+				CommandTimeoutSeconds: 10,
+				MetricsEndpoint:       "/metrics",
+				MetricPrefix:          "ubuntu",
+				LogLevel:              "info",
+				Labels:                map[string]string{"data-center": "fra"},
+			},
+			expectError: true,
+		},
+		{
+			name: "Label clashing with a metric label",
+			config: Config{
+				CheckIntervalSeconds:  300,
+				ListenAddress:         ":9100",
+				CommandTimeoutSeconds: 10,
+				MetricsEndpoint:       "/metrics",
+				MetricPrefix:          "ubuntu",
+				LogLevel:              "info",
+				Labels:                map[string]string{"severity": "high"},
+			},
+			expectError: true,
+		},
+		{
+			name: "Label clashing with a metric label of probe targets",
+			config: Config{
+				CheckIntervalSeconds:  300,
+				ListenAddress:         ":9100",
+				CommandTimeoutSeconds: 10,
+				MetricsEndpoint:       "/metrics",
+				MetricPrefix:          "ubuntu",
+				LogLevel:              "info",
+				Labels:                map[string]string{"lock": "none"},
+				Targets:               []Target{{Name: "web-1", RootDir: "/var/lib/machines/web-1"}},
+			},
+			expectError: true,
+		},
+		{
+			name: "Label clashing with histogram buckets",
+			config: Config{
+				CheckIntervalSeconds:  300,
+				ListenAddress:         ":9100",
+				CommandTimeoutSeconds: 10,
+				MetricsEndpoint:       "/metrics",
+				MetricPrefix:          "ubuntu",
+				LogLevel:              "info",
+				Labels:                map[string]string{"le": "1"},
+			},
+			expectError: true,
+		},
+		{
+			name: "Invalid auto label",
+			config: Config{
+				CheckIntervalSeconds:  300,
+				ListenAddress:         ":9100",
+				CommandTimeoutSeconds: 10,
+				MetricsEndpoint:       "/metrics",
+				MetricPrefix:          "ubuntu",
+				LogLevel:              "info",
+				AutoLabels:            []string{"kernel"},
+			},
+			expectError: true,
+		},
+		{
+			name: "Invalid log format",
+			config: Config{
+				CheckIntervalSeconds:  300,
+				ListenAddress:         ":9100",
+				CommandTimeoutSeconds: 10,
+				MetricsEndpoint:       "/metrics",
+				MetricPrefix:          "ubuntu",
+				LogLevel:              "info",
+				LogFormat:             "logfmt",
+			},
+			expectError: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -148,3 +463,83 @@ func TestValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestConstLabels(t *testing.T) {
+	rootDir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(rootDir, "etc"), 0755); err != nil {
+		t.Fatalf("Failed to create etc directory: %v", err)
+	}
+	osRelease := "ID=debian\nVERSION_ID=\"12\"\nVERSION_CODENAME=bookworm\n"
+	if err := os.WriteFile(filepath.Join(rootDir, "etc", "os-release"), []byte(osRelease), 0644); err != nil {
+		t.Fatalf("Failed to write os-release: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(rootDir, "etc", "hostname"), []byte("guest-1\n"), 0644); err != nil {
+		t.Fatalf("Failed to write hostname: %v", err)
+	}
+
+	cfg := Config{
+		RootDir:       rootDir,
+		OSReleasePath: DefaultOSReleasePath,
+		Labels:        map[string]string{"env": "prod", "os_version_id": "12.5"},
+		AutoLabels:    []string{AutoLabelHostname, AutoLabelOSRelease},
+	}
+	labels, err := cfg.ConstLabels()
+	if err != nil {
+		t.Fatalf("Failed to determine labels: %v", err)
+	}
+
+	// The hostname describes the root filesystem rather than the host
+	hostname := "guest-1"
+	want := map[string]string{"env": "prod", "hostname": hostname, "os_id": "debian", "os_version_id": "12.5"}
+	if !reflect.DeepEqual(labels, want) {
+		t.Errorf("Expected labels %v, got %v", want, labels)
+	}
+
+	// Labels that can be determined are returned along with the error
+	cfg.OSReleasePath = "/missing"
+	labels, err = cfg.ConstLabels()
+	if err == nil {
+		t.Error("Expected error for a missing os-release file, got nil")
+	}
+	if labels["env"] != "prod" || labels["hostname"] != hostname {
+		t.Errorf("Expected the other labels despite the error, got %v", labels)
+	}
+
+	// The host's own hostname is used without an alternate root filesystem
+	want["hostname"], _ = os.Hostname()
+	if got, err := (&Config{}).Hostname(); err != nil || got != want["hostname"] {
+		t.Errorf("Expected hostname %q, got %q (error: %v)", want["hostname"], got, err)
+	}
+}
+
+func TestSecretRedacted(t *testing.T) {
+	cfg := Config{
+		Pushgateway: PushgatewayConfig{URL: "http://pushgateway:9091", Username: "apt", Password: "hunter2"},
+		RemoteWrite: RemoteWriteConfig{URL: "http://mimir:9009/api/v1/push", Headers: map[string]Secret{"Authorization": "Bearer hunter3"}},
+		OTLP:        OTLPConfig{Headers: map[string]Secret{"X-Api-Key": "hunter4"}},
+	}
+
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		t.Fatalf("Failed to marshal config: %v", err)
+	}
+	for _, secret := range []string{"hunter2", "hunter3", "hunter4"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("Expected %s to be redacted, got:\n%s", secret, data)
+		}
+	}
+	if !strings.Contains(string(data), "password: <secret>") {
+		t.Errorf("Expected redacted password placeholder, got:\n%s", data)
+	}
+	if !strings.Contains(string(data), "Authorization: <secret>") {
+		t.Errorf("Expected redacted remote_write header placeholder, got:\n%s", data)
+	}
+
+	var loaded Config
+	if err := yaml.Unmarshal([]byte("pushgateway:\n  password: hunter2\n"), &loaded); err != nil {
+		t.Fatalf("Failed to unmarshal config: %v", err)
+	}
+	if loaded.Pushgateway.Password != "hunter2" {
+		t.Errorf("Expected password to be loaded, got %q", loaded.Pushgateway.Password)
+	}
+}
@@ -0,0 +1,260 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvPrefix starts the names of the environment variables that override
+// configuration fields, e.g. APT_EXPORTER_REMOTE_WRITE_URL for remote_write.url.
+const EnvPrefix = "APT_EXPORTER_"
+
+// Overrides holds configuration values given on the command line, keyed by
+// the YAML path of their field, e.g. "remote_write.url".
+type Overrides map[string]string
+
+// setting is a configuration field addressed by its YAML path.
+type setting struct {
+	path  string
+	value reflect.Value
+}
+
+// settings returns the fields of c that can be overridden, in declaration
+// order. Nested sections such as remote_write are flattened.
+func settings(c *Config) []setting {
+	var out []setting
+	collectSettings(reflect.ValueOf(c).Elem(), "", &out)
+	return out
+}
+
+func collectSettings(v reflect.Value, prefix string, out *[]setting) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		if field := v.Field(i); field.Kind() == reflect.Struct {
+			collectSettings(field, prefix+name+".", out)
+		} else {
+			*out = append(*out, setting{prefix + name, field})
+		}
+	}
+}
+
+// EnvName returns the environment variable overriding the field at path.
+func EnvName(path string) string {
+	return EnvPrefix + strings.ToUpper(strings.NewReplacer(".", "_").Replace(path))
+}
+
+// FlagName returns the command-line flag overriding the field at path.
+func FlagName(path string) string {
+	return strings.ReplaceAll(path, "_", "-")
+}
+
+// set parses value into the field. Lists are comma-separated, maps are
+// comma-separated key=value pairs and targets are name=root_dir pairs.
+func (s setting) set(value string) error {
+	v := s.value
+	if d, ok := v.Addr().Interface().(*Duration); ok {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q for %s", value, s.path)
+		}
+		*d = Duration(parsed)
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q for %s", value, s.path)
+		}
+		v.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q for %s", value, s.path)
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		if targets, ok := v.Addr().Interface().(*[]Target); ok {
+			pairs, err := splitPairs(value, s.path)
+			if err != nil {
+				return err
+			}
+			parsed := make([]Target, 0, len(pairs))
+			for _, pair := range pairs {
+				parsed = append(parsed, Target{Name: pair[0], RootDir: pair[1]})
+			}
+			*targets = parsed
+			return nil
+		}
+		items := splitList(value)
+		slice := reflect.MakeSlice(v.Type(), 0, len(items))
+		for _, item := range items {
+			slice = reflect.Append(slice, reflect.ValueOf(item).Convert(v.Type().Elem()))
+		}
+		v.Set(slice)
+	case reflect.Map:
+		pairs, err := splitPairs(value, s.path)
+		if err != nil {
+			return err
+		}
+		m := reflect.MakeMapWithSize(v.Type(), len(pairs))
+		for _, pair := range pairs {
+			m.SetMapIndex(reflect.ValueOf(pair[0]), reflect.ValueOf(pair[1]).Convert(v.Type().Elem()))
+		}
+		v.Set(m)
+	default:
+		return fmt.Errorf("%s cannot be overridden", s.path)
+	}
+	return nil
+}
+
+// checkDurationPairs reports an error if a duration and the option in
+// seconds it replaces, such as check_interval and check_interval_seconds,
+// were both given in the same source, since neither clearly wins.
+func checkDurationPairs(c *Config, source string, given map[string]bool) error {
+	for _, s := range settings(c) {
+		if _, ok := s.value.Addr().Interface().(*Duration); !ok {
+			continue
+		}
+		if given[s.path] && given[s.path+"_seconds"] {
+			return fmt.Errorf("%s and %s_seconds are both set in %s; set only one", s.path, s.path, source)
+		}
+	}
+	return nil
+}
+
+// splitList splits a comma-separated list, ignoring empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// splitPairs splits a comma-separated list of key=value pairs.
+func splitPairs(value, path string) ([][2]string, error) {
+	var pairs [][2]string
+	for _, item := range splitList(value) {
+		key, val, ok := strings.Cut(item, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid key=value pair %q for %s", item, path)
+		}
+		pairs = append(pairs, [2]string{strings.TrimSpace(key), strings.TrimSpace(val)})
+	}
+	return pairs, nil
+}
+
+// overrideFlag records the value of a configuration flag in Overrides.
+type overrideFlag struct {
+	overrides Overrides
+	setting   setting
+}
+
+func (f *overrideFlag) String() string {
+	return f.overrides[f.setting.path]
+}
+
+// Set checks that the value parses before recording it, so that flag
+// parsing reports invalid values.
+func (f *overrideFlag) Set(value string) error {
+	if err := f.setting.set(value); err != nil {
+		return err
+	}
+	f.overrides[f.setting.path] = value
+	return nil
+}
+
+func (f *overrideFlag) IsBoolFlag() bool {
+	return f.setting.value.Kind() == reflect.Bool
+}
+
+// RegisterFlags defines a flag for every configuration field on fs, named
+// after its YAML path, e.g. -remote-write.url. The values given are
+// recorded in the returned Overrides once fs is parsed.
+func RegisterFlags(fs *flag.FlagSet) Overrides {
+	overrides := make(Overrides)
+	// Values are parsed into a scratch configuration to check them
+	for _, s := range settings(&Config{}) {
+		usage := fmt.Sprintf("Overrides %s (environment variable %s)", s.path, EnvName(s.path))
+		fs.Var(&overrideFlag{overrides, s}, FlagName(s.path), usage)
+	}
+	return overrides
+}
+
+// LoadWithOverrides returns the configuration from the defaults, the YAML
+// file at path unless it is empty, the APT_EXPORTER_* variables of env and
+// the flag overrides, each taking precedence over the ones before.
+// Variables that do not name a configuration field are ignored.
+func LoadWithOverrides(path string, env []string, flags Overrides) (*Config, error) {
+	conf := Default()
+	if path != "" {
+		if err := conf.readFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	byPath := make(map[string]setting)
+	byEnvName := make(map[string]setting)
+	for _, s := range settings(conf) {
+		byPath[s.path] = s
+		byEnvName[EnvName(s.path)] = s
+	}
+	override := func(s setting, value string) error {
+		if err := s.set(value); err != nil {
+			return err
+		}
+		// An option in seconds replaces the duration from an earlier source
+		if path, ok := strings.CutSuffix(s.path, "_seconds"); ok {
+			if d, ok := byPath[path]; ok {
+				d.value.SetZero()
+			}
+		}
+		return nil
+	}
+
+	fromEnv := make(map[string]bool)
+	for _, kv := range env {
+		name, value, _ := strings.Cut(kv, "=")
+		if s, ok := byEnvName[name]; ok {
+			if err := override(s, value); err != nil {
+				return nil, fmt.Errorf("invalid environment variable %s: %w", name, err)
+			}
+			fromEnv[s.path] = true
+		}
+	}
+	if err := checkDurationPairs(conf, "the environment", fromEnv); err != nil {
+		return nil, err
+	}
+
+	fromFlags := make(map[string]bool)
+	for _, s := range settings(conf) {
+		if value, ok := flags[s.path]; ok {
+			if err := override(s, value); err != nil {
+				return nil, fmt.Errorf("invalid flag -%s: %w", FlagName(s.path), err)
+			}
+			fromFlags[s.path] = true
+		}
+	}
+	if err := checkDurationPairs(conf, "the flags", fromFlags); err != nil {
+		return nil, err
+	}
+
+	if err := conf.validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return conf, nil
+}
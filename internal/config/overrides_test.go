@@ -0,0 +1,184 @@
+package config
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadWithOverrides(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yml")
+	configContent := `check_interval_seconds: 600
+listen_address: ":9200"
+log_level: "debug"
+metric_prefix: "debian"
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+
+	env := []string{
+		"HOME=/root",
+		"APT_EXPORTER_LISTEN_ADDRESS=:9300",
+		"APT_EXPORTER_LOG_LEVEL=warn",
+		"APT_EXPORTER_DISABLE_FILE_WATCH=true",
+		"APT_EXPORTER_REMOTE_WRITE_URL=https://mimir.example.com/api/v1/push",
+		"APT_EXPORTER_REMOTE_WRITE_EXTERNAL_LABELS=job=apt, datacenter=fra",
+		"APT_EXPORTER_REMOTE_WRITE_HEADERS=X-Scope-OrgID=tenant1",
+		"APT_EXPORTER_DPKG_LOCK_FILES=/var/lib/dpkg/lock,/var/lib/apt/lists/lock",
+		"APT_EXPORTER_UNKNOWN=ignored",
+	}
+	flags := Overrides{"log_level": "error", "targets": "web-1=/var/lib/machines/web-1"}
+
+	cfg, err := LoadWithOverrides(configPath, env, flags)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	// The file overrides the defaults, the environment the file and the flags the environment
+	if cfg.CheckIntervalSeconds != 600 {
+		t.Errorf("Expected CheckIntervalSeconds from the file, got %d", cfg.CheckIntervalSeconds)
+	}
+	if cfg.ListenAddress != ":9300" {
+		t.Errorf("Expected ListenAddress from the environment, got %s", cfg.ListenAddress)
+	}
+	if cfg.LogLevel != "error" {
+		t.Errorf("Expected LogLevel from the flags, got %s", cfg.LogLevel)
+	}
+	if cfg.CommandTimeoutSeconds != DefaultCommandTimeoutSeconds {
+		t.Errorf("Expected default CommandTimeoutSeconds, got %d", cfg.CommandTimeoutSeconds)
+	}
+
+	if !cfg.DisableFileWatch {
+		t.Error("Expected DisableFileWatch from the environment")
+	}
+	if cfg.RemoteWrite.URL != "https://mimir.example.com/api/v1/push" {
+		t.Errorf("Expected nested remote_write.url from the environment, got %s", cfg.RemoteWrite.URL)
+	}
+	if cfg.RemoteWrite.ExternalLabels["job"] != "apt" || cfg.RemoteWrite.ExternalLabels["datacenter"] != "fra" {
+		t.Errorf("Expected external labels from the environment, got %v", cfg.RemoteWrite.ExternalLabels)
+	}
+	if cfg.RemoteWrite.Headers["X-Scope-OrgID"] != "tenant1" {
+		t.Errorf("Expected remote_write headers from the environment, got %v", cfg.RemoteWrite.Headers)
+	}
+	if want := []string{"/var/lib/dpkg/lock", "/var/lib/apt/lists/lock"}; !reflect.DeepEqual(cfg.DpkgLockFiles, want) {
+		t.Errorf("Expected lock files %v, got %v", want, cfg.DpkgLockFiles)
+	}
+	if len(cfg.Targets) != 1 || cfg.Targets[0].Name != "web-1" || cfg.Targets[0].RootDir != "/var/lib/machines/web-1" {
+		t.Errorf("Expected target web-1 from the flags, got %+v", cfg.Targets)
+	}
+}
+
+func TestLoadWithOverridesWithoutFile(t *testing.T) {
+	cfg, err := LoadWithOverrides("", nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to load default config: %v", err)
+	}
+
+	if cfg.CheckIntervalSeconds != DefaultCheckIntervalSeconds || cfg.ListenAddress != DefaultListenAddress {
+		t.Errorf("Expected defaults, got interval %d and address %s", cfg.CheckIntervalSeconds, cfg.ListenAddress)
+	}
+	if cfg.MetricsEndpoint != DefaultMetricsEndpoint || cfg.MetricPrefix != DefaultMetricPrefix {
+		t.Errorf("Expected default endpoint and prefix, got %s and %s", cfg.MetricsEndpoint, cfg.MetricPrefix)
+	}
+}
+
+func TestLoadWithOverridesInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		env   []string
+		flags Overrides
+	}{
+		{"Invalid integer", []string{"APT_EXPORTER_CHECK_INTERVAL_SECONDS=often"}, nil},
+		{"Invalid boolean", []string{"APT_EXPORTER_DISABLE_FILE_WATCH=maybe"}, nil},
+		{"Invalid map", []string{"APT_EXPORTER_PUSHGATEWAY_GROUPING=web-1"}, nil},
+		{"Invalid value after overrides", nil, Overrides{"check_interval_seconds": "0"}},
+		{"Duration and seconds in the environment", []string{"APT_EXPORTER_CHECK_INTERVAL=5m", "APT_EXPORTER_CHECK_INTERVAL_SECONDS=60"}, nil},
+		{"Duration and seconds in the flags", nil, Overrides{"otlp.timeout": "5s", "otlp.timeout_seconds": "5"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadWithOverrides("", tt.env, tt.flags); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestRegisterFlags(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	overrides := RegisterFlags(fs)
+
+	err := fs.Parse([]string{"-check-interval-seconds=60", "--remote-write.url", "https://mimir.example.com/api/v1/push", "-disable-file-watch"})
+	if err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+
+	want := Overrides{
+		"check_interval_seconds": "60",
+		"remote_write.url":       "https://mimir.example.com/api/v1/push",
+		"disable_file_watch":     "true",
+	}
+	if !reflect.DeepEqual(overrides, want) {
+		t.Errorf("Expected overrides %v, got %v", want, overrides)
+	}
+
+	if err := fs.Parse([]string{"-check-interval-seconds=often"}); err == nil {
+		t.Error("Expected error for an invalid flag value, got nil")
+	}
+}
+
+func TestEnvAndFlagNames(t *testing.T) {
+	if got := EnvName("remote_write.buffer_dir"); got != "APT_EXPORTER_REMOTE_WRITE_BUFFER_DIR" {
+		t.Errorf("Expected APT_EXPORTER_REMOTE_WRITE_BUFFER_DIR, got %s", got)
+	}
+	if got := FlagName("remote_write.buffer_dir"); got != "remote-write.buffer-dir" {
+		t.Errorf("Expected remote-write.buffer-dir, got %s", got)
+	}
+
+	// Every field has a kind that can be parsed
+	for _, s := range settings(Default()) {
+		switch s.value.Kind() {
+		case reflect.String, reflect.Int, reflect.Bool, reflect.Slice, reflect.Map:
+		default:
+			if _, ok := s.value.Addr().Interface().(*Duration); ok {
+				continue
+			}
+			t.Errorf("Expected %s to be overridable, got kind %s", s.path, s.value.Kind())
+		}
+	}
+}
+
+func TestLoadWithOverridesDurations(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yml")
+	configContent := `check_interval: 10m
+command_timeout: 30s
+`
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write test config file: %v", err)
+	}
+
+	env := []string{"APT_EXPORTER_CHECK_INTERVAL_SECONDS=60", "APT_EXPORTER_OTLP_TIMEOUT=5s"}
+	cfg, err := LoadWithOverrides(configPath, env, Overrides{"metrics_backend": "otlp"})
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	// The environment takes precedence over the file, whichever form either uses
+	if cfg.CheckIntervalSeconds != 60 {
+		t.Errorf("Expected CheckIntervalSeconds 60 from the environment, got %d", cfg.CheckIntervalSeconds)
+	}
+	if cfg.CommandTimeoutSeconds != 30 {
+		t.Errorf("Expected CommandTimeoutSeconds 30 from the file, got %d", cfg.CommandTimeoutSeconds)
+	}
+	if cfg.OTLP.TimeoutSeconds != 5 {
+		t.Errorf("Expected OTLP TimeoutSeconds 5 from the environment, got %d", cfg.OTLP.TimeoutSeconds)
+	}
+}
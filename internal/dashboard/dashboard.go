@@ -0,0 +1,342 @@
+// Package dashboard generates a Grafana dashboard for the metrics of the
+// exporter.
+package dashboard
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/ncecere/apt-exporter/internal/metrics"
+)
+
+// Defaults for the dashboard options.
+const (
+	DefaultTitle = "APT Updates Dashboard"
+	DefaultUID   = "apt-updates"
+)
+
+// Options configure the generated dashboard.
+type Options struct {
+	// Prefix and Naming select the metric names, as configured in the exporter
+	Prefix string
+	Naming metrics.Naming
+
+	Title string
+	UID   string
+}
+
+// Dashboard is the JSON model of a Grafana dashboard.
+type Dashboard struct {
+	Title         string     `json:"title"`
+	UID           string     `json:"uid"`
+	Tags          []string   `json:"tags"`
+	Editable      bool       `json:"editable"`
+	SchemaVersion int        `json:"schemaVersion"`
+	Refresh       string     `json:"refresh"`
+	Time          TimeRange  `json:"time"`
+	Templating    Templating `json:"templating"`
+	Panels        []Panel    `json:"panels"`
+}
+
+// TimeRange is the default time range of a dashboard.
+type TimeRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Templating holds the template variables of a dashboard.
+type Templating struct {
+	List []Variable `json:"list"`
+}
+
+// Variable is a template variable, selecting a data source or label values.
+type Variable struct {
+	Name       string      `json:"name"`
+	Label      string      `json:"label"`
+	Type       string      `json:"type"`
+	Query      any         `json:"query"`
+	Current    *Option     `json:"current,omitempty"`
+	Datasource *Datasource `json:"datasource,omitempty"`
+	Definition string      `json:"definition,omitempty"`
+	Refresh    int         `json:"refresh,omitempty"`
+	Multi      bool        `json:"multi,omitempty"`
+	IncludeAll bool        `json:"includeAll,omitempty"`
+	AllValue   string      `json:"allValue,omitempty"`
+	Sort       int         `json:"sort,omitempty"`
+}
+
+// Option is a value of a template variable.
+type Option struct {
+	Text  []string `json:"text"`
+	Value []string `json:"value"`
+}
+
+// Datasource refers to the data source queried by a panel or variable.
+type Datasource struct {
+	Type string `json:"type"`
+	UID  string `json:"uid"`
+}
+
+// Panel is a dashboard panel or, with type "row", a row of panels.
+type Panel struct {
+	ID              int              `json:"id"`
+	Type            string           `json:"type"`
+	Title           string           `json:"title"`
+	Description     string           `json:"description,omitempty"`
+	Datasource      *Datasource      `json:"datasource,omitempty"`
+	GridPos         GridPos          `json:"gridPos"`
+	Targets         []Target         `json:"targets,omitempty"`
+	FieldConfig     *FieldConfig     `json:"fieldConfig,omitempty"`
+	Options         map[string]any   `json:"options,omitempty"`
+	Transformations []Transformation `json:"transformations,omitempty"`
+	Collapsed       *bool            `json:"collapsed,omitempty"`
+	Panels          []Panel          `json:"panels,omitempty"`
+}
+
+// GridPos is the position and size of a panel on the 24 column grid.
+type GridPos struct {
+	H int `json:"h"`
+	W int `json:"w"`
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// Target is a Prometheus query of a panel.
+type Target struct {
+	RefID        string `json:"refId"`
+	Expr         string `json:"expr"`
+	LegendFormat string `json:"legendFormat,omitempty"`
+	Instant      bool   `json:"instant,omitempty"`
+	Format       string `json:"format,omitempty"`
+}
+
+// FieldConfig sets how the values of a panel are displayed.
+type FieldConfig struct {
+	Defaults  FieldDefaults `json:"defaults"`
+	Overrides []any         `json:"overrides"`
+}
+
+// FieldDefaults are the display settings of all fields of a panel.
+type FieldDefaults struct {
+	Unit       string      `json:"unit,omitempty"`
+	Thresholds *Thresholds `json:"thresholds,omitempty"`
+}
+
+// Thresholds color values from the step they reach.
+type Thresholds struct {
+	Mode  string          `json:"mode"`
+	Steps []ThresholdStep `json:"steps"`
+}
+
+// ThresholdStep is a color from a value on, the first step having no value.
+type ThresholdStep struct {
+	Color string   `json:"color"`
+	Value *float64 `json:"value"`
+}
+
+// Transformation transforms the results of a panel's queries.
+type Transformation struct {
+	ID      string         `json:"id"`
+	Options map[string]any `json:"options"`
+}
+
+// datasource is the data source chosen with the datasource variable.
+var datasource = &Datasource{Type: "prometheus", UID: "${datasource}"}
+
+// metricName returns the name a metric is exposed under. With both naming
+// schemes enabled, the dashboard uses the v2 name.
+func metricName(metric any) string {
+	descs := metrics.DescsOf(metric)
+	if len(descs) == 0 {
+		return ""
+	}
+	return descs[len(descs)-1].Name
+}
+
+// metricHelp returns the help text of a metric, used as panel description.
+func metricHelp(metric any) string {
+	if descs := metrics.DescsOf(metric); len(descs) > 0 {
+		return descs[0].Help
+	}
+	return ""
+}
+
+// selector restricts name to the instances and jobs selected on the dashboard.
+func selector(name string) string {
+	return name + `{job=~"$job", instance=~"$instance"}`
+}
+
+// thresholds colors values green below red, or only green if red is 0.
+func thresholds(red float64) *Thresholds {
+	steps := []ThresholdStep{{Color: "green"}}
+	if red > 0 {
+		steps = append(steps, ThresholdStep{Color: "red", Value: &red})
+	}
+	return &Thresholds{Mode: "absolute", Steps: steps}
+}
+
+// layout places panels left to right in rows of the 24 column grid.
+type layout struct {
+	panels []Panel
+	x, y   int
+	rowH   int
+}
+
+// add places p with width w and height h, starting a new line if it does not fit.
+func (l *layout) add(p Panel, w, h int) {
+	if l.x+w > 24 {
+		l.x, l.y, l.rowH = 0, l.y+l.rowH, 0
+	}
+	p.ID = len(l.panels) + 1
+	p.GridPos = GridPos{H: h, W: w, X: l.x, Y: l.y}
+	if p.Type != "row" {
+		p.Datasource = datasource
+	}
+	l.panels = append(l.panels, p)
+	l.x += w
+	l.rowH = max(l.rowH, h)
+}
+
+// row starts a row of panels titled title.
+func (l *layout) row(title string) {
+	if l.x > 0 {
+		l.x, l.y, l.rowH = 0, l.y+l.rowH, 0
+	}
+	collapsed := false
+	l.add(Panel{Type: "row", Title: title, Collapsed: &collapsed}, 24, 1)
+}
+
+// stat is a panel showing the single value of expr.
+func stat(title, description, expr string, red float64) Panel {
+	return Panel{
+		Type:        "stat",
+		Title:       title,
+		Description: description,
+		Targets:     []Target{{RefID: "A", Expr: expr, Instant: true}},
+		FieldConfig: &FieldConfig{Defaults: FieldDefaults{Thresholds: thresholds(red)}, Overrides: []any{}},
+		Options: map[string]any{
+			"colorMode":     "value",
+			"graphMode":     "none",
+			"reduceOptions": map[string]any{"calcs": []string{"lastNotNull"}, "fields": "", "values": false},
+		},
+	}
+}
+
+// table is a panel listing the series of expr by their labels, with the
+// value column titled valueTitle.
+func table(title, description, expr, valueTitle, unit string, red float64) Panel {
+	return Panel{
+		Type:        "table",
+		Title:       title,
+		Description: description,
+		Targets:     []Target{{RefID: "A", Expr: expr, Instant: true, Format: "table"}},
+		FieldConfig: &FieldConfig{Defaults: FieldDefaults{Unit: unit, Thresholds: thresholds(red)}, Overrides: []any{}},
+		Options: map[string]any{
+			"showHeader": true,
+			"sortBy":     []map[string]any{{"displayName": valueTitle, "desc": true}},
+		},
+		Transformations: []Transformation{{
+			ID: "organize",
+			Options: map[string]any{
+				"excludeByName": map[string]bool{"Time": true, "__name__": true},
+				"renameByName":  map[string]string{"Value": valueTitle},
+			},
+		}},
+	}
+}
+
+// timeseries is a panel graphing expr, with a series per legend.
+func timeseries(title, description, expr, legend, unit string) Panel {
+	return Panel{
+		Type:        "timeseries",
+		Title:       title,
+		Description: description,
+		Targets:     []Target{{RefID: "A", Expr: expr, LegendFormat: legend}},
+		FieldConfig: &FieldConfig{Defaults: FieldDefaults{Unit: unit}, Overrides: []any{}},
+		Options: map[string]any{
+			"legend":  map[string]any{"displayMode": "list", "placement": "bottom"},
+			"tooltip": map[string]any{"mode": "multi"},
+		},
+	}
+}
+
+// Generate returns a dashboard for the metrics named as by opts. The metric
+// names and panel descriptions are taken from the metric definitions.
+func Generate(opts Options) Dashboard {
+	m := metrics.NewDescribedMetrics(opts.Prefix, opts.Naming)
+	updates := metricName(m.UpdatesAvailable)
+	security := metricName(m.SecurityUpdatesAvailable)
+	reboot := metricName(m.RebootRequired)
+	vulnerabilities := metricName(m.Vulnerabilities)
+	oldestSecurity := metricName(m.OldestPendingSecurityUpdateAgeSeconds)
+	installed := metricName(m.PackagesInstalled)
+	hashInfo := metricName(m.PackageSetHashInfo)
+	lockHeld := metricName(m.DpkgLockHeld)
+	success := metricName(m.CollectionSuccess)
+	duration := metricName(m.CollectionDurationSeconds)
+	collectionErrors := metricName(m.CollectionErrors)
+	buildInfo := metricName(m.ExporterBuildInfo)
+
+	// v2 names expose the time of the last update rather than its age
+	var listsAge string
+	if timestamp := metricName(m.LastUpdateTimestamp); timestamp != "" {
+		listsAge = fmt.Sprintf("time() - (%s > 0)", selector(timestamp))
+	} else {
+		listsAge = selector(metricName(m.SecondsSinceLastUpdate))
+	}
+
+	var l layout
+	l.row("Fleet")
+	l.add(stat("Hosts", metricHelp(m.ExporterBuildInfo), fmt.Sprintf("count(%s)", selector(buildInfo)), 0), 6, 4)
+	l.add(stat("Hosts with security updates", metricHelp(m.SecurityUpdatesAvailable), fmt.Sprintf("count(%s > 0) or vector(0)", selector(security)), 1), 6, 4)
+	l.add(stat("Hosts requiring a reboot", metricHelp(m.RebootRequired), fmt.Sprintf("count(%s == 1) or vector(0)", selector(reboot)), 1), 6, 4)
+	l.add(stat("Hosts failing collection", metricHelp(m.CollectionSuccess), fmt.Sprintf("count(%s == 0) or vector(0)", selector(success)), 1), 6, 4)
+	l.add(table("Hosts by pending security updates", metricHelp(m.SecurityUpdatesAvailable), fmt.Sprintf("sort_desc(%s > 0)", selector(security)), "Security updates", "", 1), 12, 8)
+	l.add(table("Hosts requiring a reboot", metricHelp(m.RebootRequired), fmt.Sprintf("%s == 1", selector(reboot)), "Reboot required", "", 0), 12, 8)
+	l.add(table("Hosts by package list age", "Time since the last successful apt update", fmt.Sprintf("sort_desc(%s)", listsAge), "Lists age", "s", 604800), 12, 8)
+	l.add(table("Exporter versions", metricHelp(m.ExporterBuildInfo), fmt.Sprintf("count by (version, goversion) (%s)", selector(buildInfo)), "Hosts", "", 0), 12, 8)
+
+	l.row("Updates")
+	l.add(timeseries("Available updates", metricHelp(m.UpdatesAvailable), selector(updates), "{{instance}}", "short"), 12, 8)
+	l.add(timeseries("Security updates", metricHelp(m.SecurityUpdatesAvailable), selector(security), "{{instance}}", "short"), 12, 8)
+	l.add(timeseries("Time since last update", "Time since the last successful apt update", listsAge, "{{instance}}", "s"), 12, 8)
+	l.add(timeseries("Oldest pending security update", metricHelp(m.OldestPendingSecurityUpdateAgeSeconds), selector(oldestSecurity), "{{instance}}", "s"), 12, 8)
+	l.add(timeseries("Vulnerabilities by severity", metricHelp(m.Vulnerabilities), fmt.Sprintf("sum by (severity) (%s)", selector(vulnerabilities)), "{{severity}}", "short"), 24, 8)
+
+	l.row("Packages")
+	l.add(timeseries("Installed packages", metricHelp(m.PackagesInstalled), fmt.Sprintf("sum by (instance) (%s)", selector(installed)), "{{instance}}", "short"), 12, 8)
+	l.add(table("Hosts by package set", metricHelp(m.PackageSetHashInfo), fmt.Sprintf("count by (sha256) (%s)", selector(hashInfo)), "Hosts", "", 0), 12, 8)
+	l.add(timeseries("dpkg locks held", metricHelp(m.DpkgLockHeld), selector(lockHeld), "{{instance}} {{lock}}", "short"), 24, 8)
+
+	l.row("Collector")
+	l.add(timeseries("Collection duration (p95)", metricHelp(m.CollectionDurationSeconds), fmt.Sprintf("histogram_quantile(0.95, sum by (instance, le) (rate(%s[$__rate_interval])))", selector(duration+"_bucket")), "{{instance}}", "s"), 12, 8)
+	l.add(timeseries("Collection errors", metricHelp(m.CollectionErrors), fmt.Sprintf("sum by (instance, check) (increase(%s[$__rate_interval]))", selector(collectionErrors)), "{{instance}} {{check}}", "short"), 12, 8)
+
+	jobQuery := fmt.Sprintf("label_values(%s, job)", buildInfo)
+	all := &Option{Text: []string{"All"}, Value: []string{"$__all"}}
+	instanceQuery := fmt.Sprintf(`label_values(%s{job=~"$job"}, instance)`, buildInfo)
+	return Dashboard{
+		Title:         opts.Title,
+		UID:           opts.UID,
+		Tags:          []string{"apt", "apt-exporter"},
+		Editable:      true,
+		SchemaVersion: 39,
+		Refresh:       "1m",
+		Time:          TimeRange{From: "now-24h", To: "now"},
+		Templating: Templating{List: []Variable{
+			{Name: "datasource", Label: "Data source", Type: "datasource", Query: "prometheus"},
+			{Name: "job", Label: "Job", Type: "query", Datasource: datasource, Query: map[string]any{"query": jobQuery, "refId": "job"}, Current: all, Definition: jobQuery, Refresh: 2, Multi: true, IncludeAll: true, AllValue: ".*", Sort: 1},
+			{Name: "instance", Label: "Instance", Type: "query", Datasource: datasource, Query: map[string]any{"query": instanceQuery, "refId": "instance"}, Current: all, Definition: instanceQuery, Refresh: 2, Multi: true, IncludeAll: true, AllValue: ".*", Sort: 1},
+		}},
+		Panels: l.panels,
+	}
+}
+
+// Write writes d to w as indented JSON.
+func Write(w io.Writer, d Dashboard) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(d)
+}
@@ -0,0 +1,105 @@
+package dashboard
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ncecere/apt-exporter/internal/metrics"
+)
+
+// exprs returns the queries of the panels of d by panel title.
+func exprs(d Dashboard) map[string]string {
+	out := make(map[string]string)
+	for _, p := range d.Panels {
+		if len(p.Targets) > 0 {
+			out[p.Title] = p.Targets[0].Expr
+		}
+	}
+	return out
+}
+
+func TestGenerate(t *testing.T) {
+	d := Generate(Options{Prefix: "debian", Naming: metrics.NamingV1, Title: DefaultTitle, UID: DefaultUID})
+	queries := exprs(d)
+
+	want := map[string]string{
+		"Hosts by pending security updates": `sort_desc(debian_security_updates_available{job=~"$job", instance=~"$instance"} > 0)`,
+		"Hosts by package list age":         `sort_desc(debian_seconds_since_last_update{job=~"$job", instance=~"$instance"})`,
+		"Collection duration (p95)":         `histogram_quantile(0.95, sum by (instance, le) (rate(debian_collector_duration_seconds_bucket{job=~"$job", instance=~"$instance"}[$__rate_interval])))`,
+	}
+	for title, expr := range want {
+		if queries[title] != expr {
+			t.Errorf("Expected %s to query %q, got %q", title, expr, queries[title])
+		}
+	}
+
+	// Every query uses the configured prefix
+	for title, expr := range queries {
+		if !strings.Contains(expr, "debian_") {
+			t.Errorf("Expected %s to use the debian prefix, got %q", title, expr)
+		}
+	}
+
+	var names []string
+	for _, v := range d.Templating.List {
+		names = append(names, v.Name)
+	}
+	if strings.Join(names, ",") != "datasource,job,instance" {
+		t.Errorf("Expected datasource, job and instance variables, got %v", names)
+	}
+
+	// Panels have unique IDs and fit the grid
+	ids := make(map[int]bool)
+	for _, p := range d.Panels {
+		if ids[p.ID] {
+			t.Errorf("Duplicate panel ID %d", p.ID)
+		}
+		ids[p.ID] = true
+		if p.GridPos.X+p.GridPos.W > 24 {
+			t.Errorf("Panel %s exceeds the grid: %+v", p.Title, p.GridPos)
+		}
+	}
+}
+
+func TestGenerateV2(t *testing.T) {
+	queries := exprs(Generate(Options{Prefix: "ubuntu", Naming: metrics.NamingDual}))
+
+	if got, want := queries["Hosts by package list age"], `sort_desc(time() - (apt_update_last_success_timestamp_seconds{job=~"$job", instance=~"$instance"} > 0))`; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	if got := queries["Hosts requiring a reboot"]; !strings.HasPrefix(got, "node_reboot_required{") {
+		t.Errorf("Expected node_reboot_required, got %q", got)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, Generate(Options{Prefix: "ubuntu", Naming: metrics.NamingV1})); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if strings.Contains(buf.String(), `\u003e`) {
+		t.Error("Expected queries without escaped comparison operators")
+	}
+	var d map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &d); err != nil {
+		t.Fatalf("Failed to parse dashboard: %v", err)
+	}
+}
+
+func TestExampleDashboard(t *testing.T) {
+	// The example dashboard is generated with the default prefix and naming
+	want, err := os.ReadFile("../../dashboards/apt_exporter_dashboard.json")
+	if err != nil {
+		t.Fatalf("Failed to read example dashboard: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, Generate(Options{Prefix: "ubuntu", Naming: metrics.NamingV1, Title: DefaultTitle, UID: DefaultUID})); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Error("Example dashboard is out of date, regenerate it with: apt-exporter dashboard generate -prefix ubuntu -naming v1 -output dashboards/apt_exporter_dashboard.json")
+	}
+}
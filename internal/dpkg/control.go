@@ -0,0 +1,70 @@
+// Package dpkg reads the dpkg status database and Debian control files.
+package dpkg
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Paragraph is a single stanza of a Debian control file, keyed by field name.
+// Continuation lines of multi-line fields are joined with newlines.
+type Paragraph map[string]string
+
+// ReadParagraphs parses Debian control data (dpkg status, APT Packages
+// indices) and calls fn for every paragraph in order.
+// Parsing stops at the first error returned by fn.
+func ReadParagraphs(r io.Reader, fn func(Paragraph) error) error {
+	scanner := bufio.NewScanner(r)
+	// Description fields can be long, so allow generous line lengths
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+
+	var (
+		paragraph Paragraph
+		lastField string
+		lineNo    int
+	)
+	flush := func() error {
+		if len(paragraph) == 0 {
+			return nil
+		}
+		p := paragraph
+		paragraph, lastField = nil, ""
+		return fn(p)
+	}
+
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+
+		switch {
+		case strings.TrimSpace(line) == "":
+			if err := flush(); err != nil {
+				return err
+			}
+		case line[0] == ' ' || line[0] == '\t':
+			if lastField == "" {
+				return fmt.Errorf("line %d: continuation line without field", lineNo)
+			}
+			paragraph[lastField] += "\n" + strings.TrimSpace(line)
+		case line[0] == '#':
+			// Comments are allowed in some control files
+		default:
+			name, value, ok := strings.Cut(line, ":")
+			if !ok {
+				return fmt.Errorf("line %d: invalid field %q", lineNo, line)
+			}
+			if paragraph == nil {
+				paragraph = make(Paragraph)
+			}
+			lastField = strings.TrimSpace(name)
+			paragraph[lastField] = strings.TrimSpace(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	return flush()
+}
@@ -0,0 +1,89 @@
+package dpkg
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Package is a binary package recorded in the dpkg status database.
+type Package struct {
+	Name          string `json:"name"`
+	Version       string `json:"version"`
+	Architecture  string `json:"architecture"`
+	Source        string `json:"source"`
+	SourceVersion string `json:"source_version"`
+	Section       string `json:"section,omitempty"`
+	Priority      string `json:"priority,omitempty"`
+	Status        string `json:"-"`
+}
+
+// Installed reports whether the package is fully installed, as opposed to
+// removed with its configuration files left behind or half-installed.
+func (p Package) Installed() bool {
+	fields := strings.Fields(p.Status)
+	return len(fields) == 3 && fields[2] == "installed"
+}
+
+// PackageFromParagraph builds a Package from a status or Packages index paragraph.
+// The source package defaults to the binary package, as dpkg omits the
+// Source field when both share the same name and version.
+func PackageFromParagraph(p Paragraph) Package {
+	pkg := Package{
+		Name:          p["Package"],
+		Version:       p["Version"],
+		Architecture:  p["Architecture"],
+		Source:        p["Package"],
+		SourceVersion: p["Version"],
+		Section:       p["Section"],
+		Priority:      p["Priority"],
+		Status:        p["Status"],
+	}
+
+	// The Source field may carry a version when it differs: "Source: foo (1.2-3)"
+	if source := p["Source"]; source != "" {
+		name, version, ok := strings.Cut(source, " ")
+		pkg.Source = name
+		if ok {
+			pkg.SourceVersion = strings.Trim(strings.TrimSpace(version), "()")
+		}
+	}
+
+	return pkg
+}
+
+// ReadStatus reads the installed packages from a dpkg status file.
+// Packages that are not fully installed are skipped.
+func ReadStatus(path string) ([]Package, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dpkg status file: %w", err)
+	}
+	defer f.Close()
+
+	packages, err := ParseStatus(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse dpkg status file %s: %w", path, err)
+	}
+
+	return packages, nil
+}
+
+// ParseStatus parses the installed packages from dpkg status content.
+// Packages that are not fully installed are skipped.
+func ParseStatus(r io.Reader) ([]Package, error) {
+	var packages []Package
+	err := ReadParagraphs(r, func(p Paragraph) error {
+		pkg := PackageFromParagraph(p)
+		if pkg.Name != "" && pkg.Installed() {
+			packages = append(packages, pkg)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return packages, nil
+}
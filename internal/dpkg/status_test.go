@@ -0,0 +1,92 @@
+package dpkg
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testStatus = `Package: libssl3
+Status: install ok installed
+Priority: optional
+Section: libs
+Architecture: amd64
+Source: openssl
+Version: 3.0.2-0ubuntu1.10
+Description: Secure Sockets Layer toolkit - shared libraries
+ This package is part of the OpenSSL project's implementation of the SSL
+ and TLS cryptographic protocols.
+
+Package: bash
+Status: install ok installed
+Priority: required
+Section: shells
+Architecture: amd64
+Version: 5.1-6ubuntu1
+
+Package: libgcc-s1
+Status: install ok installed
+Architecture: amd64
+Source: gcc-12 (12.3.0-1ubuntu1~22.04)
+Version: 12.3.0-1ubuntu1~22.04
+
+Package: old-removed
+Status: deinstall ok config-files
+Architecture: amd64
+Version: 1.0-1
+`
+
+func TestReadStatus(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "status")
+	if err := os.WriteFile(path, []byte(testStatus), 0644); err != nil {
+		t.Fatalf("Failed to write status file: %v", err)
+	}
+
+	packages, err := ReadStatus(path)
+	if err != nil {
+		t.Fatalf("ReadStatus() returned error: %v", err)
+	}
+
+	if len(packages) != 3 {
+		t.Fatalf("Expected 3 installed packages, got %d: %+v", len(packages), packages)
+	}
+
+	want := []Package{
+		{Name: "libssl3", Version: "3.0.2-0ubuntu1.10", Architecture: "amd64", Source: "openssl", SourceVersion: "3.0.2-0ubuntu1.10", Section: "libs", Priority: "optional"},
+		{Name: "bash", Version: "5.1-6ubuntu1", Architecture: "amd64", Source: "bash", SourceVersion: "5.1-6ubuntu1", Section: "shells", Priority: "required"},
+		{Name: "libgcc-s1", Version: "12.3.0-1ubuntu1~22.04", Architecture: "amd64", Source: "gcc-12", SourceVersion: "12.3.0-1ubuntu1~22.04"},
+	}
+	for i, w := range want {
+		got := packages[i]
+		got.Status = ""
+		if got != w {
+			t.Errorf("Package %d = %+v, want %+v", i, got, w)
+		}
+	}
+}
+
+func TestReadParagraphs(t *testing.T) {
+	var paragraphs []Paragraph
+	err := ReadParagraphs(strings.NewReader(testStatus), func(p Paragraph) error {
+		paragraphs = append(paragraphs, p)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ReadParagraphs() returned error: %v", err)
+	}
+
+	if len(paragraphs) != 4 {
+		t.Fatalf("Expected 4 paragraphs, got %d", len(paragraphs))
+	}
+	description := paragraphs[0]["Description"]
+	if !strings.HasPrefix(description, "Secure Sockets Layer toolkit - shared libraries\nThis package") {
+		t.Errorf("Expected multi-line description to be joined, got %q", description)
+	}
+
+	// A continuation line without a field is invalid
+	err = ReadParagraphs(strings.NewReader(" orphan\n"), func(Paragraph) error { return nil })
+	if err == nil {
+		t.Error("Expected error for continuation line without field, got nil")
+	}
+}
@@ -0,0 +1,116 @@
+package dpkg
+
+import (
+	"strconv"
+	"strings"
+)
+
+// CompareVersions compares two Debian package versions following the rules of
+// dpkg --compare-versions. It returns -1 if a < b, 0 if a == b and 1 if a > b.
+func CompareVersions(a, b string) int {
+	epochA, upstreamA, revisionA := splitVersion(a)
+	epochB, upstreamB, revisionB := splitVersion(b)
+
+	if epochA != epochB {
+		if epochA < epochB {
+			return -1
+		}
+		return 1
+	}
+	if c := compareFragment(upstreamA, upstreamB); c != 0 {
+		return c
+	}
+	return compareFragment(revisionA, revisionB)
+}
+
+// splitVersion splits a version into epoch, upstream version and Debian revision.
+func splitVersion(v string) (int, string, string) {
+	v = strings.TrimSpace(v)
+
+	epoch := 0
+	if e, rest, ok := strings.Cut(v, ":"); ok {
+		if n, err := strconv.Atoi(e); err == nil {
+			epoch = n
+			v = rest
+		}
+	}
+
+	revision := ""
+	if i := strings.LastIndex(v, "-"); i >= 0 {
+		v, revision = v[:i], v[i+1:]
+	}
+
+	return epoch, v, revision
+}
+
+// compareFragment compares upstream versions or revisions by alternating
+// non-digit and digit parts, as dpkg's verrevcmp does.
+func compareFragment(a, b string) int {
+	for a != "" || b != "" {
+		// Compare the leading non-digit parts character by character
+		for (a != "" && !isDigit(a[0])) || (b != "" && !isDigit(b[0])) {
+			ac, bc := charOrder(a), charOrder(b)
+			if ac != bc {
+				if ac < bc {
+					return -1
+				}
+				return 1
+			}
+			a, b = a[1:], b[1:]
+		}
+
+		// Compare the leading digit parts numerically
+		var na, nb string
+		na, a = leadingDigits(a)
+		nb, b = leadingDigits(b)
+		na, nb = strings.TrimLeft(na, "0"), strings.TrimLeft(nb, "0")
+		if len(na) != len(nb) {
+			if len(na) < len(nb) {
+				return -1
+			}
+			return 1
+		}
+		if na != nb {
+			if na < nb {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// charOrder returns the sort weight of the first character of s within a
+// non-digit part: the end of the part sorts before everything except a
+// tilde, letters sort before non-letters.
+func charOrder(s string) int {
+	if s == "" || isDigit(s[0]) {
+		return 0
+	}
+	c := s[0]
+	switch {
+	case c == '~':
+		return -1
+	case isLetter(c):
+		return int(c)
+	default:
+		return int(c) + 256
+	}
+}
+
+// leadingDigits splits s into its leading digits and the remainder.
+func leadingDigits(s string) (string, string) {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
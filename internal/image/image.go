@@ -0,0 +1,534 @@
+// Package image reads the dpkg status database and os-release file of
+// container images saved with "docker save" or in OCI image layout, without
+// a container runtime. Layers are read in order and whiteouts are applied,
+// but only the few files of interest are kept in memory.
+package image
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ncecere/apt-exporter/internal/dpkg"
+	"github.com/ncecere/apt-exporter/internal/osrelease"
+)
+
+// Paths of interest within the image, without the leading slash.
+const (
+	statusPath        = "var/lib/dpkg/status"
+	statusDir         = "var/lib/dpkg/status.d/"
+	osReleasePath     = "etc/os-release"
+	osReleaseFallback = "usr/lib/os-release"
+)
+
+// maxSymlinks bounds the number of symlinks followed to find a file.
+const maxSymlinks = 16
+
+// maxIndexDepth bounds the nesting of OCI image indices.
+const maxIndexDepth = 4
+
+// Image is the package information extracted from a container image.
+type Image struct {
+	// Reference is the first tag or OCI reference name of the image, if any
+	Reference string
+	OS        osrelease.Info
+	Packages  []dpkg.Package
+}
+
+// Open reads an image tarball written by "docker save" or containing an OCI image layout.
+func Open(path string) (*Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open image: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat image: %w", err)
+	}
+
+	img, err := Read(f, info.Size())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image %s: %w", path, err)
+	}
+	return img, nil
+}
+
+// Read reads an image tarball of the given size.
+func Read(r io.ReaderAt, size int64) (*Image, error) {
+	a, err := indexArchive(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	var reference string
+	var layers []string
+	switch {
+	case a.has("manifest.json"):
+		reference, layers, err = a.dockerLayers()
+	case a.has("index.json"):
+		reference, layers, err = a.ociLayers()
+	default:
+		err = errors.New("neither manifest.json nor index.json found, not a docker save or OCI layout tarball")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	fs := make(layerFS)
+	for _, layer := range layers {
+		if err := a.applyLayer(fs, layer); err != nil {
+			return nil, fmt.Errorf("failed to read layer %s: %w", layer, err)
+		}
+	}
+
+	packages, err := fs.packages()
+	if err != nil {
+		return nil, err
+	}
+	osInfo, err := fs.osRelease()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Image{Reference: reference, OS: osInfo, Packages: packages}, nil
+}
+
+// archive is an index of the regular files of the outer image tarball.
+type archive struct {
+	entries map[string]*io.SectionReader
+}
+
+// indexArchive records the location of every regular file in the tarball,
+// so that blobs can be read in any order without extracting them.
+func indexArchive(r io.ReaderAt, size int64) (*archive, error) {
+	sr := io.NewSectionReader(r, 0, size)
+	tr := tar.NewReader(sr)
+
+	a := &archive{entries: make(map[string]*io.SectionReader)}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read image tarball: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		// The file data starts right after its header
+		offset, err := sr.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, err
+		}
+		a.entries[cleanName(hdr.Name)] = io.NewSectionReader(r, offset, hdr.Size)
+	}
+
+	return a, nil
+}
+
+// has reports whether the tarball contains the named file.
+func (a *archive) has(name string) bool {
+	_, ok := a.entries[name]
+	return ok
+}
+
+// open returns a reader for the named file.
+func (a *archive) open(name string) (*io.SectionReader, error) {
+	entry, ok := a.entries[cleanName(name)]
+	if !ok {
+		return nil, fmt.Errorf("%s not found in image tarball", name)
+	}
+	return io.NewSectionReader(entry, 0, entry.Size()), nil
+}
+
+// decodeJSON decodes the named JSON file of the tarball.
+func (a *archive) decodeJSON(name string, v any) error {
+	r, err := a.open(name)
+	if err != nil {
+		return err
+	}
+	if err := json.NewDecoder(r).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	return nil
+}
+
+// dockerLayers returns the layers of the first image in a "docker save" tarball.
+func (a *archive) dockerLayers() (string, []string, error) {
+	var manifest []struct {
+		RepoTags []string
+		Layers   []string
+	}
+	if err := a.decodeJSON("manifest.json", &manifest); err != nil {
+		return "", nil, err
+	}
+	if len(manifest) == 0 {
+		return "", nil, errors.New("manifest.json lists no images")
+	}
+
+	var reference string
+	if len(manifest[0].RepoTags) > 0 {
+		reference = manifest[0].RepoTags[0]
+	}
+	return reference, manifest[0].Layers, nil
+}
+
+// OCI media types of image indices and manifests.
+const (
+	ociIndexMediaType    = "application/vnd.oci.image.index.v1+json"
+	dockerListMediaType  = "application/vnd.docker.distribution.manifest.list.v2+json"
+	ociRefNameAnnotation = "org.opencontainers.image.ref.name"
+)
+
+// ociDescriptor references a blob of an OCI image layout.
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Annotations map[string]string `json:"annotations"`
+	Platform    *struct {
+		OS           string `json:"os"`
+		Architecture string `json:"architecture"`
+	} `json:"platform"`
+}
+
+// blobPath returns the location of a blob within the layout, e.g. blobs/sha256/<hex>.
+func (d ociDescriptor) blobPath() (string, error) {
+	algorithm, hex, ok := strings.Cut(d.Digest, ":")
+	if !ok || algorithm == "" || hex == "" || strings.ContainsAny(d.Digest, "/\\") {
+		return "", fmt.Errorf("invalid digest %q", d.Digest)
+	}
+	return "blobs/" + algorithm + "/" + hex, nil
+}
+
+// ociLayers returns the layers of the first image in an OCI image layout.
+// For multi-platform images, the manifest for the current architecture is
+// preferred, falling back to the first one.
+func (a *archive) ociLayers() (string, []string, error) {
+	var index struct {
+		Manifests []ociDescriptor `json:"manifests"`
+	}
+	if err := a.decodeJSON("index.json", &index); err != nil {
+		return "", nil, err
+	}
+	if len(index.Manifests) == 0 {
+		return "", nil, errors.New("index.json lists no manifests")
+	}
+
+	desc := selectPlatform(index.Manifests)
+	reference := desc.Annotations[ociRefNameAnnotation]
+	for depth := 0; desc.MediaType == ociIndexMediaType || desc.MediaType == dockerListMediaType; depth++ {
+		if depth == maxIndexDepth {
+			return "", nil, errors.New("image indices are nested too deeply")
+		}
+		blob, err := desc.blobPath()
+		if err != nil {
+			return "", nil, err
+		}
+		var nested struct {
+			Manifests []ociDescriptor `json:"manifests"`
+		}
+		if err := a.decodeJSON(blob, &nested); err != nil {
+			return "", nil, err
+		}
+		if len(nested.Manifests) == 0 {
+			return "", nil, fmt.Errorf("image index %s lists no manifests", desc.Digest)
+		}
+		desc = selectPlatform(nested.Manifests)
+	}
+
+	blob, err := desc.blobPath()
+	if err != nil {
+		return "", nil, err
+	}
+	var manifest struct {
+		Layers []ociDescriptor `json:"layers"`
+	}
+	if err := a.decodeJSON(blob, &manifest); err != nil {
+		return "", nil, err
+	}
+
+	layers := make([]string, 0, len(manifest.Layers))
+	for _, layer := range manifest.Layers {
+		blob, err := layer.blobPath()
+		if err != nil {
+			return "", nil, err
+		}
+		layers = append(layers, blob)
+	}
+	return reference, layers, nil
+}
+
+// selectPlatform picks the linux manifest for the current architecture, or the first one.
+func selectPlatform(manifests []ociDescriptor) ociDescriptor {
+	for _, m := range manifests {
+		if m.Platform != nil && m.Platform.OS == "linux" && m.Platform.Architecture == runtime.GOARCH {
+			return m
+		}
+	}
+	return manifests[0]
+}
+
+// layerFS holds the files of interest of the image as layers are applied.
+type layerFS map[string]*layerFile
+
+// layerFile is a file or symlink of interest within the image.
+type layerFile struct {
+	data []byte
+	link string // symlink target, empty for regular files
+}
+
+// Whiteout markers used by layers to delete files of lower layers.
+const (
+	whiteoutPrefix = ".wh."
+	whiteoutOpaque = ".wh..wh..opq"
+)
+
+// applyLayer applies a layer on top of the files of the lower layers.
+func (a *archive) applyLayer(fs layerFS, name string) error {
+	// Whiteouts only apply to lower layers, so they are collected first
+	added := make(layerFS)
+	var removed, opaque []string
+	// links maps hard link targets that are not of interest to the links of interest
+	links := make(map[string][]string)
+
+	err := a.readLayer(name, func(hdr *tar.Header, r io.Reader) error {
+		name := cleanName(hdr.Name)
+		dir, base := path.Split(name)
+		switch {
+		case base == whiteoutOpaque:
+			opaque = append(opaque, dir)
+			return nil
+		case strings.HasPrefix(base, whiteoutPrefix):
+			removed = append(removed, dir+strings.TrimPrefix(base, whiteoutPrefix))
+			return nil
+		case !wanted(name):
+			return nil
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeReg:
+			data, err := io.ReadAll(r)
+			if err != nil {
+				return err
+			}
+			added[name] = &layerFile{data: data}
+		case tar.TypeSymlink:
+			added[name] = &layerFile{link: hdr.Linkname}
+		case tar.TypeLink:
+			target := cleanName(hdr.Linkname)
+			if f, ok := added[target]; ok {
+				added[name] = f
+			} else {
+				links[target] = append(links[target], name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Hard links to files that are not of interest, such as a status file
+	// written under a temporary name, need another pass to read the target
+	if len(links) > 0 {
+		err := a.readLayer(name, func(hdr *tar.Header, r io.Reader) error {
+			names, ok := links[cleanName(hdr.Name)]
+			if !ok || hdr.Typeflag != tar.TypeReg {
+				return nil
+			}
+			data, err := io.ReadAll(r)
+			if err != nil {
+				return err
+			}
+			for _, name := range names {
+				added[name] = &layerFile{data: data}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, dir := range opaque {
+		for file := range fs {
+			if strings.HasPrefix(file, dir) {
+				delete(fs, file)
+			}
+		}
+	}
+	for _, target := range removed {
+		for file := range fs {
+			if file == target || strings.HasPrefix(file, target+"/") {
+				delete(fs, file)
+			}
+		}
+	}
+	for name, f := range added {
+		fs[name] = f
+	}
+
+	return nil
+}
+
+// readLayer calls fn for every entry of a layer, which may be uncompressed,
+// gzip or zstd compressed.
+func (a *archive) readLayer(name string, fn func(*tar.Header, io.Reader) error) error {
+	blob, err := a.open(name)
+	if err != nil {
+		return err
+	}
+	r, err := decompress(bufio.NewReader(blob))
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err := fn(hdr, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// read returns the content of a file, following symlinks within the image.
+func (fs layerFS) read(name string) ([]byte, bool) {
+	for i := 0; i < maxSymlinks; i++ {
+		f, ok := fs[name]
+		if !ok {
+			return nil, false
+		}
+		if f.link == "" {
+			return f.data, true
+		}
+		if path.IsAbs(f.link) {
+			name = cleanName(f.link)
+		} else {
+			name = cleanName(path.Join(path.Dir(name), f.link))
+		}
+	}
+	return nil, false
+}
+
+// packages returns the installed packages recorded in the dpkg status
+// database. Distroless images have no status file, but one file per package
+// in status.d, whose entries may omit the Status field.
+func (fs layerFS) packages() ([]dpkg.Package, error) {
+	var packages []dpkg.Package
+	found := false
+
+	if data, ok := fs.read(statusPath); ok {
+		found = true
+		parsed, err := dpkg.ParseStatus(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse /%s: %w", statusPath, err)
+		}
+		packages = append(packages, parsed...)
+	}
+
+	var names []string
+	for name := range fs {
+		if strings.HasPrefix(name, statusDir) && !strings.HasSuffix(name, ".md5sums") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		data, ok := fs.read(name)
+		if !ok {
+			continue
+		}
+		found = true
+		err := dpkg.ReadParagraphs(bytes.NewReader(data), func(p dpkg.Paragraph) error {
+			pkg := dpkg.PackageFromParagraph(p)
+			if pkg.Name != "" && (pkg.Status == "" || pkg.Installed()) {
+				packages = append(packages, pkg)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse /%s: %w", name, err)
+		}
+	}
+
+	if !found {
+		return nil, errors.New("no dpkg status database found in image")
+	}
+	return packages, nil
+}
+
+// osRelease returns the operating system identification of the image, which
+// is empty if the image has no os-release file.
+func (fs layerFS) osRelease() (osrelease.Info, error) {
+	for _, name := range []string{osReleasePath, osReleaseFallback} {
+		data, ok := fs.read(name)
+		if !ok {
+			continue
+		}
+		info, err := osrelease.Parse(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse /%s: %w", name, err)
+		}
+		return info, nil
+	}
+	return osrelease.Info{}, nil
+}
+
+// wanted reports whether a file of a layer needs to be kept.
+func wanted(name string) bool {
+	return name == statusPath || strings.HasPrefix(name, statusDir) ||
+		name == osReleasePath || name == osReleaseFallback
+}
+
+// cleanName normalizes a tar entry name to a relative slash-separated path.
+func cleanName(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+// Compressed layer signatures.
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// decompress detects the compression of a layer from its first bytes.
+func decompress(r *bufio.Reader) (io.ReadCloser, error) {
+	magic, _ := r.Peek(len(zstdMagic))
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress layer: %w", err)
+		}
+		return gz, nil
+	case bytes.HasPrefix(magic, zstdMagic):
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress layer: %w", err)
+		}
+		return zr.IOReadCloser(), nil
+	default:
+		return io.NopCloser(r), nil
+	}
+}
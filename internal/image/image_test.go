@@ -0,0 +1,218 @@
+package image
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// tarEntry is a file, symlink or hard link of a test tarball.
+type tarEntry struct {
+	name     string
+	content  string
+	link     string
+	typeflag byte
+}
+
+// file, symlink and hardlink create tar entries.
+func file(name, content string) tarEntry {
+	return tarEntry{name: name, content: content, typeflag: tar.TypeReg}
+}
+func symlink(name, link string) tarEntry {
+	return tarEntry{name: name, link: link, typeflag: tar.TypeSymlink}
+}
+func hardlink(name, link string) tarEntry {
+	return tarEntry{name: name, link: link, typeflag: tar.TypeLink}
+}
+
+// buildTar returns a tarball of the entries.
+func buildTar(t *testing.T, entries ...tarEntry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Linkname: e.link, Typeflag: e.typeflag, Mode: 0644, Size: int64(len(e.content))}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("Failed to write tar header: %v", err)
+		}
+		if _, err := tw.Write([]byte(e.content)); err != nil {
+			t.Fatalf("Failed to write tar content: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Failed to close tar writer: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// writeImage writes an image tarball of the entries to a temporary file.
+func writeImage(t *testing.T, entries ...tarEntry) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "image.tar")
+	if err := os.WriteFile(path, buildTar(t, entries...), 0644); err != nil {
+		t.Fatalf("Failed to write image: %v", err)
+	}
+	return path
+}
+
+const (
+	baseStatus = "Package: base-files\nStatus: install ok installed\nArchitecture: amd64\nVersion: 12.4+deb12u5\n\n" +
+		"Package: openssl\nStatus: install ok installed\nArchitecture: amd64\nVersion: 3.0.11-1~deb12u1\n\n" +
+		"Package: curl\nStatus: install ok installed\nArchitecture: amd64\nVersion: 7.88.1-10+deb12u5\n"
+	osRelease = "PRETTY_NAME=\"Debian GNU/Linux 12 (bookworm)\"\nID=debian\nVERSION_ID=\"12\"\nVERSION_CODENAME=bookworm\n"
+)
+
+func TestOpenDockerSave(t *testing.T) {
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write(buildTar(t,
+		file("usr/lib/os-release", osRelease),
+		symlink("etc/os-release", "../usr/lib/os-release"),
+		file("var/lib/dpkg/status", baseStatus),
+	))
+	zw.Close()
+
+	// The second layer removes curl by replacing the status file and deletes
+	// a file that only existed in the lower layer
+	upperStatus := "Package: base-files\nStatus: install ok installed\nArchitecture: amd64\nVersion: 12.4+deb12u5\n\n" +
+		"Package: openssl\nStatus: install ok installed\nArchitecture: amd64\nVersion: 3.0.11-1~deb12u1\n\n" +
+		"Package: curl\nStatus: deinstall ok config-files\nArchitecture: amd64\nVersion: 7.88.1-10+deb12u5\n"
+	upper := buildTar(t,
+		file("./var/lib/dpkg/status-new", upperStatus),
+		hardlink("./var/lib/dpkg/status", "var/lib/dpkg/status-new"),
+		file("etc/.wh.hostname", ""),
+	)
+
+	manifest := `[{"Config": "config.json", "RepoTags": ["example/app:1.0"], "Layers": ["lower/layer.tar", "upper/layer.tar"]}]`
+	path := writeImage(t,
+		file("manifest.json", manifest),
+		file("lower/layer.tar", gz.String()),
+		file("upper/layer.tar", string(upper)),
+	)
+
+	img, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() returned error: %v", err)
+	}
+
+	if img.Reference != "example/app:1.0" {
+		t.Errorf("Expected reference example/app:1.0, got %q", img.Reference)
+	}
+	if img.OS.Codename() != "bookworm" {
+		t.Errorf("Expected os-release to be read through its symlink, got %v", img.OS)
+	}
+	if len(img.Packages) != 2 || img.Packages[0].Name != "base-files" || img.Packages[1].Name != "openssl" {
+		t.Errorf("Expected base-files and openssl from the upper layer, got %+v", img.Packages)
+	}
+}
+
+func TestOpenOCILayout(t *testing.T) {
+	// A distroless-style image with one status file per package
+	lower := buildTar(t,
+		file("etc/os-release", osRelease),
+		file("var/lib/dpkg/status.d/base", "Package: base-files\nArchitecture: amd64\nVersion: 12.4+deb12u5\n"),
+		file("var/lib/dpkg/status.d/base.md5sums", "d41d8cd98f00b204e9800998ecf8427e  etc/debian_version\n"),
+		file("var/lib/dpkg/status.d/libssl3", "Package: libssl3\nArchitecture: amd64\nVersion: 3.0.11-1~deb12u1\n"),
+	)
+	// The opaque whiteout hides everything status.d held in lower layers
+	var zst bytes.Buffer
+	zw, err := zstd.NewWriter(&zst)
+	if err != nil {
+		t.Fatalf("Failed to create zstd writer: %v", err)
+	}
+	zw.Write(buildTar(t,
+		file("var/lib/dpkg/status.d/.wh..wh..opq", ""),
+		file("var/lib/dpkg/status.d/tzdata", "Package: tzdata\nArchitecture: all\nVersion: 2024a-0+deb12u1\n"),
+	))
+	zw.Close()
+
+	blobs := map[string][]byte{}
+	digest := func(data []byte) string {
+		sum := sha256.Sum256(data)
+		d := "sha256:" + hex.EncodeToString(sum[:])
+		blobs[d] = data
+		return d
+	}
+	descriptor := func(mediaType, d string, extra map[string]any) map[string]any {
+		desc := map[string]any{"mediaType": mediaType, "digest": d}
+		for k, v := range extra {
+			desc[k] = v
+		}
+		return desc
+	}
+	mustJSON := func(v any) []byte {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("Failed to encode JSON: %v", err)
+		}
+		return data
+	}
+
+	manifest := digest(mustJSON(map[string]any{
+		"schemaVersion": 2,
+		"layers": []any{
+			descriptor("application/vnd.oci.image.layer.v1.tar", digest(lower), nil),
+			descriptor("application/vnd.oci.image.layer.v1.tar+zstd", digest(zst.Bytes()), nil),
+		},
+	}))
+	platformIndex := digest(mustJSON(map[string]any{
+		"schemaVersion": 2,
+		"manifests": []any{
+			descriptor("application/vnd.oci.image.manifest.v1+json", manifest, nil),
+		},
+	}))
+	index := mustJSON(map[string]any{
+		"schemaVersion": 2,
+		"manifests": []any{
+			descriptor(ociIndexMediaType, platformIndex, map[string]any{
+				"annotations": map[string]string{ociRefNameAnnotation: "distroless:latest"},
+			}),
+		},
+	})
+
+	entries := []tarEntry{file("oci-layout", `{"imageLayoutVersion": "1.0.0"}`), file("index.json", string(index))}
+	for d, data := range blobs {
+		entries = append(entries, file("blobs/sha256/"+d[len("sha256:"):], string(data)))
+	}
+	img, err := Open(writeImage(t, entries...))
+	if err != nil {
+		t.Fatalf("Open() returned error: %v", err)
+	}
+
+	if img.Reference != "distroless:latest" {
+		t.Errorf("Expected reference distroless:latest, got %q", img.Reference)
+	}
+	if len(img.Packages) != 1 || img.Packages[0].Name != "tzdata" {
+		t.Errorf("Expected only tzdata after the opaque whiteout, got %+v", img.Packages)
+	}
+	if img.OS.ID() != "debian" {
+		t.Errorf("Expected os-release of debian, got %v", img.OS)
+	}
+}
+
+func TestOpenInvalid(t *testing.T) {
+	tests := map[string][]tarEntry{
+		"not an image": {file("hello.txt", "hello")},
+		"no dpkg":      {file("manifest.json", `[{"Layers": ["layer.tar"]}]`), file("layer.tar", "")},
+		"bad digest":   {file("index.json", `{"manifests": [{"digest": "sha256:../../etc/passwd"}]}`)},
+	}
+
+	for name, entries := range tests {
+		if name == "no dpkg" {
+			entries[1] = file("layer.tar", string(buildTar(t, file("etc/hostname", "app\n"))))
+		}
+		if _, err := Open(writeImage(t, entries...)); err == nil {
+			t.Errorf("Expected error for %s, got nil", name)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package image
+
+import (
+	"github.com/ncecere/apt-exporter/internal/apt"
+	"github.com/ncecere/apt-exporter/internal/collector"
+	"github.com/ncecere/apt-exporter/internal/metrics"
+)
+
+// SetMetrics sets the metrics of m that describe the image, as the collector
+// sets them for a host: the installed packages and, when package indices are
+// available, the updates the image is behind. Updates are only determined if
+// idx is not nil.
+func (img *Image) SetMetrics(m *metrics.Metrics, idx *apt.Index) {
+	collector.SetInventory(m, img.Packages)
+	if idx == nil {
+		return
+	}
+
+	updates, security := idx.PendingUpdates(img.Packages), 0
+	for _, u := range updates {
+		if u.Security {
+			security++
+		}
+	}
+	m.UpdatesAvailable.Set(float64(len(updates)))
+	m.SecurityUpdatesAvailable.Set(float64(security))
+}
+
+// Metrics returns the metrics of m that SetMetrics sets, including the
+// updates available only if withUpdates is true. The other metrics of m do
+// not apply to an image.
+func Metrics(m *metrics.Metrics, withUpdates bool) []any {
+	set := []any{m.PackagesInstalled, m.PackageSetHashInfo}
+	if withUpdates {
+		set = append(set, m.UpdatesAvailable, m.SecurityUpdatesAvailable)
+	}
+	return set
+}
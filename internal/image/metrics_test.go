@@ -0,0 +1,71 @@
+package image
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ncecere/apt-exporter/internal/apt"
+	"github.com/ncecere/apt-exporter/internal/dpkg"
+	"github.com/ncecere/apt-exporter/internal/metrics"
+	"github.com/ncecere/apt-exporter/internal/osrelease"
+)
+
+func TestSetMetrics(t *testing.T) {
+	img := &Image{
+		Reference: "example/app:1.0",
+		OS:        osrelease.Info{"PRETTY_NAME": "Debian GNU/Linux 12 (bookworm)"},
+		Packages: []dpkg.Package{
+			{Name: "openssl", Architecture: "amd64", Section: "utils", Priority: "optional", Version: "3.0.11-1~deb12u1"},
+			{Name: "tzdata", Architecture: "all", Section: "localization", Priority: "required", Version: "2024a-0+deb12u1"},
+			{Name: "bash", Architecture: "amd64", Section: "shells", Priority: "required", Version: "5.2.15-2+b2"},
+		},
+	}
+
+	m := metrics.NewTestMetrics()
+	img.SetMetrics(m, nil)
+	installed := m.PackagesInstalled.(*metrics.TestGaugeVec)
+	if v, _ := installed.Get("amd64", "utils", "optional"); v != 1 {
+		t.Errorf("Expected 1 amd64 utils optional package, got %f", v)
+	}
+	if installed.Len() != 3 {
+		t.Errorf("Expected 3 package groups, got %d", installed.Len())
+	}
+	if m.PackageSetHashInfo.(*metrics.TestGaugeVec).Len() != 1 {
+		t.Error("Expected a package set hash")
+	}
+
+	idx := apt.NewIndex()
+	if err := idx.Add(strings.NewReader("Package: tzdata\nArchitecture: all\nVersion: 2024b-0+deb12u1\n"), false); err != nil {
+		t.Fatalf("Failed to add index: %v", err)
+	}
+	if err := idx.Add(strings.NewReader("Package: openssl\nArchitecture: amd64\nVersion: 3.0.11-1~deb12u2\n"), true); err != nil {
+		t.Fatalf("Failed to add security index: %v", err)
+	}
+
+	img.SetMetrics(m, idx)
+	if v := m.UpdatesAvailable.(*metrics.TestGauge).Get(); v != 2 {
+		t.Errorf("Expected 2 updates available, got %f", v)
+	}
+	if v := m.SecurityUpdatesAvailable.(*metrics.TestGauge).Get(); v != 1 {
+		t.Errorf("Expected 1 security update available, got %f", v)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	m := metrics.NewDescribedMetrics("ubuntu", metrics.NamingV1)
+
+	var names []string
+	for _, metric := range Metrics(m, true) {
+		for _, desc := range metrics.DescsOf(metric) {
+			names = append(names, desc.Name)
+		}
+	}
+	want := "ubuntu_packages_installed ubuntu_package_set_hash_info ubuntu_updates_available ubuntu_security_updates_available"
+	if got := strings.Join(names, " "); got != want {
+		t.Errorf("Expected metrics %q, got %q", want, got)
+	}
+
+	if n := len(Metrics(m, false)); n != 2 {
+		t.Errorf("Expected 2 metrics without updates, got %d", n)
+	}
+}
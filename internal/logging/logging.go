@@ -0,0 +1,36 @@
+// Package logging sets up the exporter's structured logger.
+package logging
+
+import (
+	"io"
+	"log/slog"
+)
+
+// New creates a logger writing to w at the given level ("debug", "info",
+// "warn" or "error") in the given format ("text" or "json"). Debug logging
+// includes the source location of each message.
+func New(w io.Writer, level, format string) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: ParseLevel(level)}
+	if opts.Level == slog.LevelDebug {
+		opts.AddSource = true
+	}
+
+	if format == "json" {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
+
+// ParseLevel returns the slog level for a configured log level, defaulting to info.
+func ParseLevel(level string) slog.Level {
+	switch level {
+	case "debug":
+		return slog.LevelDebug
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
@@ -0,0 +1,55 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestLevelFiltering(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, "warn", "text")
+
+	logger.Info("Collecting APT metrics")
+	logger.Warn("File watching disabled")
+	logger.Error("Check failed", "check", "updates")
+
+	out := buf.String()
+	if strings.Contains(out, "Collecting APT metrics") {
+		t.Errorf("Expected info messages to be filtered at warn level, got:\n%s", out)
+	}
+	if !strings.Contains(out, "File watching disabled") || !strings.Contains(out, "check=updates") {
+		t.Errorf("Expected warn and error messages, got:\n%s", out)
+	}
+}
+
+func TestJSONFormat(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, "info", "json")
+
+	logger.Error("Check failed", "check", "updates", "exit_code", 2)
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Expected a JSON log entry, got %q: %v", buf.String(), err)
+	}
+	if entry["level"] != "ERROR" || entry["msg"] != "Check failed" || entry["check"] != "updates" || entry["exit_code"] != float64(2) {
+		t.Errorf("Unexpected log entry %v", entry)
+	}
+}
+
+func TestDebugIncludesSource(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, "debug", "json")
+
+	logger.Debug("Collecting APT metrics")
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Expected a JSON log entry, got %q: %v", buf.String(), err)
+	}
+	if _, ok := entry["source"]; !ok {
+		t.Errorf("Expected the source location at debug level, got %v", entry)
+	}
+}
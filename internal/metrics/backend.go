@@ -0,0 +1,291 @@
+package metrics
+
+import (
+	"maps"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Desc describes a metric independently of the backend it is written to.
+type Desc struct {
+	Name       string
+	Help       string
+	LabelNames []string
+
+	// Buckets are the upper bounds of a distribution's buckets
+	Buckets []float64
+
+	// ConstLabels are labels with fixed values added to every series
+	ConstLabels map[string]string
+}
+
+// Backend creates the metrics the collector writes to, so the collector does
+// not depend on where the values end up.
+type Backend interface {
+	NewGauge(desc Desc) Gauge
+	NewGaugeVec(desc Desc) GaugeVec
+	NewCounterVec(desc Desc) CounterVec
+	NewHistogram(desc Desc) Histogram
+	NewDistribution(desc Desc) Distribution
+}
+
+// collectorsProvider is implemented by backends that expose Prometheus collectors.
+type collectorsProvider interface {
+	Collectors() []prometheus.Collector
+}
+
+// PrometheusBackend creates Prometheus metrics, to be registered with a
+// registry and served on the metrics endpoint.
+type PrometheusBackend struct {
+	collectors []prometheus.Collector
+}
+
+// NewPrometheusBackend creates a Prometheus backend.
+func NewPrometheusBackend() *PrometheusBackend {
+	return &PrometheusBackend{}
+}
+
+// NewGauge creates a Prometheus gauge
+func (b *PrometheusBackend) NewGauge(desc Desc) Gauge {
+	g := prometheus.NewGauge(prometheus.GaugeOpts{Name: desc.Name, Help: desc.Help, ConstLabels: desc.ConstLabels})
+	b.collectors = append(b.collectors, g)
+	return g
+}
+
+// NewGaugeVec creates a Prometheus gauge vector
+func (b *PrometheusBackend) NewGaugeVec(desc Desc) GaugeVec {
+	v := promGaugeVec{prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: desc.Name, Help: desc.Help, ConstLabels: desc.ConstLabels}, desc.LabelNames)}
+	b.collectors = append(b.collectors, v.GaugeVec)
+	return v
+}
+
+// NewCounterVec creates a Prometheus counter vector
+func (b *PrometheusBackend) NewCounterVec(desc Desc) CounterVec {
+	v := promCounterVec{prometheus.NewCounterVec(prometheus.CounterOpts{Name: desc.Name, Help: desc.Help, ConstLabels: desc.ConstLabels}, desc.LabelNames)}
+	b.collectors = append(b.collectors, v.CounterVec)
+	return v
+}
+
+// NewHistogram creates a Prometheus histogram
+func (b *PrometheusBackend) NewHistogram(desc Desc) Histogram {
+	h := prometheus.NewHistogram(prometheus.HistogramOpts{Name: desc.Name, Help: desc.Help, ConstLabels: desc.ConstLabels, Buckets: desc.Buckets})
+	b.collectors = append(b.collectors, h)
+	return h
+}
+
+// NewDistribution creates a Prometheus histogram that is replaced on every Set
+func (b *PrometheusBackend) NewDistribution(desc Desc) Distribution {
+	h := newSnapshotHistogram(desc.Name, desc.Help, desc.Buckets, desc.ConstLabels)
+	b.collectors = append(b.collectors, h)
+	return h
+}
+
+// Collectors returns the metrics created so far, in order of creation.
+func (b *PrometheusBackend) Collectors() []prometheus.Collector {
+	return b.collectors
+}
+
+// WithConstLabels returns a backend adding labels with fixed values to every
+// metric created by b, for example to tell environments apart.
+func WithConstLabels(b Backend, labels map[string]string) Backend {
+	if len(labels) == 0 {
+		return b
+	}
+	return constLabelsBackend{b, labels}
+}
+
+// constLabelsBackend adds const labels to the metrics of a backend
+type constLabelsBackend struct {
+	Backend
+	labels map[string]string
+}
+
+// withLabels returns desc with the const labels added
+func (c constLabelsBackend) withLabels(desc Desc) Desc {
+	labels := maps.Clone(desc.ConstLabels)
+	if labels == nil {
+		labels = make(map[string]string, len(c.labels))
+	}
+	maps.Copy(labels, c.labels)
+	desc.ConstLabels = labels
+	return desc
+}
+
+// NewGauge creates a gauge with the const labels
+func (c constLabelsBackend) NewGauge(desc Desc) Gauge {
+	return c.Backend.NewGauge(c.withLabels(desc))
+}
+
+// NewGaugeVec creates a gauge vector with the const labels
+func (c constLabelsBackend) NewGaugeVec(desc Desc) GaugeVec {
+	return c.Backend.NewGaugeVec(c.withLabels(desc))
+}
+
+// NewCounterVec creates a counter vector with the const labels
+func (c constLabelsBackend) NewCounterVec(desc Desc) CounterVec {
+	return c.Backend.NewCounterVec(c.withLabels(desc))
+}
+
+// NewHistogram creates a histogram with the const labels
+func (c constLabelsBackend) NewHistogram(desc Desc) Histogram {
+	return c.Backend.NewHistogram(c.withLabels(desc))
+}
+
+// NewDistribution creates a distribution with the const labels
+func (c constLabelsBackend) NewDistribution(desc Desc) Distribution {
+	return c.Backend.NewDistribution(c.withLabels(desc))
+}
+
+// Collectors returns the Prometheus collectors of the wrapped backend, if any
+func (c constLabelsBackend) Collectors() []prometheus.Collector {
+	if p, ok := c.Backend.(collectorsProvider); ok {
+		return p.Collectors()
+	}
+	return nil
+}
+
+// Tee returns a backend writing every value to all of the given backends.
+func Tee(backends ...Backend) Backend {
+	return teeBackend(backends)
+}
+
+// teeBackend writes to several backends
+type teeBackend []Backend
+
+// NewGauge creates a gauge in every backend
+func (t teeBackend) NewGauge(desc Desc) Gauge {
+	gauges := make(teeGauge, len(t))
+	for i, b := range t {
+		gauges[i] = b.NewGauge(desc)
+	}
+	return gauges
+}
+
+// NewGaugeVec creates a gauge vector in every backend
+func (t teeBackend) NewGaugeVec(desc Desc) GaugeVec {
+	vecs := make(teeGaugeVec, len(t))
+	for i, b := range t {
+		vecs[i] = b.NewGaugeVec(desc)
+	}
+	return vecs
+}
+
+// NewCounterVec creates a counter vector in every backend
+func (t teeBackend) NewCounterVec(desc Desc) CounterVec {
+	vecs := make(teeCounterVec, len(t))
+	for i, b := range t {
+		vecs[i] = b.NewCounterVec(desc)
+	}
+	return vecs
+}
+
+// NewHistogram creates a histogram in every backend
+func (t teeBackend) NewHistogram(desc Desc) Histogram {
+	hists := make(teeHistogram, len(t))
+	for i, b := range t {
+		hists[i] = b.NewHistogram(desc)
+	}
+	return hists
+}
+
+// NewDistribution creates a distribution in every backend
+func (t teeBackend) NewDistribution(desc Desc) Distribution {
+	dists := make(teeDistribution, len(t))
+	for i, b := range t {
+		dists[i] = b.NewDistribution(desc)
+	}
+	return dists
+}
+
+// Collectors returns the Prometheus collectors of the backends that have them
+func (t teeBackend) Collectors() []prometheus.Collector {
+	var collectors []prometheus.Collector
+	for _, b := range t {
+		if p, ok := b.(collectorsProvider); ok {
+			collectors = append(collectors, p.Collectors()...)
+		}
+	}
+	return collectors
+}
+
+// teeGauge sets several gauges
+type teeGauge []Gauge
+
+// Set sets every gauge
+func (t teeGauge) Set(val float64) {
+	for _, g := range t {
+		g.Set(val)
+	}
+}
+
+// teeGaugeVec writes to several gauge vectors
+type teeGaugeVec []GaugeVec
+
+// WithLabelValues returns the gauges for the given label values in every vector
+func (t teeGaugeVec) WithLabelValues(lvs ...string) Gauge {
+	gauges := make(teeGauge, len(t))
+	for i, v := range t {
+		gauges[i] = v.WithLabelValues(lvs...)
+	}
+	return gauges
+}
+
+// DeleteLabelValues removes the series from every vector
+func (t teeGaugeVec) DeleteLabelValues(lvs ...string) bool {
+	deleted := false
+	for _, v := range t {
+		if v.DeleteLabelValues(lvs...) {
+			deleted = true
+		}
+	}
+	return deleted
+}
+
+// Reset resets every vector
+func (t teeGaugeVec) Reset() {
+	for _, v := range t {
+		v.Reset()
+	}
+}
+
+// teeDistribution sets several distributions
+type teeDistribution []Distribution
+
+// Set sets every distribution
+func (t teeDistribution) Set(values []float64) {
+	for _, d := range t {
+		d.Set(values)
+	}
+}
+
+// teeCounter increments several counters
+type teeCounter []Counter
+
+// Inc increments every counter
+func (t teeCounter) Inc() {
+	for _, c := range t {
+		c.Inc()
+	}
+}
+
+// teeCounterVec writes to several counter vectors
+type teeCounterVec []CounterVec
+
+// WithLabelValues returns the counters for the given label values in every vector
+func (t teeCounterVec) WithLabelValues(lvs ...string) Counter {
+	counters := make(teeCounter, len(t))
+	for i, v := range t {
+		counters[i] = v.WithLabelValues(lvs...)
+	}
+	return counters
+}
+
+// teeHistogram observes into several histograms
+type teeHistogram []Histogram
+
+// Observe adds the value to every histogram
+func (t teeHistogram) Observe(val float64) {
+	for _, h := range t {
+		h.Observe(val)
+	}
+}
@@ -0,0 +1,118 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestPrometheusBackendCollectors(t *testing.T) {
+	m := NewMetrics("test", false)
+
+	// The collectors are returned in the order of the Metrics fields
+	collectors := m.GetCollectors()
+	if len(collectors) != 18 {
+		t.Fatalf("Expected 18 collectors, got %d", len(collectors))
+	}
+	if collectors[0] != m.UpdatesAvailable.(prometheus.Collector) {
+		t.Error("Expected UpdatesAvailable to be the first collector")
+	}
+
+	// Test metrics are not backed by Prometheus
+	if got := NewTestMetrics().GetCollectors(); len(got) != 0 {
+		t.Errorf("Expected no collectors for test metrics, got %d", len(got))
+	}
+}
+
+func TestTee(t *testing.T) {
+	prom := NewPrometheusBackend()
+	store := NewStore()
+	m := NewMetricsWithBackend("test", Tee(prom, store))
+
+	m.UpdatesAvailable.Set(7)
+	m.DpkgLockHeld.WithLabelValues("/var/lib/dpkg/lock").Set(1)
+
+	// Only the Prometheus backend provides collectors
+	if got := len(m.GetCollectors()); got != len(prom.Collectors()) {
+		t.Errorf("Expected %d collectors, got %d", len(prom.Collectors()), got)
+	}
+
+	expected := `
+# HELP test_updates_available Number of available package updates
+# TYPE test_updates_available gauge
+test_updates_available 7
+`
+	if err := testutil.CollectAndCompare(prom.Collectors()[0], strings.NewReader(expected)); err != nil {
+		t.Errorf("Unexpected Prometheus gauge: %v", err)
+	}
+
+	families := store.Snapshot()
+	if families[0].Name != "test_updates_available" || families[0].Points[0].Value != 7 {
+		t.Errorf("Expected test_updates_available 7 in the store, got %+v", families[0])
+	}
+	if lock := families[4]; lock.Name != "test_dpkg_lock_held" || len(lock.Points) != 1 || lock.Points[0].Value != 1 {
+		t.Errorf("Expected test_dpkg_lock_held 1 in the store, got %+v", lock)
+	}
+
+	m.DpkgLockHeld.Reset()
+	if got := len(store.Snapshot()[4].Points); got != 0 {
+		t.Errorf("Expected no series after reset, got %d", got)
+	}
+}
+
+func TestWithConstLabels(t *testing.T) {
+	prom := NewPrometheusBackend()
+	store := NewStore()
+	labels := map[string]string{"env": "prod", "role": "db"}
+	m := NewMetricsWithBackend("test", WithConstLabels(Tee(prom, store), labels))
+
+	m.UpdatesAvailable.Set(7)
+	m.PendingUpdateAgeSeconds.Set([]float64{60})
+
+	if got := len(m.GetCollectors()); got != len(prom.Collectors()) {
+		t.Errorf("Expected %d collectors, got %d", len(prom.Collectors()), got)
+	}
+
+	expected := `
+# HELP test_updates_available Number of available package updates
+# TYPE test_updates_available gauge
+test_updates_available{env="prod",role="db"} 7
+`
+	if err := testutil.CollectAndCompare(prom.Collectors()[0], strings.NewReader(expected)); err != nil {
+		t.Errorf("Unexpected Prometheus gauge: %v", err)
+	}
+
+	// Every metric carries the labels, including histograms
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(prom.Collectors()...)
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Failed to gather metrics: %v", err)
+	}
+	for _, f := range families {
+		for _, metric := range f.GetMetric() {
+			found := 0
+			for _, label := range metric.GetLabel() {
+				if labels[label.GetName()] == label.GetValue() {
+					found++
+				}
+			}
+			if found != len(labels) {
+				t.Errorf("Expected const labels on %s, got %v", f.GetName(), metric.GetLabel())
+			}
+		}
+	}
+
+	for _, f := range store.Snapshot() {
+		if f.ConstLabels["env"] != "prod" || f.ConstLabels["role"] != "db" {
+			t.Errorf("Expected const labels on stored %s, got %v", f.Name, f.ConstLabels)
+		}
+	}
+
+	// Without labels the backend is returned as is
+	if b := WithConstLabels(prom, nil); b != Backend(prom) {
+		t.Error("Expected the backend itself without labels")
+	}
+}
@@ -0,0 +1,121 @@
+package metrics
+
+import "reflect"
+
+// NewDescribedMetrics creates metrics that record nothing but whose names
+// can be read back with DescsOf, e.g. to generate alerting rules that
+// cannot drift from the metrics the exporter exposes.
+func NewDescribedMetrics(prefix string, naming Naming) *Metrics {
+	return NewMetricsWithNaming(prefix, naming, describeBackend{})
+}
+
+// DescsOf returns the descriptions of a metric created by
+// NewDescribedMetrics, one for each name it is created under: the v1 name
+// before the v2 name. It returns nil for a metric without a name in the
+// naming scheme and for metrics of other backends.
+func DescsOf(metric any) []Desc {
+	var descs []Desc
+	switch m := metric.(type) {
+	case interface{ description() Desc }:
+		descs = append(descs, m.description())
+	case teeGauge:
+		for _, g := range m {
+			descs = append(descs, DescsOf(g)...)
+		}
+	case teeGaugeVec:
+		for _, v := range m {
+			descs = append(descs, DescsOf(v)...)
+		}
+	case teeCounterVec:
+		for _, v := range m {
+			descs = append(descs, DescsOf(v)...)
+		}
+	case teeHistogram:
+		for _, h := range m {
+			descs = append(descs, DescsOf(h)...)
+		}
+	case teeDistribution:
+		for _, d := range m {
+			descs = append(descs, DescsOf(d)...)
+		}
+	}
+	return descs
+}
+
+// LabelNames returns the names of the labels of the exporter's metrics under
+// any naming scheme, including "le" for the buckets of histograms. Constant
+// labels must not reuse them, or the series of a metric would clash.
+func LabelNames() map[string]bool {
+	names := make(map[string]bool)
+	v := reflect.ValueOf(NewDescribedMetrics("apt", NamingDual)).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if !v.Type().Field(i).IsExported() {
+			continue
+		}
+		for _, desc := range DescsOf(v.Field(i).Interface()) {
+			for _, name := range desc.LabelNames {
+				names[name] = true
+			}
+			if desc.Buckets != nil {
+				names["le"] = true
+			}
+		}
+	}
+	return names
+}
+
+// describeBackend creates metrics that only keep their description.
+type describeBackend struct{}
+
+func (describeBackend) NewGauge(desc Desc) Gauge {
+	return described{desc}
+}
+
+func (describeBackend) NewGaugeVec(desc Desc) GaugeVec {
+	return describedVec{described{desc}}
+}
+
+func (describeBackend) NewCounterVec(desc Desc) CounterVec {
+	return describedCounterVec{described{desc}}
+}
+
+func (describeBackend) NewHistogram(desc Desc) Histogram {
+	return described{desc}
+}
+
+func (describeBackend) NewDistribution(desc Desc) Distribution {
+	return describedDistribution{described{desc}}
+}
+
+// described is a gauge, counter or histogram that discards its values.
+type described struct {
+	desc Desc
+}
+
+func (d described) description() Desc { return d.desc }
+func (described) Set(float64)         {}
+func (described) Inc()                {}
+func (described) Observe(float64)     {}
+
+// describedVec is a gauge vector that discards its values.
+type describedVec struct {
+	described
+}
+
+func (v describedVec) WithLabelValues(...string) Gauge { return v.described }
+func (describedVec) DeleteLabelValues(...string) bool  { return false }
+func (describedVec) Reset()                            {}
+
+// describedCounterVec is a counter vector that discards its values.
+type describedCounterVec struct {
+	described
+}
+
+func (v describedCounterVec) WithLabelValues(...string) Counter { return v.described }
+
+// describedDistribution is a distribution that discards its values.
+type describedDistribution struct {
+	described
+}
+
+func (describedDistribution) Set([]float64) {}
@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDescsOf(t *testing.T) {
+	m := NewDescribedMetrics("debian", NamingDual)
+
+	var names []string
+	for _, d := range DescsOf(m.UpdatesAvailable) {
+		names = append(names, d.Name)
+	}
+	if want := []string{"debian_updates_available", "apt_upgrades_pending"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Expected names %v, got %v", want, names)
+	}
+
+	if descs := DescsOf(m.Vulnerabilities); len(descs) != 2 || !reflect.DeepEqual(descs[1].LabelNames, []string{"severity"}) {
+		t.Errorf("Expected both vulnerability descriptions with their labels, got %+v", descs)
+	}
+	if descs := DescsOf(m.CollectionDurationSeconds); len(descs) != 2 || !reflect.DeepEqual(descs[0].Buckets, CollectionDurationBuckets) {
+		t.Errorf("Expected both duration descriptions with their buckets, got %+v", descs)
+	}
+
+	// Metrics without a name in the naming scheme have no description
+	if descs := DescsOf(NewDescribedMetrics("debian", NamingV1).LastUpdateTimestamp); len(descs) != 0 {
+		t.Errorf("Expected no v1 last update timestamp, got %+v", descs)
+	}
+
+	// The described metrics discard their values
+	m.UpdatesAvailable.Set(1)
+	m.CollectionErrors.WithLabelValues("updates").Inc()
+	m.PendingUpdateAgeSeconds.Set([]float64{60})
+
+	if descs := DescsOf(NewTestMetrics().UpdatesAvailable); descs != nil {
+		t.Errorf("Expected no descriptions of other backends, got %+v", descs)
+	}
+}
+
+func TestLabelNames(t *testing.T) {
+	names := LabelNames()
+	for _, name := range []string{"lock", "severity", "package", "check", "version", "sha256", "le"} {
+		if !names[name] {
+			t.Errorf("Expected label %s among the metric labels", name)
+		}
+	}
+	for _, name := range []string{"env", "hostname", "os_id", "os_version_id"} {
+		if names[name] {
+			t.Errorf("Expected label %s not to be a metric label", name)
+		}
+	}
+}
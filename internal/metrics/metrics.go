@@ -1,6 +1,10 @@
 package metrics
 
 import (
+	"runtime"
+	"strings"
+	"sync"
+
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -24,91 +28,383 @@ func (g *TestGauge) Get() float64 {
 	return g.value
 }
 
-// Metrics holds all the Prometheus metrics for the APT exporter.
+// GaugeVec is an interface that allows us to use both prometheus.GaugeVec and test gauge vectors
+type GaugeVec interface {
+	WithLabelValues(lvs ...string) Gauge
+	DeleteLabelValues(lvs ...string) bool
+	Reset()
+}
+
+// promGaugeVec adapts a prometheus.GaugeVec to the GaugeVec interface
+type promGaugeVec struct {
+	*prometheus.GaugeVec
+}
+
+// WithLabelValues returns the gauge for the given label values
+func (v promGaugeVec) WithLabelValues(lvs ...string) Gauge {
+	return v.GaugeVec.WithLabelValues(lvs...)
+}
+
+// TestGaugeVec is a mock implementation of GaugeVec for testing
+type TestGaugeVec struct {
+	gauges map[string]*TestGauge
+}
+
+// WithLabelValues returns the test gauge for the given label values, creating it if needed
+func (v *TestGaugeVec) WithLabelValues(lvs ...string) Gauge {
+	if v.gauges == nil {
+		v.gauges = make(map[string]*TestGauge)
+	}
+	key := strings.Join(lvs, ",")
+	g, ok := v.gauges[key]
+	if !ok {
+		g = &TestGauge{}
+		v.gauges[key] = g
+	}
+	return g
+}
+
+// DeleteLabelValues removes the gauge with the given label values, reporting whether it existed
+func (v *TestGaugeVec) DeleteLabelValues(lvs ...string) bool {
+	key := strings.Join(lvs, ",")
+	_, ok := v.gauges[key]
+	delete(v.gauges, key)
+	return ok
+}
+
+// Reset removes all gauges from the vector
+func (v *TestGaugeVec) Reset() {
+	v.gauges = nil
+}
+
+// Get returns the value of the gauge with the given label values and whether it exists (for testing)
+func (v *TestGaugeVec) Get(lvs ...string) (float64, bool) {
+	g, ok := v.gauges[strings.Join(lvs, ",")]
+	if !ok {
+		return 0, false
+	}
+	return g.Get(), true
+}
+
+// Len returns the number of label combinations in the vector (for testing)
+func (v *TestGaugeVec) Len() int {
+	return len(v.gauges)
+}
+
+// Counter is an interface for values that only increase
+type Counter interface {
+	Inc()
+}
+
+// CounterVec is an interface that allows us to use both prometheus.CounterVec and test counter vectors
+type CounterVec interface {
+	WithLabelValues(lvs ...string) Counter
+}
+
+// promCounterVec adapts a prometheus.CounterVec to the CounterVec interface
+type promCounterVec struct {
+	*prometheus.CounterVec
+}
+
+// WithLabelValues returns the counter for the given label values
+func (v promCounterVec) WithLabelValues(lvs ...string) Counter {
+	return v.CounterVec.WithLabelValues(lvs...)
+}
+
+// TestCounterVec is a mock implementation of CounterVec for testing
+type TestCounterVec struct {
+	counts map[string]float64
+}
+
+// testCounter is a counter of a TestCounterVec
+type testCounter struct {
+	vec *TestCounterVec
+	key string
+}
+
+// Inc increments the counter
+func (c testCounter) Inc() {
+	c.vec.counts[c.key]++
+}
+
+// WithLabelValues returns the test counter for the given label values
+func (v *TestCounterVec) WithLabelValues(lvs ...string) Counter {
+	if v.counts == nil {
+		v.counts = make(map[string]float64)
+	}
+	return testCounter{v, strings.Join(lvs, ",")}
+}
+
+// Get returns the value of the counter with the given label values (for testing)
+func (v *TestCounterVec) Get(lvs ...string) float64 {
+	return v.counts[strings.Join(lvs, ",")]
+}
+
+// Histogram is an interface for histograms that accumulate observations over time
+type Histogram interface {
+	Observe(float64)
+}
+
+// TestHistogram is a mock implementation of Histogram for testing
+type TestHistogram struct {
+	observations []float64
+}
+
+// Observe records the value
+func (h *TestHistogram) Observe(val float64) {
+	h.observations = append(h.observations, val)
+}
+
+// Get returns the observed values (for testing)
+func (h *TestHistogram) Get() []float64 {
+	return h.observations
+}
+
+// Distribution is an interface for histograms that describe a snapshot of
+// values, as opposed to accumulating observations over time
+type Distribution interface {
+	Set(values []float64)
+}
+
+// snapshotHistogram is a Prometheus histogram that is replaced on every Set
+type snapshotHistogram struct {
+	desc    *prometheus.Desc
+	buckets []float64
+
+	mu     sync.Mutex
+	counts map[float64]uint64
+	count  uint64
+	sum    float64
+}
+
+// newSnapshotHistogram creates a prometheus-backed Distribution
+func newSnapshotHistogram(name, help string, buckets []float64, constLabels map[string]string) *snapshotHistogram {
+	h := &snapshotHistogram{
+		desc:    prometheus.NewDesc(name, help, nil, constLabels),
+		buckets: buckets,
+	}
+	h.Set(nil)
+	return h
+}
+
+// Set replaces the histogram with the distribution of values
+func (h *snapshotHistogram) Set(values []float64) {
+	// Every bucket is exposed, even when empty
+	counts := make(map[float64]uint64, len(h.buckets))
+	for _, upper := range h.buckets {
+		counts[upper] = 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+		for _, upper := range h.buckets {
+			if v <= upper {
+				counts[upper]++
+			}
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.counts, h.count, h.sum = counts, uint64(len(values)), sum
+}
+
+// Describe implements prometheus.Collector
+func (h *snapshotHistogram) Describe(ch chan<- *prometheus.Desc) {
+	ch <- h.desc
+}
+
+// Collect implements prometheus.Collector
+func (h *snapshotHistogram) Collect(ch chan<- prometheus.Metric) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ch <- prometheus.MustNewConstHistogram(h.desc, h.count, h.sum, h.counts)
+}
+
+// TestDistribution is a mock implementation of Distribution for testing
+type TestDistribution struct {
+	values []float64
+}
+
+// Set replaces the values of the distribution
+func (d *TestDistribution) Set(values []float64) {
+	d.values = append([]float64(nil), values...)
+}
+
+// Get returns the current values of the distribution (for testing)
+func (d *TestDistribution) Get() []float64 {
+	return d.values
+}
+
+// PendingUpdateAgeBuckets are the histogram buckets for pending update ages:
+// 1 hour, 6 hours, 1, 3, 7, 14, 30 and 90 days
+var PendingUpdateAgeBuckets = []float64{3600, 21600, 86400, 259200, 604800, 1209600, 2592000, 7776000}
+
+// CollectionDurationBuckets are the histogram buckets for collection durations in seconds
+var CollectionDurationBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+// Metrics holds all the metrics for the APT exporter, as created by a Backend.
 type Metrics struct {
 	// Core metrics
 	UpdatesAvailable         Gauge
 	SecurityUpdatesAvailable Gauge
 	SecondsSinceLastUpdate   Gauge
+	LastUpdateTimestamp      Gauge
 	RebootRequired           Gauge
 
+	// Package manager lock metrics
+	DpkgLockHeld        GaugeVec
+	DpkgLockHeldSeconds GaugeVec
+	DpkgLockHolderInfo  GaugeVec
+
+	// Vulnerability metrics
+	Vulnerabilities GaugeVec
+	PackageCVE      GaugeVec
+
+	// Pending update age metrics
+	OldestPendingSecurityUpdateAgeSeconds Gauge
+	PendingUpdateAgeSeconds               Distribution
+
+	// Package inventory metrics
+	PackagesInstalled  GaugeVec
+	PackageSetHashInfo GaugeVec
+
 	// Collector metrics
 	CollectionSuccess         Gauge
-	CollectionDurationSeconds Gauge
+	CollectionDurationSeconds Histogram
 	LastCollectionTimestamp   Gauge
+	CollectionErrors          CounterVec
+
+	// Exporter metrics
+	ExporterBuildInfo GaugeVec
+
+	// backend created the metrics
+	backend Backend
 }
 
 // NewMetrics creates metrics with the provided prefix.
 // If useDefaultRegistry is true, metrics are registered with the default Prometheus registry.
 // Otherwise, they are just created but not registered (caller must register them).
 func NewMetrics(prefix string, useDefaultRegistry bool) *Metrics {
-	m := &Metrics{
+	m := NewMetricsWithBackend(prefix, NewPrometheusBackend())
+
+	// Register all metrics with the default Prometheus registry if requested
+	if useDefaultRegistry {
+		prometheus.MustRegister(m.GetCollectors()...)
+	}
+
+	return m
+}
+
+// NewMetricsWithBackend creates metrics with the provided prefix in the given backend.
+func NewMetricsWithBackend(prefix string, b Backend) *Metrics {
+	return NewMetricsWithNaming(prefix, NamingV1, b)
+}
+
+// NewMetricsWithNaming creates metrics in the given backend under the names
+// of naming: NamingV1 names start with prefix, NamingV2 names follow the
+// Prometheus conventions in the apt namespace, and NamingDual creates both.
+func NewMetricsWithNaming(prefix string, naming Naming, b Backend) *Metrics {
+	n := namer{prefix: prefix, naming: naming, backend: b}
+	return &Metrics{
+		backend: b,
+
 		// Core metrics
-		UpdatesAvailable: prometheus.NewGauge(prometheus.GaugeOpts{
-			Name: prefix + "_updates_available",
+		UpdatesAvailable: n.gauge("_updates_available", "apt_upgrades_pending", Desc{
 			Help: "Number of available package updates",
 		}),
-		SecurityUpdatesAvailable: prometheus.NewGauge(prometheus.GaugeOpts{
-			Name: prefix + "_security_updates_available",
+		SecurityUpdatesAvailable: n.gauge("_security_updates_available", "apt_security_upgrades_pending", Desc{
 			Help: "Number of available security updates",
 		}),
-		SecondsSinceLastUpdate: prometheus.NewGauge(prometheus.GaugeOpts{
-			Name: prefix + "_seconds_since_last_update",
+		SecondsSinceLastUpdate: n.gauge("_seconds_since_last_update", "", Desc{
 			Help: "Seconds since last successful apt update",
 		}),
-		RebootRequired: prometheus.NewGauge(prometheus.GaugeOpts{
-			Name: prefix + "_reboot_required",
+		LastUpdateTimestamp: n.gauge("", "apt_update_last_success_timestamp_seconds", Desc{
+			Help: "Unix timestamp of the last successful apt update",
+		}),
+		RebootRequired: n.gauge("_reboot_required", "node_reboot_required", Desc{
 			Help: "1 if a reboot is required, 0 otherwise",
 		}),
 
+		// Package manager lock metrics
+		DpkgLockHeld: n.gaugeVec("_dpkg_lock_held", "apt_dpkg_lock_held", Desc{
+			Help:       "1 if the dpkg/apt lock file is currently held by a process, 0 otherwise",
+			LabelNames: []string{"lock"},
+		}),
+		DpkgLockHeldSeconds: n.gaugeVec("_dpkg_lock_held_seconds", "apt_dpkg_lock_held_seconds", Desc{
+			Help:       "Approximate seconds the dpkg/apt lock file has been held, based on the age of the holding process",
+			LabelNames: []string{"lock"},
+		}),
+		DpkgLockHolderInfo: n.gaugeVec("_dpkg_lock_holder_info", "apt_dpkg_lock_holder_info", Desc{
+			Help:       "Process holding the dpkg/apt lock file, always 1",
+			LabelNames: []string{"lock", "pid", "command"},
+		}),
+
+		// Vulnerability metrics
+		Vulnerabilities: n.gaugeVec("_vulnerabilities", "apt_vulnerabilities", Desc{
+			Help:       "Number of known vulnerabilities affecting installed packages that are fixed in a newer version, by severity",
+			LabelNames: []string{"severity"},
+		}),
+		PackageCVE: n.gaugeVec("_package_cve", "apt_package_cve_info", Desc{
+			Help:       "Vulnerability affecting an installed source package, always 1",
+			LabelNames: []string{"package", "cve", "severity"},
+		}),
+
+		// Pending update age metrics
+		OldestPendingSecurityUpdateAgeSeconds: n.gauge("_oldest_pending_security_update_age_seconds", "apt_oldest_pending_security_update_age_seconds", Desc{
+			Help: "Seconds since the oldest pending security update was first seen, 0 if none are pending",
+		}),
+		PendingUpdateAgeSeconds: n.distribution("_pending_update_age_seconds", "apt_pending_update_age_seconds", Desc{
+			Help:    "Distribution of the time since each pending update was first seen",
+			Buckets: PendingUpdateAgeBuckets,
+		}),
+
+		// Package inventory metrics
+		PackagesInstalled: n.gaugeVec("_packages_installed", "apt_packages_installed", Desc{
+			Help:       "Number of installed packages by architecture, section and priority",
+			LabelNames: []string{"architecture", "section", "priority"},
+		}),
+		PackageSetHashInfo: n.gaugeVec("_package_set_hash_info", "apt_package_set_hash_info", Desc{
+			Help:       "SHA-256 of the sorted name=version list of installed packages, always 1",
+			LabelNames: []string{"sha256"},
+		}),
+
 		// Collector metrics
-		CollectionSuccess: prometheus.NewGauge(prometheus.GaugeOpts{
-			Name: prefix + "_collector_success",
+		CollectionSuccess: n.gauge("_collector_success", "apt_collector_success", Desc{
 			Help: "1 if the last collection was successful, 0 otherwise",
 		}),
-		CollectionDurationSeconds: prometheus.NewGauge(prometheus.GaugeOpts{
-			Name: prefix + "_collector_duration_seconds",
-			Help: "Duration of the last collection in seconds",
+		CollectionDurationSeconds: n.histogram("_collector_duration_seconds", "apt_collector_duration_seconds", Desc{
+			Help:    "Duration of collections in seconds",
+			Buckets: CollectionDurationBuckets,
 		}),
-		LastCollectionTimestamp: prometheus.NewGauge(prometheus.GaugeOpts{
-			Name: prefix + "_collector_last_timestamp",
+		LastCollectionTimestamp: n.gauge("_collector_last_timestamp", "apt_collector_last_timestamp_seconds", Desc{
 			Help: "Timestamp of the last collection",
 		}),
-	}
+		CollectionErrors: n.counterVec("_collector_errors_total", "apt_collector_errors_total", Desc{
+			Help:       "Number of failed checks, by check",
+			LabelNames: []string{"check"},
+		}),
 
-	// Register all metrics with the default Prometheus registry if requested
-	if useDefaultRegistry {
-		prometheus.MustRegister(
-			// Core metrics
-			m.UpdatesAvailable.(prometheus.Collector),
-			m.SecurityUpdatesAvailable.(prometheus.Collector),
-			m.SecondsSinceLastUpdate.(prometheus.Collector),
-			m.RebootRequired.(prometheus.Collector),
-
-			// Collector metrics
-			m.CollectionSuccess.(prometheus.Collector),
-			m.CollectionDurationSeconds.(prometheus.Collector),
-			m.LastCollectionTimestamp.(prometheus.Collector),
-		)
+		// Exporter metrics
+		ExporterBuildInfo: n.gaugeVec("_exporter_build_info", "apt_exporter_build_info", Desc{
+			Help:       "Build of the running exporter, always 1",
+			LabelNames: []string{"version", "commit", "date", "goversion"},
+		}),
 	}
+}
 
-	return m
+// SetBuildInfo records the build of the running exporter in ExporterBuildInfo.
+func (m *Metrics) SetBuildInfo(version, commit, date string) {
+	m.ExporterBuildInfo.WithLabelValues(version, commit, date, runtime.Version()).Set(1)
 }
 
-// GetCollectors returns all metrics as Prometheus collectors
+// GetCollectors returns all metrics as Prometheus collectors, or none if
+// the metrics are not backed by Prometheus
 func (m *Metrics) GetCollectors() []prometheus.Collector {
-	return []prometheus.Collector{
-		// Core metrics
-		m.UpdatesAvailable.(prometheus.Collector),
-		m.SecurityUpdatesAvailable.(prometheus.Collector),
-		m.SecondsSinceLastUpdate.(prometheus.Collector),
-		m.RebootRequired.(prometheus.Collector),
-
-		// Collector metrics
-		m.CollectionSuccess.(prometheus.Collector),
-		m.CollectionDurationSeconds.(prometheus.Collector),
-		m.LastCollectionTimestamp.(prometheus.Collector),
+	if p, ok := m.backend.(collectorsProvider); ok {
+		return p.Collectors()
 	}
+	return nil
 }
 
 // NewTestMetrics creates a new Metrics instance with TestGauge implementations for testing
@@ -118,11 +414,33 @@ func NewTestMetrics() *Metrics {
 		UpdatesAvailable:         &TestGauge{},
 		SecurityUpdatesAvailable: &TestGauge{},
 		SecondsSinceLastUpdate:   &TestGauge{},
+		LastUpdateTimestamp:      &TestGauge{},
 		RebootRequired:           &TestGauge{},
 
+		// Package manager lock metrics
+		DpkgLockHeld:        &TestGaugeVec{},
+		DpkgLockHeldSeconds: &TestGaugeVec{},
+		DpkgLockHolderInfo:  &TestGaugeVec{},
+
+		// Vulnerability metrics
+		Vulnerabilities: &TestGaugeVec{},
+		PackageCVE:      &TestGaugeVec{},
+
+		// Pending update age metrics
+		OldestPendingSecurityUpdateAgeSeconds: &TestGauge{},
+		PendingUpdateAgeSeconds:               &TestDistribution{},
+
+		// Package inventory metrics
+		PackagesInstalled:  &TestGaugeVec{},
+		PackageSetHashInfo: &TestGaugeVec{},
+
 		// Collector metrics
 		CollectionSuccess:         &TestGauge{},
-		CollectionDurationSeconds: &TestGauge{},
+		CollectionDurationSeconds: &TestHistogram{},
 		LastCollectionTimestamp:   &TestGauge{},
+		CollectionErrors:          &TestCounterVec{},
+
+		// Exporter metrics
+		ExporterBuildInfo: &TestGaugeVec{},
 	}
 }
@@ -1,9 +1,11 @@
 package metrics
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 func TestNewMetrics(t *testing.T) {
@@ -75,3 +77,36 @@ func TestNewTestMetrics(t *testing.T) {
 		t.Errorf("Expected TestGauge value to be 42, got %f", testGauge.Get())
 	}
 }
+
+func TestSnapshotHistogram(t *testing.T) {
+	h := newSnapshotHistogram("test_age_seconds", "Test ages", []float64{10, 100}, nil)
+
+	h.Set([]float64{5, 50, 500})
+	expected := `
+# HELP test_age_seconds Test ages
+# TYPE test_age_seconds histogram
+test_age_seconds_bucket{le="10"} 1
+test_age_seconds_bucket{le="100"} 2
+test_age_seconds_bucket{le="+Inf"} 3
+test_age_seconds_sum 555
+test_age_seconds_count 3
+`
+	if err := testutil.CollectAndCompare(h, strings.NewReader(expected)); err != nil {
+		t.Errorf("Unexpected histogram: %v", err)
+	}
+
+	// Setting new values replaces the previous snapshot
+	h.Set(nil)
+	expected = `
+# HELP test_age_seconds Test ages
+# TYPE test_age_seconds histogram
+test_age_seconds_bucket{le="10"} 0
+test_age_seconds_bucket{le="100"} 0
+test_age_seconds_bucket{le="+Inf"} 0
+test_age_seconds_sum 0
+test_age_seconds_count 0
+`
+	if err := testutil.CollectAndCompare(h, strings.NewReader(expected)); err != nil {
+		t.Errorf("Unexpected histogram after reset: %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package metrics
+
+// Naming selects the names under which the metrics are created.
+type Naming string
+
+// Naming schemes.
+const (
+	// NamingV1 names start with the configured prefix, e.g. ubuntu_updates_available
+	NamingV1 Naming = "v1"
+	// NamingV2 names follow the Prometheus conventions, e.g. apt_upgrades_pending
+	NamingV2 Naming = "v2"
+	// NamingDual creates every metric under both names, to migrate dashboards
+	NamingDual Naming = "dual"
+)
+
+// namer creates each metric under its names in the enabled naming schemes.
+// A metric without a name in a scheme is not created in it, and a metric
+// created under both names writes to both.
+type namer struct {
+	prefix  string
+	naming  Naming
+	backend Backend
+}
+
+// descs returns desc named prefix+v1 and v2, as enabled and not empty.
+func (n namer) descs(v1, v2 string, desc Desc) []Desc {
+	var descs []Desc
+	if v1 != "" && n.naming != NamingV2 {
+		d := desc
+		d.Name = n.prefix + v1
+		descs = append(descs, d)
+	}
+	if v2 != "" && (n.naming == NamingV2 || n.naming == NamingDual) {
+		d := desc
+		d.Name = v2
+		descs = append(descs, d)
+	}
+	return descs
+}
+
+func (n namer) gauge(v1, v2 string, desc Desc) Gauge {
+	var gauges teeGauge
+	for _, d := range n.descs(v1, v2, desc) {
+		gauges = append(gauges, n.backend.NewGauge(d))
+	}
+	if len(gauges) == 1 {
+		return gauges[0]
+	}
+	return gauges
+}
+
+func (n namer) gaugeVec(v1, v2 string, desc Desc) GaugeVec {
+	var vecs teeGaugeVec
+	for _, d := range n.descs(v1, v2, desc) {
+		vecs = append(vecs, n.backend.NewGaugeVec(d))
+	}
+	if len(vecs) == 1 {
+		return vecs[0]
+	}
+	return vecs
+}
+
+func (n namer) counterVec(v1, v2 string, desc Desc) CounterVec {
+	var vecs teeCounterVec
+	for _, d := range n.descs(v1, v2, desc) {
+		vecs = append(vecs, n.backend.NewCounterVec(d))
+	}
+	if len(vecs) == 1 {
+		return vecs[0]
+	}
+	return vecs
+}
+
+func (n namer) histogram(v1, v2 string, desc Desc) Histogram {
+	var hists teeHistogram
+	for _, d := range n.descs(v1, v2, desc) {
+		hists = append(hists, n.backend.NewHistogram(d))
+	}
+	if len(hists) == 1 {
+		return hists[0]
+	}
+	return hists
+}
+
+func (n namer) distribution(v1, v2 string, desc Desc) Distribution {
+	var dists teeDistribution
+	for _, d := range n.descs(v1, v2, desc) {
+		dists = append(dists, n.backend.NewDistribution(d))
+	}
+	if len(dists) == 1 {
+		return dists[0]
+	}
+	return dists
+}
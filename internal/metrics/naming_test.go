@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"reflect"
+	"testing"
+)
+
+// familyValues returns the value of the first point of each family by name.
+func familyValues(s *Store) map[string]float64 {
+	values := make(map[string]float64)
+	for _, f := range s.Snapshot() {
+		if len(f.Points) > 0 {
+			values[f.Name] = f.Points[0].Value
+		} else {
+			values[f.Name] = 0
+		}
+	}
+	return values
+}
+
+func TestNewMetricsWithNaming(t *testing.T) {
+	tests := []struct {
+		naming  Naming
+		present []string
+		absent  []string
+	}{
+		{
+			naming:  NamingV1,
+			present: []string{"debian_updates_available", "debian_seconds_since_last_update", "debian_reboot_required"},
+			absent:  []string{"apt_upgrades_pending", "apt_update_last_success_timestamp_seconds", "node_reboot_required"},
+		},
+		{
+			naming:  NamingV2,
+			present: []string{"apt_upgrades_pending", "apt_update_last_success_timestamp_seconds", "node_reboot_required", "apt_package_cve_info"},
+			absent:  []string{"debian_updates_available", "debian_seconds_since_last_update", "debian_reboot_required"},
+		},
+		{
+			naming:  NamingDual,
+			present: []string{"debian_updates_available", "apt_upgrades_pending", "debian_seconds_since_last_update", "apt_update_last_success_timestamp_seconds"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.naming), func(t *testing.T) {
+			store := NewStore()
+			NewMetricsWithNaming("debian", tt.naming, store)
+			values := familyValues(store)
+			for _, name := range tt.present {
+				if _, ok := values[name]; !ok {
+					t.Errorf("Expected metric %s", name)
+				}
+			}
+			for _, name := range tt.absent {
+				if _, ok := values[name]; ok {
+					t.Errorf("Expected no metric %s", name)
+				}
+			}
+		})
+	}
+}
+
+func TestNamingDualWritesBoth(t *testing.T) {
+	store := NewStore()
+	m := NewMetricsWithNaming("ubuntu", NamingDual, store)
+	m.UpdatesAvailable.Set(7)
+	m.Vulnerabilities.WithLabelValues("high").Set(2)
+	m.CollectionErrors.WithLabelValues("updates").Inc()
+
+	values := familyValues(store)
+	want := map[string]float64{
+		"ubuntu_updates_available":      7,
+		"apt_upgrades_pending":          7,
+		"ubuntu_vulnerabilities":        2,
+		"apt_vulnerabilities":           2,
+		"ubuntu_collector_errors_total": 1,
+		"apt_collector_errors_total":    1,
+	}
+	got := make(map[string]float64, len(want))
+	for name := range want {
+		got[name] = values[name]
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	// Metrics without a name in a scheme are not written there
+	m.SecondsSinceLastUpdate.Set(60)
+	m.LastUpdateTimestamp.Set(1740916800)
+	values = familyValues(store)
+	if values["ubuntu_seconds_since_last_update"] != 60 || values["apt_update_last_success_timestamp_seconds"] != 1740916800 {
+		t.Errorf("Unexpected last update values %v and %v", values["ubuntu_seconds_since_last_update"], values["apt_update_last_success_timestamp_seconds"])
+	}
+}
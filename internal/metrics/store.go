@@ -0,0 +1,271 @@
+package metrics
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kind is the type of a metric kept in a Store.
+type Kind int
+
+// Kinds of stored metrics.
+const (
+	KindGauge Kind = iota
+	KindCounter
+	KindHistogram
+	KindDistribution
+)
+
+// Family is a snapshot of a stored metric and its series.
+type Family struct {
+	Desc
+	Kind   Kind
+	Points []Point
+}
+
+// Point is the current value of a series of a stored metric.
+type Point struct {
+	LabelValues []string
+	Time        time.Time
+
+	// StartTime is when the series was created, from which counters and
+	// histograms accumulate
+	StartTime time.Time
+
+	// Value of a gauge or counter
+	Value float64
+
+	// Count, Sum and BucketCounts of a histogram or distribution. BucketCounts
+	// are not cumulative and have a final entry for values above the last bucket.
+	Count        uint64
+	Sum          float64
+	BucketCounts []uint64
+}
+
+// Store is a Backend keeping the current value of every series in memory,
+// for exporters that push snapshots of the metrics rather than being scraped.
+type Store struct {
+	mu       sync.Mutex
+	families []*storeFamily
+	now      func() time.Time
+}
+
+// storeFamily holds the series of a stored metric by their label values
+type storeFamily struct {
+	desc   Desc
+	kind   Kind
+	points map[string]*Point
+}
+
+// NewStore creates an empty Store.
+func NewStore() *Store {
+	return &Store{now: time.Now}
+}
+
+// newFamily adds a metric to the store
+func (s *Store) newFamily(desc Desc, kind Kind) *storeFamily {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f := &storeFamily{desc: desc, kind: kind, points: make(map[string]*Point)}
+	s.families = append(s.families, f)
+	return f
+}
+
+// point returns the series with the given label values, creating it if needed.
+// The caller must hold s.mu.
+func (s *Store) point(f *storeFamily, lvs []string) *Point {
+	key := strings.Join(lvs, "\xff")
+	p, ok := f.points[key]
+	if !ok {
+		now := s.now()
+		p = &Point{LabelValues: append([]string(nil), lvs...), Time: now, StartTime: now}
+		f.points[key] = p
+	}
+	return p
+}
+
+// NewGauge creates a stored gauge, which like a Prometheus gauge starts at 0
+func (s *Store) NewGauge(desc Desc) Gauge {
+	f := s.newFamily(desc, KindGauge)
+	g := &storeGauge{store: s, family: f}
+	g.Set(0)
+	return g
+}
+
+// NewGaugeVec creates a stored gauge vector
+func (s *Store) NewGaugeVec(desc Desc) GaugeVec {
+	return &storeGaugeVec{store: s, family: s.newFamily(desc, KindGauge)}
+}
+
+// NewCounterVec creates a stored counter vector
+func (s *Store) NewCounterVec(desc Desc) CounterVec {
+	return &storeCounterVec{store: s, family: s.newFamily(desc, KindCounter)}
+}
+
+// NewHistogram creates a stored histogram, which starts out empty
+func (s *Store) NewHistogram(desc Desc) Histogram {
+	h := &storeHistogram{store: s, family: s.newFamily(desc, KindHistogram)}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.point(h.family, nil).BucketCounts = make([]uint64, len(desc.Buckets)+1)
+	return h
+}
+
+// NewDistribution creates a stored distribution, which starts out empty
+func (s *Store) NewDistribution(desc Desc) Distribution {
+	d := &storeDistribution{store: s, family: s.newFamily(desc, KindDistribution)}
+	d.Set(nil)
+	return d
+}
+
+// Snapshot returns the current values of all stored metrics, in order of
+// creation, with the series of each metric sorted by label values.
+func (s *Store) Snapshot() []Family {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	families := make([]Family, 0, len(s.families))
+	for _, f := range s.families {
+		keys := make([]string, 0, len(f.points))
+		for key := range f.points {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		family := Family{Desc: f.desc, Kind: f.kind, Points: make([]Point, 0, len(keys))}
+		for _, key := range keys {
+			p := *f.points[key]
+			p.BucketCounts = append([]uint64(nil), p.BucketCounts...)
+			family.Points = append(family.Points, p)
+		}
+		families = append(families, family)
+	}
+	return families
+}
+
+// storeGauge is a series of a stored gauge
+type storeGauge struct {
+	store  *Store
+	family *storeFamily
+	lvs    []string
+}
+
+// Set sets the gauge to an arbitrary value
+func (g *storeGauge) Set(val float64) {
+	g.store.mu.Lock()
+	defer g.store.mu.Unlock()
+
+	p := g.store.point(g.family, g.lvs)
+	p.Value, p.Time = val, g.store.now()
+}
+
+// storeGaugeVec is a stored gauge vector
+type storeGaugeVec struct {
+	store  *Store
+	family *storeFamily
+}
+
+// WithLabelValues returns the gauge for the given label values, creating it at 0 if needed
+func (v *storeGaugeVec) WithLabelValues(lvs ...string) Gauge {
+	v.store.mu.Lock()
+	defer v.store.mu.Unlock()
+
+	v.store.point(v.family, lvs)
+	return &storeGauge{store: v.store, family: v.family, lvs: append([]string(nil), lvs...)}
+}
+
+// DeleteLabelValues removes the series with the given label values, reporting whether it existed
+func (v *storeGaugeVec) DeleteLabelValues(lvs ...string) bool {
+	v.store.mu.Lock()
+	defer v.store.mu.Unlock()
+
+	key := strings.Join(lvs, "\xff")
+	_, ok := v.family.points[key]
+	delete(v.family.points, key)
+	return ok
+}
+
+// Reset removes all series from the vector
+func (v *storeGaugeVec) Reset() {
+	v.store.mu.Lock()
+	defer v.store.mu.Unlock()
+
+	v.family.points = make(map[string]*Point)
+}
+
+// storeCounter is a series of a stored counter
+type storeCounter struct {
+	store  *Store
+	family *storeFamily
+	lvs    []string
+}
+
+// Inc increments the counter
+func (c *storeCounter) Inc() {
+	c.store.mu.Lock()
+	defer c.store.mu.Unlock()
+
+	p := c.store.point(c.family, c.lvs)
+	p.Value++
+	p.Time = c.store.now()
+}
+
+// storeCounterVec is a stored counter vector
+type storeCounterVec struct {
+	store  *Store
+	family *storeFamily
+}
+
+// WithLabelValues returns the counter for the given label values, creating it at 0 if needed
+func (v *storeCounterVec) WithLabelValues(lvs ...string) Counter {
+	v.store.mu.Lock()
+	defer v.store.mu.Unlock()
+
+	v.store.point(v.family, lvs)
+	return &storeCounter{store: v.store, family: v.family, lvs: append([]string(nil), lvs...)}
+}
+
+// storeHistogram is a stored histogram
+type storeHistogram struct {
+	store  *Store
+	family *storeFamily
+}
+
+// Observe adds the value to the histogram
+func (h *storeHistogram) Observe(val float64) {
+	h.store.mu.Lock()
+	defer h.store.mu.Unlock()
+
+	p := h.store.point(h.family, nil)
+	p.Count++
+	p.Sum += val
+	p.BucketCounts[sort.SearchFloat64s(h.family.desc.Buckets, val)]++
+	p.Time = h.store.now()
+}
+
+// storeDistribution is a stored distribution
+type storeDistribution struct {
+	store  *Store
+	family *storeFamily
+}
+
+// Set replaces the distribution with the distribution of values
+func (d *storeDistribution) Set(values []float64) {
+	buckets := d.family.desc.Buckets
+	counts := make([]uint64, len(buckets)+1)
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+		i := sort.SearchFloat64s(buckets, v)
+		counts[i]++
+	}
+
+	d.store.mu.Lock()
+	defer d.store.mu.Unlock()
+
+	p := d.store.point(d.family, nil)
+	p.Count, p.Sum, p.BucketCounts, p.Time = uint64(len(values)), sum, counts, d.store.now()
+}
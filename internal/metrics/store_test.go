@@ -0,0 +1,99 @@
+package metrics
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	s := NewStore()
+	now := time.Date(2025, 3, 2, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	gauge := s.NewGauge(Desc{Name: "test_gauge", Help: "Test gauge"})
+	vec := s.NewGaugeVec(Desc{Name: "test_vec", Help: "Test vector", LabelNames: []string{"severity"}})
+	dist := s.NewDistribution(Desc{Name: "test_age_seconds", Help: "Test ages", Buckets: []float64{10, 100}})
+
+	// Gauges start at 0, like Prometheus gauges
+	families := s.Snapshot()
+	if len(families) != 3 {
+		t.Fatalf("Expected 3 families, got %d", len(families))
+	}
+	if points := families[0].Points; len(points) != 1 || points[0].Value != 0 {
+		t.Errorf("Expected a single gauge point at 0, got %+v", points)
+	}
+	if points := families[1].Points; len(points) != 0 {
+		t.Errorf("Expected no vector points before use, got %+v", points)
+	}
+
+	gauge.Set(3)
+	vec.WithLabelValues("low").Set(2)
+	vec.WithLabelValues("high").Set(1)
+	dist.Set([]float64{5, 10, 50, 500})
+
+	families = s.Snapshot()
+	if got := families[0].Points[0]; got.Value != 3 || !got.Time.Equal(now) {
+		t.Errorf("Expected gauge 3 at %v, got %+v", now, got)
+	}
+
+	// Series are sorted by label values
+	var labels []string
+	for _, p := range families[1].Points {
+		labels = append(labels, p.LabelValues[0])
+	}
+	if !reflect.DeepEqual(labels, []string{"high", "low"}) {
+		t.Errorf("Expected series high and low, got %v", labels)
+	}
+
+	// Bucket counts are not cumulative and end with the +Inf bucket
+	d := families[2]
+	if d.Kind != KindDistribution {
+		t.Errorf("Expected a distribution, got kind %d", d.Kind)
+	}
+	p := d.Points[0]
+	if p.Count != 4 || p.Sum != 565 || !reflect.DeepEqual(p.BucketCounts, []uint64{2, 1, 1}) {
+		t.Errorf("Unexpected distribution point %+v", p)
+	}
+}
+
+func TestStoreCounterAndHistogram(t *testing.T) {
+	s := NewStore()
+	start := time.Date(2025, 3, 2, 12, 0, 0, 0, time.UTC)
+	now := start
+	s.now = func() time.Time { return now }
+
+	counter := s.NewCounterVec(Desc{Name: "test_errors_total", Help: "Test errors", LabelNames: []string{"check"}})
+	histogram := s.NewHistogram(Desc{Name: "test_duration_seconds", Help: "Test durations", Buckets: []float64{1, 10}})
+
+	// Histograms start empty, with the +Inf bucket
+	families := s.Snapshot()
+	if got := families[1].Points[0]; got.Count != 0 || !reflect.DeepEqual(got.BucketCounts, []uint64{0, 0, 0}) {
+		t.Errorf("Expected an empty histogram, got %+v", got)
+	}
+
+	counter.WithLabelValues("apt")
+	now = start.Add(time.Minute)
+	counter.WithLabelValues("apt").Inc()
+	counter.WithLabelValues("apt").Inc()
+	histogram.Observe(0.5)
+	histogram.Observe(20)
+
+	// Counters and histograms accumulate from their start time
+	families = s.Snapshot()
+	c := families[0]
+	if c.Kind != KindCounter {
+		t.Errorf("Expected a counter, got kind %d", c.Kind)
+	}
+	if got := c.Points[0]; got.Value != 2 || !got.StartTime.Equal(start) || !got.Time.Equal(now) {
+		t.Errorf("Expected counter 2 from %v to %v, got %+v", start, now, got)
+	}
+
+	h := families[1]
+	if h.Kind != KindHistogram {
+		t.Errorf("Expected a histogram, got kind %d", h.Kind)
+	}
+	if got := h.Points[0]; got.Count != 2 || got.Sum != 20.5 || !reflect.DeepEqual(got.BucketCounts, []uint64{1, 0, 1}) || !got.StartTime.Equal(start) {
+		t.Errorf("Unexpected histogram point %+v", got)
+	}
+}
@@ -0,0 +1,84 @@
+// Package osrelease reads operating system identification from os-release files.
+package osrelease
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Info holds the variables of an os-release file, e.g. ID, VERSION_ID and VERSION_CODENAME.
+type Info map[string]string
+
+// ID returns the lower-case operating system identifier, e.g. "ubuntu" or "debian".
+func (i Info) ID() string {
+	return i["ID"]
+}
+
+// VersionID returns the operating system version, e.g. "22.04" or "12".
+func (i Info) VersionID() string {
+	return i["VERSION_ID"]
+}
+
+// Codename returns the release codename, e.g. "jammy" or "bookworm".
+func (i Info) Codename() string {
+	if codename := i["VERSION_CODENAME"]; codename != "" {
+		return codename
+	}
+	return i["UBUNTU_CODENAME"]
+}
+
+// Read parses an os-release file.
+func Read(path string) (Info, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open os-release file: %w", err)
+	}
+	defer f.Close()
+
+	info, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read os-release file %s: %w", path, err)
+	}
+	return info, nil
+}
+
+// Parse parses os-release content.
+func Parse(r io.Reader) (Info, error) {
+	info := make(Info)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		info[key] = unquote(value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// unquote removes shell-style quoting from an os-release value.
+func unquote(value string) string {
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			return unquoted
+		}
+		return value[1 : len(value)-1]
+	}
+	if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
@@ -0,0 +1,47 @@
+package osrelease
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "os-release")
+	content := `PRETTY_NAME="Ubuntu 22.04.3 LTS"
+NAME="Ubuntu"
+VERSION_ID="22.04"
+# A comment
+ID=ubuntu
+ID_LIKE='debian'
+UBUNTU_CODENAME=jammy
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write os-release file: %v", err)
+	}
+
+	info, err := Read(path)
+	if err != nil {
+		t.Fatalf("Read() returned error: %v", err)
+	}
+
+	if info.ID() != "ubuntu" {
+		t.Errorf("Expected ID to be 'ubuntu', got %q", info.ID())
+	}
+	if info.VersionID() != "22.04" {
+		t.Errorf("Expected VERSION_ID to be '22.04', got %q", info.VersionID())
+	}
+	if info.Codename() != "jammy" {
+		t.Errorf("Expected codename to fall back to UBUNTU_CODENAME 'jammy', got %q", info.Codename())
+	}
+	if info["PRETTY_NAME"] != "Ubuntu 22.04.3 LTS" {
+		t.Errorf("Expected PRETTY_NAME to be unquoted, got %q", info["PRETTY_NAME"])
+	}
+	if info["ID_LIKE"] != "debian" {
+		t.Errorf("Expected single-quoted ID_LIKE to be unquoted, got %q", info["ID_LIKE"])
+	}
+
+	if _, err := Read(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("Expected error for missing file, got nil")
+	}
+}
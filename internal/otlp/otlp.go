@@ -0,0 +1,140 @@
+// Package otlp exports the exporter's metrics to an OpenTelemetry collector
+// over OTLP/HTTP, for platforms that standardise on OpenTelemetry.
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/ncecere/apt-exporter/internal/config"
+	"github.com/ncecere/apt-exporter/internal/metrics"
+	"github.com/ncecere/apt-exporter/internal/osrelease"
+)
+
+// Exporter sends the metrics kept in a Store to an OpenTelemetry collector
+// whenever triggered.
+type Exporter struct {
+	cfg      config.OTLPConfig
+	store    *metrics.Store
+	resource []Attribute
+	version  string
+	client   *http.Client
+	logger   *slog.Logger
+	trigger  chan struct{}
+}
+
+// New creates an Exporter for the metrics in store, describing the host
+// with the given resource attributes.
+func New(cfg config.OTLPConfig, store *metrics.Store, resource []Attribute, version string, logger *slog.Logger) *Exporter {
+	return &Exporter{
+		cfg:      cfg,
+		store:    store,
+		resource: resource,
+		version:  version,
+		client:   &http.Client{Timeout: time.Duration(cfg.TimeoutSeconds) * time.Second},
+		logger:   logger,
+		trigger:  make(chan struct{}, 1),
+	}
+}
+
+// Resource returns the resource attributes of the inspected system,
+// following the OpenTelemetry semantic conventions for hosts and operating
+// systems, overridden by the configured attributes. The OS is read from
+// the os-release file at osReleasePath and omitted if that fails.
+func Resource(cfg config.OTLPConfig, osReleasePath, version string) []Attribute {
+	attrs := map[string]string{
+		"service.name":    "apt-exporter",
+		"service.version": version,
+		"host.arch":       runtime.GOARCH,
+		"os.type":         runtime.GOOS,
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		attrs["host.name"] = hostname
+	}
+	if info, err := osrelease.Read(osReleasePath); err == nil {
+		for key, field := range map[string]string{
+			"os.name":        "NAME",
+			"os.version":     "VERSION_ID",
+			"os.description": "PRETTY_NAME",
+		} {
+			if value := info[field]; value != "" {
+				attrs[key] = value
+			}
+		}
+	}
+	for key, value := range cfg.ResourceAttributes {
+		attrs[key] = value
+	}
+
+	keys := make([]string, 0, len(attrs))
+	for key := range attrs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	resource := make([]Attribute, 0, len(keys))
+	for _, key := range keys {
+		resource = append(resource, Attribute{key, attrs[key]})
+	}
+	return resource
+}
+
+// Trigger requests an export without blocking. Triggers that arrive while
+// an export is pending are coalesced.
+func (e *Exporter) Trigger() {
+	select {
+	case e.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Run exports whenever triggered until the context is canceled.
+func (e *Exporter) Run(ctx context.Context) {
+	for {
+		select {
+		case <-e.trigger:
+		case <-ctx.Done():
+			return
+		}
+
+		if err := e.Export(ctx); err != nil {
+			e.logger.Error("Error exporting metrics over OTLP", "endpoint", e.cfg.Endpoint, "error", err)
+		}
+	}
+}
+
+// Export sends the current metrics to the collector.
+func (e *Exporter) Export(ctx context.Context) error {
+	body := encodeRequest(e.store.Snapshot(), e.resource, e.version)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for name, value := range e.cfg.Headers {
+		req.Header.Set(name, string(value))
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("User-Agent", "apt-exporter/"+e.version)
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to export to %s: %w", e.cfg.Endpoint, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s returned %s: %s", e.cfg.Endpoint, resp.Status, bytes.TrimSpace(msg))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
@@ -0,0 +1,224 @@
+package otlp
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ncecere/apt-exporter/internal/config"
+	"github.com/ncecere/apt-exporter/internal/metrics"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// field is a decoded protobuf field.
+type field struct {
+	num   protowire.Number
+	bytes []byte
+	value uint64
+}
+
+// decode splits a protobuf message into its fields.
+func decode(t *testing.T, b []byte) []field {
+	t.Helper()
+
+	var fields []field
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			t.Fatalf("Invalid tag: %v", protowire.ParseError(n))
+		}
+		b = b[n:]
+
+		f := field{num: num}
+		switch typ {
+		case protowire.BytesType:
+			f.bytes, n = protowire.ConsumeBytes(b)
+		case protowire.Fixed64Type:
+			f.value, n = protowire.ConsumeFixed64(b)
+		case protowire.VarintType:
+			f.value, n = protowire.ConsumeVarint(b)
+		default:
+			t.Fatalf("Unexpected wire type %d", typ)
+		}
+		if n < 0 {
+			t.Fatalf("Invalid field %d: %v", num, protowire.ParseError(n))
+		}
+		b = b[n:]
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// get returns the fields with the given number.
+func get(fields []field, num protowire.Number) []field {
+	var matching []field
+	for _, f := range fields {
+		if f.num == num {
+			matching = append(matching, f)
+		}
+	}
+	return matching
+}
+
+// attributes decodes KeyValue fields with string values.
+func attributes(t *testing.T, fields []field) map[string]string {
+	t.Helper()
+
+	attrs := make(map[string]string)
+	for _, f := range fields {
+		kv := decode(t, f.bytes)
+		value := decode(t, get(kv, keyValueValue)[0].bytes)
+		attrs[string(get(kv, keyValueKey)[0].bytes)] = string(get(value, anyValueString)[0].bytes)
+	}
+	return attrs
+}
+
+func TestExport(t *testing.T) {
+	var body []byte
+	var header http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		header = r.Header
+	}))
+	defer ts.Close()
+
+	store := metrics.NewStore()
+	m := metrics.NewMetricsWithBackend("apt", metrics.WithConstLabels(store, map[string]string{"env": "prod"}))
+	m.UpdatesAvailable.Set(12)
+	m.Vulnerabilities.WithLabelValues("high").Set(2)
+	m.PendingUpdateAgeSeconds.Set([]float64{60, 100000})
+	m.CollectionErrors.WithLabelValues("apt").Inc()
+	m.CollectionDurationSeconds.Observe(0.3)
+
+	cfg := config.OTLPConfig{
+		Endpoint:       ts.URL + "/v1/metrics",
+		Headers:        map[string]config.Secret{"Authorization": "Bearer token"},
+		TimeoutSeconds: 5,
+	}
+	resource := []Attribute{{"host.name", "web-1"}, {"os.name", "Ubuntu"}}
+	e := New(cfg, store, resource, "1.2.3", nil)
+	if err := e.Export(context.Background()); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	if got := header.Get("Content-Type"); got != "application/x-protobuf" {
+		t.Errorf("Expected protobuf content type, got %q", got)
+	}
+	if got := header.Get("Authorization"); got != "Bearer token" {
+		t.Errorf("Expected configured header, got %q", got)
+	}
+
+	rm := decode(t, get(decode(t, body), requestResourceMetrics)[0].bytes)
+	res := decode(t, get(rm, resourceMetricsResource)[0].bytes)
+	if got := attributes(t, get(res, resourceAttributes)); !reflect.DeepEqual(got, map[string]string{"host.name": "web-1", "os.name": "Ubuntu"}) {
+		t.Errorf("Unexpected resource attributes %v", got)
+	}
+
+	// Index the metrics by name
+	sm := decode(t, get(rm, resourceMetricsScopeMetrics)[0].bytes)
+	byName := make(map[string][]field)
+	for _, f := range get(sm, scopeMetricsMetrics) {
+		metric := decode(t, f.bytes)
+		byName[string(get(metric, metricName)[0].bytes)] = metric
+	}
+	if len(byName) != 18 {
+		t.Errorf("Expected 18 metrics, got %d", len(byName))
+	}
+
+	// Gauges carry their value and labels as attributes
+	gauge := decode(t, get(byName["apt_updates_available"], metricGauge)[0].bytes)
+	point := decode(t, get(gauge, gaugeDataPoints)[0].bytes)
+	if got := math.Float64frombits(get(point, numberPointAsDouble)[0].value); got != 12 {
+		t.Errorf("Expected apt_updates_available 12, got %v", got)
+	}
+
+	gauge = decode(t, get(byName["apt_vulnerabilities"], metricGauge)[0].bytes)
+	point = decode(t, get(gauge, gaugeDataPoints)[0].bytes)
+	if got := attributes(t, get(point, numberPointAttributes)); !reflect.DeepEqual(got, map[string]string{"severity": "high", "env": "prod"}) {
+		t.Errorf("Expected severity and const label attributes, got %v", got)
+	}
+
+	// Counters are cumulative monotonic sums with a start time
+	sum := decode(t, get(byName["apt_collector_errors_total"], metricSum)[0].bytes)
+	if got := get(sum, sumIsMonotonic)[0].value; got != 1 {
+		t.Errorf("Expected a monotonic sum, got %d", got)
+	}
+	point = decode(t, get(sum, sumDataPoints)[0].bytes)
+	if got := math.Float64frombits(get(point, numberPointAsDouble)[0].value); got != 1 {
+		t.Errorf("Expected apt_collector_errors_total 1, got %v", got)
+	}
+	if len(get(point, numberPointStartTime)) != 1 {
+		t.Error("Expected a start time on a counter point")
+	}
+
+	histogram := decode(t, get(byName["apt_collector_duration_seconds"], metricHistogram)[0].bytes)
+	point = decode(t, get(histogram, histogramDataPoints)[0].bytes)
+	if got := get(point, histogramPointCount)[0].value; got != 1 {
+		t.Errorf("Expected a single observed duration, got %d", got)
+	}
+
+	// Distributions are cumulative histograms with per-bucket counts
+	metric := byName["apt_pending_update_age_seconds"]
+	if unit := get(metric, metricUnit); len(unit) != 1 || string(unit[0].bytes) != "s" {
+		t.Errorf("Expected unit s for a _seconds metric")
+	}
+	histogram = decode(t, get(metric, metricHistogram)[0].bytes)
+	if got := get(histogram, histogramAggregationTemporality)[0].value; got != aggregationTemporalityCumulative {
+		t.Errorf("Expected cumulative temporality, got %d", got)
+	}
+	point = decode(t, get(histogram, histogramDataPoints)[0].bytes)
+	if got := get(point, histogramPointCount)[0].value; got != 2 {
+		t.Errorf("Expected count 2, got %d", got)
+	}
+	counts := get(point, histogramPointBucketCounts)[0].bytes
+	if n := len(counts) / 8; n != len(metrics.PendingUpdateAgeBuckets)+1 {
+		t.Errorf("Expected %d bucket counts, got %d", len(metrics.PendingUpdateAgeBuckets)+1, n)
+	}
+}
+
+func TestExportError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad request", http.StatusBadRequest)
+	}))
+	defer ts.Close()
+
+	e := New(config.OTLPConfig{Endpoint: ts.URL, TimeoutSeconds: 5}, metrics.NewStore(), nil, "1.2.3", nil)
+	if err := e.Export(context.Background()); err == nil {
+		t.Error("Expected an error for a rejected export")
+	}
+}
+
+func TestResource(t *testing.T) {
+	osRelease := filepath.Join(t.TempDir(), "os-release")
+	content := "NAME=\"Ubuntu\"\nVERSION_ID=\"22.04\"\nPRETTY_NAME=\"Ubuntu 22.04.4 LTS\"\n"
+	if err := os.WriteFile(osRelease, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write os-release: %v", err)
+	}
+
+	cfg := config.OTLPConfig{ResourceAttributes: map[string]string{"deployment.environment": "production", "host.name": "web-1"}}
+	attrs := make(map[string]string)
+	for _, attr := range Resource(cfg, osRelease, "1.2.3") {
+		attrs[attr.Key] = attr.Value
+	}
+
+	for key, want := range map[string]string{
+		"service.name":           "apt-exporter",
+		"service.version":        "1.2.3",
+		"os.name":                "Ubuntu",
+		"os.version":             "22.04",
+		"os.description":         "Ubuntu 22.04.4 LTS",
+		"deployment.environment": "production",
+		// Configured attributes take precedence over detected ones
+		"host.name": "web-1",
+	} {
+		if attrs[key] != want {
+			t.Errorf("Expected %s %q, got %q", key, want, attrs[key])
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package otlp
+
+import (
+	"maps"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/ncecere/apt-exporter/internal/metrics"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Field numbers of the OTLP metrics protobuf messages
+// (opentelemetry/proto/collector/metrics/v1 and metrics/v1).
+const (
+	requestResourceMetrics = 1
+
+	resourceMetricsResource     = 1
+	resourceMetricsScopeMetrics = 2
+
+	resourceAttributes = 1
+
+	scopeMetricsScope   = 1
+	scopeMetricsMetrics = 2
+
+	scopeName    = 1
+	scopeVersion = 2
+
+	metricName        = 1
+	metricDescription = 2
+	metricUnit        = 3
+	metricGauge       = 5
+	metricSum         = 7
+	metricHistogram   = 9
+
+	gaugeDataPoints = 1
+
+	sumDataPoints             = 1
+	sumAggregationTemporality = 2
+	sumIsMonotonic            = 3
+
+	histogramDataPoints             = 1
+	histogramAggregationTemporality = 2
+
+	numberPointStartTime  = 2
+	numberPointTime       = 3
+	numberPointAsDouble   = 4
+	numberPointAttributes = 7
+
+	histogramPointStartTime      = 2
+	histogramPointTime           = 3
+	histogramPointCount          = 4
+	histogramPointSum            = 5
+	histogramPointBucketCounts   = 6
+	histogramPointExplicitBounds = 7
+	histogramPointAttributes     = 9
+
+	keyValueKey   = 1
+	keyValueValue = 2
+
+	anyValueString = 1
+
+	// aggregationTemporalityCumulative is AGGREGATION_TEMPORALITY_CUMULATIVE
+	aggregationTemporalityCumulative = 2
+)
+
+// Attribute is a key-value pair of a resource or data point.
+type Attribute struct {
+	Key, Value string
+}
+
+// encodeRequest encodes the stored metrics as an ExportMetricsServiceRequest
+// with a single resource and instrumentation scope. Gauges map to OTel
+// gauges, with the metric labels as attributes, counters to cumulative
+// monotonic sums, and histograms and distributions to cumulative histograms.
+func encodeRequest(families []metrics.Family, resource []Attribute, version string) []byte {
+	var res []byte
+	for _, attr := range resource {
+		res = appendMessage(res, resourceAttributes, appendKeyValue(nil, attr))
+	}
+
+	var scope []byte
+	scope = protowire.AppendTag(scope, scopeName, protowire.BytesType)
+	scope = protowire.AppendString(scope, "github.com/ncecere/apt-exporter")
+	scope = protowire.AppendTag(scope, scopeVersion, protowire.BytesType)
+	scope = protowire.AppendString(scope, version)
+
+	var sm []byte
+	sm = appendMessage(sm, scopeMetricsScope, scope)
+	for _, f := range families {
+		sm = appendMessage(sm, scopeMetricsMetrics, encodeMetric(f))
+	}
+
+	var rm []byte
+	rm = appendMessage(rm, resourceMetricsResource, res)
+	rm = appendMessage(rm, resourceMetricsScopeMetrics, sm)
+
+	return appendMessage(nil, requestResourceMetrics, rm)
+}
+
+// encodeMetric encodes a Metric with the data points of a family.
+func encodeMetric(f metrics.Family) []byte {
+	var m []byte
+	m = protowire.AppendTag(m, metricName, protowire.BytesType)
+	m = protowire.AppendString(m, f.Name)
+	m = protowire.AppendTag(m, metricDescription, protowire.BytesType)
+	m = protowire.AppendString(m, f.Help)
+	if strings.HasSuffix(f.Name, "_seconds") {
+		m = protowire.AppendTag(m, metricUnit, protowire.BytesType)
+		m = protowire.AppendString(m, "s")
+	}
+
+	var data []byte
+	switch f.Kind {
+	case metrics.KindGauge:
+		for _, p := range f.Points {
+			data = appendMessage(data, gaugeDataPoints, encodeNumberPoint(f.Desc, f.Kind, p))
+		}
+		m = appendMessage(m, metricGauge, data)
+	case metrics.KindCounter:
+		for _, p := range f.Points {
+			data = appendMessage(data, sumDataPoints, encodeNumberPoint(f.Desc, f.Kind, p))
+		}
+		data = protowire.AppendTag(data, sumAggregationTemporality, protowire.VarintType)
+		data = protowire.AppendVarint(data, aggregationTemporalityCumulative)
+		data = protowire.AppendTag(data, sumIsMonotonic, protowire.VarintType)
+		data = protowire.AppendVarint(data, 1)
+		m = appendMessage(m, metricSum, data)
+	case metrics.KindHistogram, metrics.KindDistribution:
+		for _, p := range f.Points {
+			// A distribution is replaced as a whole, so it starts when it was set
+			if f.Kind == metrics.KindDistribution {
+				p.StartTime = p.Time
+			}
+			data = appendMessage(data, histogramDataPoints, encodeHistogramPoint(f.Desc, p))
+		}
+		data = protowire.AppendTag(data, histogramAggregationTemporality, protowire.VarintType)
+		data = protowire.AppendVarint(data, aggregationTemporalityCumulative)
+		m = appendMessage(m, metricHistogram, data)
+	}
+	return m
+}
+
+// encodeNumberPoint encodes the NumberDataPoint of a gauge or counter series.
+// Only counters accumulate from a start time.
+func encodeNumberPoint(desc metrics.Desc, kind metrics.Kind, p metrics.Point) []byte {
+	var b []byte
+	if kind == metrics.KindCounter {
+		b = protowire.AppendTag(b, numberPointStartTime, protowire.Fixed64Type)
+		b = protowire.AppendFixed64(b, uint64(p.StartTime.UnixNano()))
+	}
+	b = protowire.AppendTag(b, numberPointTime, protowire.Fixed64Type)
+	b = protowire.AppendFixed64(b, uint64(p.Time.UnixNano()))
+	b = protowire.AppendTag(b, numberPointAsDouble, protowire.Fixed64Type)
+	b = protowire.AppendFixed64(b, math.Float64bits(p.Value))
+	for _, attr := range pointAttributes(desc, p) {
+		b = appendMessage(b, numberPointAttributes, appendKeyValue(nil, attr))
+	}
+	return b
+}
+
+// encodeHistogramPoint encodes the HistogramDataPoint of a histogram or distribution.
+func encodeHistogramPoint(desc metrics.Desc, p metrics.Point) []byte {
+	var b []byte
+	b = protowire.AppendTag(b, histogramPointStartTime, protowire.Fixed64Type)
+	b = protowire.AppendFixed64(b, uint64(p.StartTime.UnixNano()))
+	b = protowire.AppendTag(b, histogramPointTime, protowire.Fixed64Type)
+	b = protowire.AppendFixed64(b, uint64(p.Time.UnixNano()))
+	b = protowire.AppendTag(b, histogramPointCount, protowire.Fixed64Type)
+	b = protowire.AppendFixed64(b, p.Count)
+	b = protowire.AppendTag(b, histogramPointSum, protowire.Fixed64Type)
+	b = protowire.AppendFixed64(b, math.Float64bits(p.Sum))
+
+	var counts []byte
+	for _, c := range p.BucketCounts {
+		counts = protowire.AppendFixed64(counts, c)
+	}
+	b = appendMessage(b, histogramPointBucketCounts, counts)
+
+	var bounds []byte
+	for _, upper := range desc.Buckets {
+		bounds = protowire.AppendFixed64(bounds, math.Float64bits(upper))
+	}
+	b = appendMessage(b, histogramPointExplicitBounds, bounds)
+
+	for _, attr := range pointAttributes(desc, p) {
+		b = appendMessage(b, histogramPointAttributes, appendKeyValue(nil, attr))
+	}
+	return b
+}
+
+// pointAttributes pairs the label names of a metric with the label values
+// of a series, dropping empty values as Prometheus does, followed by the
+// metric's const labels in name order.
+func pointAttributes(desc metrics.Desc, p metrics.Point) []Attribute {
+	attrs := make([]Attribute, 0, len(desc.LabelNames)+len(desc.ConstLabels))
+	for i, name := range desc.LabelNames {
+		if i < len(p.LabelValues) && p.LabelValues[i] != "" {
+			attrs = append(attrs, Attribute{name, p.LabelValues[i]})
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(desc.ConstLabels)) {
+		if value := desc.ConstLabels[name]; value != "" {
+			attrs = append(attrs, Attribute{name, value})
+		}
+	}
+	return attrs
+}
+
+// appendKeyValue appends the fields of a KeyValue with a string value.
+func appendKeyValue(b []byte, attr Attribute) []byte {
+	var value []byte
+	value = protowire.AppendTag(value, anyValueString, protowire.BytesType)
+	value = protowire.AppendString(value, attr.Value)
+
+	b = protowire.AppendTag(b, keyValueKey, protowire.BytesType)
+	b = protowire.AppendString(b, attr.Key)
+	return appendMessage(b, keyValueValue, value)
+}
+
+// appendMessage appends an embedded message or packed repeated field.
+func appendMessage(b []byte, num protowire.Number, msg []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, msg)
+}
@@ -0,0 +1,104 @@
+// Package pushgateway pushes the exporter's metrics to a Prometheus
+// Pushgateway, for hosts that Prometheus cannot scrape.
+package pushgateway
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/ncecere/apt-exporter/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// requestTimeout bounds each request to the Pushgateway.
+const requestTimeout = 10 * time.Second
+
+// Pusher pushes the metrics of a gatherer when triggered and, optionally, periodically.
+type Pusher struct {
+	cfg     config.PushgatewayConfig
+	pusher  *push.Pusher
+	logger  *slog.Logger
+	trigger chan struct{}
+}
+
+// New creates a Pusher for the metrics of gatherer.
+func New(cfg config.PushgatewayConfig, gatherer prometheus.Gatherer, logger *slog.Logger) *Pusher {
+	pusher := push.New(cfg.URL, cfg.Job).
+		Gatherer(gatherer).
+		Client(&http.Client{Timeout: requestTimeout})
+
+	for name, value := range cfg.Grouping {
+		pusher.Grouping(name, value)
+	}
+
+	if cfg.Username != "" {
+		pusher.BasicAuth(cfg.Username, string(cfg.Password))
+	}
+
+	return &Pusher{
+		cfg:     cfg,
+		pusher:  pusher,
+		logger:  logger,
+		trigger: make(chan struct{}, 1),
+	}
+}
+
+// Trigger requests a push without blocking. Triggers that arrive while a
+// push is pending are coalesced.
+func (p *Pusher) Trigger() {
+	select {
+	case p.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Push replaces the group on the Pushgateway with the current metrics.
+func (p *Pusher) Push(ctx context.Context) error {
+	if err := p.pusher.PushContext(ctx); err != nil {
+		return fmt.Errorf("failed to push to %s: %w", p.cfg.URL, err)
+	}
+	return nil
+}
+
+// Delete removes the group from the Pushgateway.
+func (p *Pusher) Delete() error {
+	if err := p.pusher.Delete(); err != nil {
+		return fmt.Errorf("failed to delete group from %s: %w", p.cfg.URL, err)
+	}
+	return nil
+}
+
+// Run pushes whenever triggered, and every interval_seconds if set, until
+// the context is canceled. The group is then deleted if configured.
+func (p *Pusher) Run(ctx context.Context) {
+	var tick <-chan time.Time
+	if p.cfg.IntervalSeconds > 0 {
+		ticker := time.NewTicker(time.Duration(p.cfg.IntervalSeconds) * time.Second)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-p.trigger:
+		case <-tick:
+		case <-ctx.Done():
+			if p.cfg.DeleteOnShutdown {
+				if err := p.Delete(); err != nil {
+					p.logger.Error("Error deleting metrics group", "url", p.cfg.URL, "error", err)
+				} else {
+					p.logger.Info("Deleted metrics group", "url", p.cfg.URL)
+				}
+			}
+			return
+		}
+
+		if err := p.Push(ctx); err != nil {
+			p.logger.Error("Error pushing metrics", "url", p.cfg.URL, "error", err)
+		}
+	}
+}
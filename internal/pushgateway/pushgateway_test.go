@@ -0,0 +1,130 @@
+package pushgateway
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ncecere/apt-exporter/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// request is a request received by the test Pushgateway.
+type request struct {
+	method string
+	path   string
+	user   string
+	pass   string
+	body   string
+}
+
+// gateway is a minimal Pushgateway stand-in recording the requests it receives.
+type gateway struct {
+	mu       sync.Mutex
+	requests []request
+	received chan struct{}
+}
+
+func newGateway(t *testing.T) (*gateway, *httptest.Server) {
+	t.Helper()
+
+	g := &gateway{received: make(chan struct{}, 10)}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		user, pass, _ := r.BasicAuth()
+		g.mu.Lock()
+		g.requests = append(g.requests, request{r.Method, r.URL.Path, user, pass, string(body)})
+		g.mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+		g.received <- struct{}{}
+	}))
+	t.Cleanup(ts.Close)
+	return g, ts
+}
+
+// wait waits for the next request to be received.
+func (g *gateway) wait(t *testing.T) request {
+	t.Helper()
+
+	select {
+	case <-g.received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for a request to the Pushgateway")
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.requests[len(g.requests)-1]
+}
+
+func TestRun(t *testing.T) {
+	g, ts := newGateway(t)
+
+	registry := prometheus.NewRegistry()
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_updates_available", Help: "Test gauge"})
+	gauge.Set(5)
+	registry.MustRegister(gauge)
+
+	cfg := config.PushgatewayConfig{
+		URL:              ts.URL,
+		Job:              "apt_exporter",
+		Grouping:         map[string]string{"instance": "web-1", "datacenter": "fra"},
+		Username:         "apt",
+		Password:         "secret",
+		DeleteOnShutdown: true,
+	}
+	p := New(cfg, registry, slog.New(slog.DiscardHandler))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		p.Run(ctx)
+		close(done)
+	}()
+
+	p.Trigger()
+	req := g.wait(t)
+	if req.method != http.MethodPut {
+		t.Errorf("Expected push to use PUT, got %s", req.method)
+	}
+	// The grouping labels may appear in any order
+	paths := []string{
+		"/metrics/job/apt_exporter/datacenter/fra/instance/web-1",
+		"/metrics/job/apt_exporter/instance/web-1/datacenter/fra",
+	}
+	if req.path != paths[0] && req.path != paths[1] {
+		t.Errorf("Expected push to %s, got %s", paths[0], req.path)
+	}
+	if req.user != "apt" || req.pass != "secret" {
+		t.Errorf("Expected basic auth apt:secret, got %s:%s", req.user, req.pass)
+	}
+	if req.body == "" {
+		t.Error("Expected pushed metrics in the request body")
+	}
+
+	cancel()
+	<-done
+	if req := g.wait(t); req.method != http.MethodDelete {
+		t.Errorf("Expected group to be deleted on shutdown, got %s", req.method)
+	}
+}
+
+func TestPushError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad metrics", http.StatusBadRequest)
+	}))
+	defer ts.Close()
+
+	cfg := config.PushgatewayConfig{URL: ts.URL, Job: "apt_exporter"}
+	p := New(cfg, prometheus.NewRegistry(), slog.New(slog.DiscardHandler))
+
+	err := p.Push(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "400") {
+		t.Errorf("Expected push error with the status code, got %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package remotewrite
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// bufferSuffix is the file name suffix of buffered requests.
+const bufferSuffix = ".snappy"
+
+// diskBuffer keeps encoded requests that could not be delivered, one file
+// per request named after the time it was buffered, so they sort oldest first.
+type diskBuffer struct {
+	dir string
+	max int
+	seq atomic.Uint64
+}
+
+// newDiskBuffer creates the buffer directory if needed.
+func newDiskBuffer(dir string, max int) (*diskBuffer, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create remote write buffer directory: %w", err)
+	}
+	return &diskBuffer{dir: dir, max: max}, nil
+}
+
+// add buffers a request and drops the oldest requests beyond the limit.
+// It returns the number of dropped requests.
+func (b *diskBuffer) add(data []byte) (int, error) {
+	name := fmt.Sprintf("%020d-%06d%s", time.Now().UnixNano(), b.seq.Add(1)%1000000, bufferSuffix)
+	path := filepath.Join(b.dir, name)
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return 0, fmt.Errorf("failed to buffer remote write request: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return 0, fmt.Errorf("failed to buffer remote write request: %w", err)
+	}
+
+	files, err := b.list()
+	if err != nil {
+		return 0, err
+	}
+	dropped := 0
+	for len(files)-dropped > b.max {
+		if err := os.Remove(files[dropped]); err != nil {
+			return dropped, fmt.Errorf("failed to drop buffered remote write request: %w", err)
+		}
+		dropped++
+	}
+	return dropped, nil
+}
+
+// list returns the buffered requests, oldest first.
+func (b *diskBuffer) list() ([]string, error) {
+	entries, err := os.ReadDir(b.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read remote write buffer: %w", err)
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && strings.HasSuffix(entry.Name(), bufferSuffix) {
+			files = append(files, filepath.Join(b.dir, entry.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
@@ -0,0 +1,183 @@
+package remotewrite
+
+import (
+	"math"
+	"sort"
+	"strconv"
+
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Field numbers of the remote write 1.0 protobuf messages
+// (prometheus/prompb/remote.proto and types.proto).
+const (
+	writeRequestTimeseries = 1
+	writeRequestMetadata   = 3
+
+	timeSeriesLabels  = 1
+	timeSeriesSamples = 2
+
+	labelName  = 1
+	labelValue = 2
+
+	sampleValue     = 1
+	sampleTimestamp = 2
+
+	metadataType       = 1
+	metadataFamilyName = 2
+	metadataHelp       = 4
+)
+
+// Metric types of MetricMetadata.
+const (
+	metadataTypeUnknown   = 0
+	metadataTypeCounter   = 1
+	metadataTypeGauge     = 2
+	metadataTypeHistogram = 3
+	metadataTypeSummary   = 5
+)
+
+// label is a name/value pair of a series.
+type label struct {
+	name, value string
+}
+
+// encodeWriteRequest encodes the gathered metric families as a remote write
+// WriteRequest, with one sample per series at the given timestamp in milliseconds.
+// Histograms and summaries are split into their classic series.
+func encodeWriteRequest(families []*dto.MetricFamily, externalLabels map[string]string, timestamp int64) []byte {
+	var buf []byte
+	for _, mf := range families {
+		name := mf.GetName()
+		for _, m := range mf.GetMetric() {
+			base := metricLabels(m, externalLabels)
+			ts := timestamp
+			if m.TimestampMs != nil {
+				ts = m.GetTimestampMs()
+			}
+
+			switch mf.GetType() {
+			case dto.MetricType_COUNTER:
+				buf = appendSeries(buf, name, base, m.GetCounter().GetValue(), ts)
+			case dto.MetricType_GAUGE:
+				buf = appendSeries(buf, name, base, m.GetGauge().GetValue(), ts)
+			case dto.MetricType_UNTYPED:
+				buf = appendSeries(buf, name, base, m.GetUntyped().GetValue(), ts)
+			case dto.MetricType_HISTOGRAM:
+				h := m.GetHistogram()
+				infSeen := false
+				for _, b := range h.GetBucket() {
+					le := b.GetUpperBound()
+					infSeen = infSeen || math.IsInf(le, 1)
+					buf = appendSeries(buf, name+"_bucket", withLabel(base, "le", formatFloat(le)), float64(b.GetCumulativeCount()), ts)
+				}
+				if !infSeen {
+					buf = appendSeries(buf, name+"_bucket", withLabel(base, "le", "+Inf"), float64(h.GetSampleCount()), ts)
+				}
+				buf = appendSeries(buf, name+"_sum", base, h.GetSampleSum(), ts)
+				buf = appendSeries(buf, name+"_count", base, float64(h.GetSampleCount()), ts)
+			case dto.MetricType_SUMMARY:
+				s := m.GetSummary()
+				for _, q := range s.GetQuantile() {
+					buf = appendSeries(buf, name, withLabel(base, "quantile", formatFloat(q.GetQuantile())), q.GetValue(), ts)
+				}
+				buf = appendSeries(buf, name+"_sum", base, s.GetSampleSum(), ts)
+				buf = appendSeries(buf, name+"_count", base, float64(s.GetSampleCount()), ts)
+			}
+		}
+
+		buf = appendMetadata(buf, mf)
+	}
+	return buf
+}
+
+// metricLabels returns the labels of a metric merged with the external
+// labels, which do not override labels of the metric itself. Labels with
+// empty values are dropped, as Prometheus treats them as absent.
+func metricLabels(m *dto.Metric, externalLabels map[string]string) []label {
+	labels := make([]label, 0, len(m.GetLabel())+len(externalLabels))
+	seen := make(map[string]bool, len(m.GetLabel()))
+	for _, lp := range m.GetLabel() {
+		if lp.GetValue() == "" {
+			continue
+		}
+		labels = append(labels, label{lp.GetName(), lp.GetValue()})
+		seen[lp.GetName()] = true
+	}
+	for name, value := range externalLabels {
+		if !seen[name] && value != "" {
+			labels = append(labels, label{name, value})
+		}
+	}
+	return labels
+}
+
+// withLabel returns a copy of labels with an additional label.
+func withLabel(labels []label, name, value string) []label {
+	return append(append(make([]label, 0, len(labels)+1), labels...), label{name, value})
+}
+
+// appendSeries appends a TimeSeries with a single sample. Labels are sorted
+// by name, as receivers require.
+func appendSeries(buf []byte, name string, labels []label, value float64, timestamp int64) []byte {
+	all := withLabel(labels, "__name__", name)
+	sort.Slice(all, func(i, j int) bool { return all[i].name < all[j].name })
+
+	var series []byte
+	for _, l := range all {
+		var lb []byte
+		lb = protowire.AppendTag(lb, labelName, protowire.BytesType)
+		lb = protowire.AppendString(lb, l.name)
+		lb = protowire.AppendTag(lb, labelValue, protowire.BytesType)
+		lb = protowire.AppendString(lb, l.value)
+
+		series = protowire.AppendTag(series, timeSeriesLabels, protowire.BytesType)
+		series = protowire.AppendBytes(series, lb)
+	}
+
+	var sample []byte
+	sample = protowire.AppendTag(sample, sampleValue, protowire.Fixed64Type)
+	sample = protowire.AppendFixed64(sample, math.Float64bits(value))
+	sample = protowire.AppendTag(sample, sampleTimestamp, protowire.VarintType)
+	sample = protowire.AppendVarint(sample, uint64(timestamp))
+	series = protowire.AppendTag(series, timeSeriesSamples, protowire.BytesType)
+	series = protowire.AppendBytes(series, sample)
+
+	buf = protowire.AppendTag(buf, writeRequestTimeseries, protowire.BytesType)
+	return protowire.AppendBytes(buf, series)
+}
+
+// appendMetadata appends the MetricMetadata of a metric family.
+func appendMetadata(buf []byte, mf *dto.MetricFamily) []byte {
+	metricType := metadataTypeUnknown
+	switch mf.GetType() {
+	case dto.MetricType_COUNTER:
+		metricType = metadataTypeCounter
+	case dto.MetricType_GAUGE:
+		metricType = metadataTypeGauge
+	case dto.MetricType_HISTOGRAM:
+		metricType = metadataTypeHistogram
+	case dto.MetricType_SUMMARY:
+		metricType = metadataTypeSummary
+	}
+
+	var md []byte
+	md = protowire.AppendTag(md, metadataType, protowire.VarintType)
+	md = protowire.AppendVarint(md, uint64(metricType))
+	md = protowire.AppendTag(md, metadataFamilyName, protowire.BytesType)
+	md = protowire.AppendString(md, mf.GetName())
+	md = protowire.AppendTag(md, metadataHelp, protowire.BytesType)
+	md = protowire.AppendString(md, mf.GetHelp())
+
+	buf = protowire.AppendTag(buf, writeRequestMetadata, protowire.BytesType)
+	return protowire.AppendBytes(buf, md)
+}
+
+// formatFloat formats a bucket bound or quantile as Prometheus does.
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,171 @@
+package remotewrite
+
+import (
+	"math"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// series is a decoded TimeSeries with a single sample.
+type series struct {
+	labels    map[string]string
+	value     float64
+	timestamp int64
+}
+
+// decodeWriteRequest decodes the series and metadata family names of a WriteRequest.
+func decodeWriteRequest(t *testing.T, b []byte) ([]series, []string) {
+	t.Helper()
+
+	var all []series
+	var metadata []string
+	forEachField(t, b, func(num protowire.Number, v []byte) {
+		switch num {
+		case writeRequestTimeseries:
+			s := series{labels: make(map[string]string)}
+			forEachField(t, v, func(num protowire.Number, v []byte) {
+				switch num {
+				case timeSeriesLabels:
+					var name, value string
+					forEachField(t, v, func(num protowire.Number, v []byte) {
+						if num == labelName {
+							name = string(v)
+						} else {
+							value = string(v)
+						}
+					})
+					s.labels[name] = value
+				case timeSeriesSamples:
+					s.value, s.timestamp = decodeSample(t, v)
+				}
+			})
+			all = append(all, s)
+		case writeRequestMetadata:
+			forEachField(t, v, func(num protowire.Number, v []byte) {
+				if num == metadataFamilyName {
+					metadata = append(metadata, string(v))
+				}
+			})
+		}
+	})
+	return all, metadata
+}
+
+// forEachField calls fn with the number and contents of each length-delimited field.
+func forEachField(t *testing.T, b []byte, fn func(protowire.Number, []byte)) {
+	t.Helper()
+
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			t.Fatalf("Invalid tag: %v", protowire.ParseError(n))
+		}
+		b = b[n:]
+		if typ != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			b = b[n:]
+			continue
+		}
+		v, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			t.Fatalf("Invalid field %d: %v", num, protowire.ParseError(n))
+		}
+		fn(num, v)
+		b = b[n:]
+	}
+}
+
+// decodeSample decodes the value and timestamp of a Sample.
+func decodeSample(t *testing.T, b []byte) (float64, int64) {
+	t.Helper()
+
+	var value float64
+	var timestamp int64
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		b = b[n:]
+		switch {
+		case num == sampleValue && typ == protowire.Fixed64Type:
+			v, n := protowire.ConsumeFixed64(b)
+			value = math.Float64frombits(v)
+			b = b[n:]
+		case num == sampleTimestamp && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			timestamp = int64(v)
+			b = b[n:]
+		default:
+			t.Fatalf("Unexpected sample field %d", num)
+		}
+	}
+	return value, timestamp
+}
+
+// find returns the series with the given labels.
+func find(all []series, labels map[string]string) *series {
+	for i, s := range all {
+		match := len(s.labels) == len(labels)
+		for name, value := range labels {
+			match = match && s.labels[name] == value
+		}
+		if match {
+			return &all[i]
+		}
+	}
+	return nil
+}
+
+func TestEncodeWriteRequest(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "apt_upgrades_pending", Help: "Pending upgrades"}, []string{"origin", "instance"})
+	gauge.WithLabelValues("Debian", "").Set(3)
+	gauge.WithLabelValues("Debian-Security", "override").Set(1)
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "apt_duration_seconds", Help: "Duration", Buckets: []float64{0.5, 1}})
+	histogram.Observe(0.75)
+	registry.MustRegister(gauge, histogram)
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Failed to gather metrics: %v", err)
+	}
+
+	external := map[string]string{"job": "apt_exporter", "instance": "host1"}
+	all, metadata := decodeWriteRequest(t, encodeWriteRequest(families, external, 1700000000000))
+
+	tests := []struct {
+		labels map[string]string
+		value  float64
+	}{
+		// An empty metric label is dropped by the registry, so the external label applies
+		{map[string]string{"__name__": "apt_upgrades_pending", "origin": "Debian", "job": "apt_exporter", "instance": "host1"}, 3},
+		// External labels do not override labels of the metric
+		{map[string]string{"__name__": "apt_upgrades_pending", "origin": "Debian-Security", "job": "apt_exporter", "instance": "override"}, 1},
+		{map[string]string{"__name__": "apt_duration_seconds_bucket", "le": "0.5", "job": "apt_exporter", "instance": "host1"}, 0},
+		{map[string]string{"__name__": "apt_duration_seconds_bucket", "le": "1", "job": "apt_exporter", "instance": "host1"}, 1},
+		{map[string]string{"__name__": "apt_duration_seconds_bucket", "le": "+Inf", "job": "apt_exporter", "instance": "host1"}, 1},
+		{map[string]string{"__name__": "apt_duration_seconds_sum", "job": "apt_exporter", "instance": "host1"}, 0.75},
+		{map[string]string{"__name__": "apt_duration_seconds_count", "job": "apt_exporter", "instance": "host1"}, 1},
+	}
+
+	if len(all) != len(tests) {
+		t.Errorf("Expected %d series, got %d: %v", len(tests), len(all), all)
+	}
+	for _, tt := range tests {
+		s := find(all, tt.labels)
+		if s == nil {
+			t.Errorf("Series %v not found", tt.labels)
+			continue
+		}
+		if s.value != tt.value {
+			t.Errorf("Expected %v for %v, got %v", tt.value, tt.labels, s.value)
+		}
+		if s.timestamp != 1700000000000 {
+			t.Errorf("Expected timestamp 1700000000000 for %v, got %d", tt.labels, s.timestamp)
+		}
+	}
+
+	if len(metadata) != 2 {
+		t.Errorf("Expected metadata for 2 families, got %v", metadata)
+	}
+}
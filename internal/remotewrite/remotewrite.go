@@ -0,0 +1,272 @@
+// Package remotewrite sends the exporter's metrics to a Prometheus
+// remote-write receiver, such as Mimir or Thanos, for push-based deployments.
+package remotewrite
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/ncecere/apt-exporter/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Retry backoff bounds.
+const (
+	minBackoff = time.Second
+	maxBackoff = 30 * time.Second
+)
+
+// Sender encodes the gathered metrics after each collection and sends them
+// to the remote-write endpoint, retrying with backoff and buffering requests
+// on disk while the receiver is unreachable.
+type Sender struct {
+	cfg      config.RemoteWriteConfig
+	gatherer prometheus.Gatherer
+	client   *http.Client
+	buffer   *diskBuffer
+	logger   *slog.Logger
+	trigger  chan struct{}
+
+	// minBackoff is the delay before the first retry, doubled for each further retry
+	minBackoff time.Duration
+
+	succeeded prometheus.Counter
+	failed    prometheus.Counter
+	buffered  prometheus.Gauge
+}
+
+// New creates a Sender for the metrics of gatherer. Its own metrics are
+// named with prefix and returned by Collectors.
+func New(cfg config.RemoteWriteConfig, prefix string, gatherer prometheus.Gatherer, logger *slog.Logger) (*Sender, error) {
+	s := &Sender{
+		cfg:        cfg,
+		gatherer:   gatherer,
+		client:     &http.Client{Timeout: time.Duration(cfg.TimeoutSeconds) * time.Second},
+		logger:     logger,
+		trigger:    make(chan struct{}, 1),
+		minBackoff: minBackoff,
+		succeeded: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: prefix + "_remote_write_requests_succeeded_total",
+			Help: "Number of remote write requests delivered, including buffered ones",
+		}),
+		failed: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: prefix + "_remote_write_requests_failed_total",
+			Help: "Number of failed remote write attempts, including retries",
+		}),
+		buffered: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: prefix + "_remote_write_buffered_requests",
+			Help: "Number of remote write requests buffered on disk",
+		}),
+	}
+
+	if cfg.BufferDir != "" {
+		buffer, err := newDiskBuffer(cfg.BufferDir, cfg.BufferMaxRequests)
+		if err != nil {
+			return nil, err
+		}
+		s.buffer = buffer
+		if files, err := buffer.list(); err == nil {
+			s.buffered.Set(float64(len(files)))
+		}
+	}
+
+	return s, nil
+}
+
+// Collectors returns the Sender's own metrics.
+func (s *Sender) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{s.succeeded, s.failed, s.buffered}
+}
+
+// Trigger requests a send without blocking. Triggers that arrive while a
+// send is pending are coalesced.
+func (s *Sender) Trigger() {
+	select {
+	case s.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Run sends the metrics whenever triggered until the context is canceled.
+func (s *Sender) Run(ctx context.Context) {
+	for {
+		select {
+		case <-s.trigger:
+			s.Send(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Send gathers the metrics and sends them. Buffered requests are sent
+// first, so the receiver gets samples in order; while they cannot be
+// delivered, new requests are buffered behind them.
+func (s *Sender) Send(ctx context.Context) {
+	families, err := s.gatherer.Gather()
+	if err != nil {
+		s.logger.Error("Error gathering metrics for remote write", "error", err)
+		if len(families) == 0 {
+			return
+		}
+	}
+	data := s2.EncodeSnappy(nil, encodeWriteRequest(families, s.cfg.ExternalLabels, time.Now().UnixMilli()))
+
+	if s.buffer != nil && !s.flush(ctx) {
+		s.bufferRequest(data)
+		return
+	}
+
+	err = s.sendWithRetry(ctx, data)
+	if err == nil {
+		return
+	}
+	s.logger.Error("Error sending remote write request", "url", s.cfg.URL, "error", err)
+	var sendErr *sendError
+	if s.buffer != nil && errors.As(err, &sendErr) && sendErr.retryable {
+		s.bufferRequest(data)
+	}
+}
+
+// flush sends the buffered requests, oldest first, and reports whether the
+// buffer is now empty. Each request gets a single attempt per cycle.
+func (s *Sender) flush(ctx context.Context) bool {
+	files, err := s.buffer.list()
+	if err != nil {
+		s.logger.Error("Error reading remote write buffer", "path", s.cfg.BufferDir, "error", err)
+		return false
+	}
+	defer s.updateBuffered()
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			s.logger.Error("Error reading buffered remote write request", "path", file, "error", err)
+			return false
+		}
+
+		err = s.send(ctx, data)
+		var sendErr *sendError
+		if err != nil && errors.As(err, &sendErr) && sendErr.retryable {
+			return false
+		}
+		if err != nil {
+			// The receiver will never accept this request
+			s.logger.Warn("Dropping buffered remote write request", "path", file, "error", err)
+		}
+		if err := os.Remove(file); err != nil {
+			s.logger.Error("Error removing buffered remote write request", "path", file, "error", err)
+			return false
+		}
+	}
+
+	if len(files) > 0 {
+		s.logger.Info("Sent buffered remote write requests", "requests", len(files))
+	}
+	return true
+}
+
+// bufferRequest keeps a request on disk to be sent later.
+func (s *Sender) bufferRequest(data []byte) {
+	dropped, err := s.buffer.add(data)
+	if err != nil {
+		s.logger.Error("Error buffering remote write request", "path", s.cfg.BufferDir, "error", err)
+	}
+	if dropped > 0 {
+		s.logger.Warn("Remote write buffer is full, dropped oldest requests", "dropped", dropped)
+	}
+	s.updateBuffered()
+}
+
+// updateBuffered updates the number of buffered requests.
+func (s *Sender) updateBuffered() {
+	if files, err := s.buffer.list(); err == nil {
+		s.buffered.Set(float64(len(files)))
+	}
+}
+
+// sendWithRetry sends a request, retrying retryable failures with
+// exponential backoff up to max_retries times.
+func (s *Sender) sendWithRetry(ctx context.Context, data []byte) error {
+	backoff := s.minBackoff
+	for attempt := 0; ; attempt++ {
+		err := s.send(ctx, data)
+		var sendErr *sendError
+		if err == nil || attempt >= s.cfg.MaxRetries || !errors.As(err, &sendErr) || !sendErr.retryable {
+			return err
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return err
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// sendError is a failed remote write attempt.
+type sendError struct {
+	err       error
+	retryable bool
+}
+
+func (e *sendError) Error() string { return e.err.Error() }
+func (e *sendError) Unwrap() error { return e.err }
+
+// send makes a single remote write attempt. Network errors, server errors
+// and rate limiting are retryable; other client errors are not.
+func (s *Sender) send(ctx context.Context, data []byte) error {
+	err := s.post(ctx, data)
+	if err != nil {
+		s.failed.Inc()
+		return err
+	}
+	s.succeeded.Inc()
+	return nil
+}
+
+// post sends an encoded request to the endpoint.
+func (s *Sender) post(ctx context.Context, data []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.URL, bytes.NewReader(data))
+	if err != nil {
+		return &sendError{err: err}
+	}
+	for name, value := range s.cfg.Headers {
+		req.Header.Set(name, string(value))
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("User-Agent", "apt-exporter")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	if s.cfg.Username != "" {
+		req.SetBasicAuth(s.cfg.Username, string(s.cfg.Password))
+	} else if s.cfg.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+string(s.cfg.BearerToken))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return &sendError{err: fmt.Errorf("failed to send to %s: %w", s.cfg.URL, err), retryable: true}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 == 2 {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return &sendError{
+		err:       fmt.Errorf("%s returned %s: %s", s.cfg.URL, resp.Status, bytes.TrimSpace(body)),
+		retryable: resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests,
+	}
+}
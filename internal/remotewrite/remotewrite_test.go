@@ -0,0 +1,277 @@
+package remotewrite
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/ncecere/apt-exporter/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// receiver is a remote-write receiver stand-in that responds with the
+// configured status codes in turn and records the requests it accepts.
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newReceiver(t *testing.T, statuses ...int) (*receiver, *httptest.Server) {
+	t.Helper()
+
+	rc := &receiver{statuses: statuses}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rc.mu.Lock()
+		defer rc.mu.Unlock()
+
+		status := http.StatusNoContent
+		if len(rc.statuses) > 0 {
+			status, rc.statuses = rc.statuses[0], rc.statuses[1:]
+		}
+		if status/100 == 2 {
+			rc.requests = append(rc.requests, r)
+			rc.bodies = append(rc.bodies, body)
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(ts.Close)
+	return rc, ts
+}
+
+// accepted returns the number of requests accepted so far.
+func (rc *receiver) accepted() int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return len(rc.bodies)
+}
+
+// newTestSender creates a Sender with a gauge to send and no retry delay.
+func newTestSender(t *testing.T, cfg config.RemoteWriteConfig) (*Sender, prometheus.Gauge) {
+	t.Helper()
+
+	registry := prometheus.NewRegistry()
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "apt_upgrades_pending", Help: "Pending upgrades"})
+	registry.MustRegister(gauge)
+
+	if cfg.TimeoutSeconds == 0 {
+		cfg.TimeoutSeconds = 5
+	}
+	if cfg.BufferMaxRequests == 0 {
+		cfg.BufferMaxRequests = 10
+	}
+	s, err := New(cfg, "apt", registry, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatalf("Failed to create sender: %v", err)
+	}
+	s.minBackoff = time.Millisecond
+	return s, gauge
+}
+
+// testValue returns the value of a counter or gauge.
+func testValue(t *testing.T, c prometheus.Collector) float64 {
+	t.Helper()
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+	families, err := registry.Gather()
+	if err != nil || len(families) != 1 {
+		t.Fatalf("Failed to gather metric: %v", err)
+	}
+	m := families[0].GetMetric()[0]
+	if m.Counter != nil {
+		return m.GetCounter().GetValue()
+	}
+	return m.GetGauge().GetValue()
+}
+
+func TestSend(t *testing.T) {
+	rc, ts := newReceiver(t)
+	s, gauge := newTestSender(t, config.RemoteWriteConfig{
+		URL:            ts.URL,
+		Headers:        map[string]config.Secret{"X-Scope-OrgID": "tenant1"},
+		Username:       "user",
+		Password:       "pass",
+		ExternalLabels: map[string]string{"instance": "host1"},
+	})
+	gauge.Set(4)
+
+	s.Send(context.Background())
+
+	if rc.accepted() != 1 {
+		t.Fatalf("Expected 1 request, got %d", rc.accepted())
+	}
+	req := rc.requests[0]
+	for header, want := range map[string]string{
+		"Content-Encoding":                  "snappy",
+		"Content-Type":                      "application/x-protobuf",
+		"X-Prometheus-Remote-Write-Version": "0.1.0",
+		"X-Scope-OrgID":                     "tenant1",
+	} {
+		if got := req.Header.Get(header); got != want {
+			t.Errorf("Expected %s header %q, got %q", header, want, got)
+		}
+	}
+	if user, pass, _ := req.BasicAuth(); user != "user" || pass != "pass" {
+		t.Errorf("Expected basic auth user:pass, got %s:%s", user, pass)
+	}
+
+	body, err := s2.Decode(nil, rc.bodies[0])
+	if err != nil {
+		t.Fatalf("Failed to decode snappy body: %v", err)
+	}
+	all, _ := decodeWriteRequest(t, body)
+	series := find(all, map[string]string{"__name__": "apt_upgrades_pending", "instance": "host1"})
+	if series == nil || series.value != 4 {
+		t.Errorf("Expected apt_upgrades_pending 4 in %v", all)
+	}
+
+	if got := testValue(t, s.succeeded); got != 1 {
+		t.Errorf("Expected 1 succeeded request, got %v", got)
+	}
+}
+
+func TestSendBearerToken(t *testing.T) {
+	rc, ts := newReceiver(t)
+	s, _ := newTestSender(t, config.RemoteWriteConfig{URL: ts.URL, BearerToken: "token"})
+
+	s.Send(context.Background())
+
+	if rc.accepted() != 1 {
+		t.Fatalf("Expected 1 request, got %d", rc.accepted())
+	}
+	if got := rc.requests[0].Header.Get("Authorization"); got != "Bearer token" {
+		t.Errorf("Expected bearer token, got %q", got)
+	}
+}
+
+func TestSendRetries(t *testing.T) {
+	rc, ts := newReceiver(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	s, _ := newTestSender(t, config.RemoteWriteConfig{URL: ts.URL, MaxRetries: 3})
+
+	s.Send(context.Background())
+
+	if rc.accepted() != 1 {
+		t.Errorf("Expected the request to be accepted after retrying, got %d requests", rc.accepted())
+	}
+	if got := testValue(t, s.failed); got != 2 {
+		t.Errorf("Expected 2 failed attempts, got %v", got)
+	}
+	if got := testValue(t, s.succeeded); got != 1 {
+		t.Errorf("Expected 1 succeeded request, got %v", got)
+	}
+}
+
+func TestSendClientErrorNotRetried(t *testing.T) {
+	dir := t.TempDir()
+	rc, ts := newReceiver(t, http.StatusBadRequest)
+	s, _ := newTestSender(t, config.RemoteWriteConfig{URL: ts.URL, MaxRetries: 3, BufferDir: dir})
+
+	s.Send(context.Background())
+
+	if rc.accepted() != 0 {
+		t.Errorf("Expected no accepted requests, got %d", rc.accepted())
+	}
+	if got := testValue(t, s.failed); got != 1 {
+		t.Errorf("Expected 1 failed attempt, got %v", got)
+	}
+	// The receiver will never accept the request, so it is not buffered
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Expected an empty buffer, got %d files", len(entries))
+	}
+}
+
+func TestSendBuffersUntilReachable(t *testing.T) {
+	dir := t.TempDir()
+	rc, ts := newReceiver(t, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+	s, gauge := newTestSender(t, config.RemoteWriteConfig{URL: ts.URL, MaxRetries: 1, BufferDir: dir})
+
+	// Both attempts fail, so the request is buffered
+	gauge.Set(1)
+	s.Send(context.Background())
+	if got := testValue(t, s.buffered); got != 1 {
+		t.Fatalf("Expected 1 buffered request, got %v", got)
+	}
+
+	// The receiver is reachable again: the buffered request is sent first
+	gauge.Set(2)
+	s.Send(context.Background())
+
+	if rc.accepted() != 2 {
+		t.Fatalf("Expected 2 accepted requests, got %d", rc.accepted())
+	}
+	for i, want := range []float64{1, 2} {
+		body, err := s2.Decode(nil, rc.bodies[i])
+		if err != nil {
+			t.Fatalf("Failed to decode snappy body: %v", err)
+		}
+		all, _ := decodeWriteRequest(t, body)
+		series := find(all, map[string]string{"__name__": "apt_upgrades_pending"})
+		if series == nil || series.value != want {
+			t.Errorf("Expected request %d to have apt_upgrades_pending %v", i, want)
+		}
+	}
+	if got := testValue(t, s.buffered); got != 0 {
+		t.Errorf("Expected an empty buffer, got %v", got)
+	}
+}
+
+func TestBufferDropsOldest(t *testing.T) {
+	b, err := newDiskBuffer(t.TempDir(), 2)
+	if err != nil {
+		t.Fatalf("Failed to create buffer: %v", err)
+	}
+
+	for _, data := range []string{"first", "second", "third"} {
+		if _, err := b.add([]byte(data)); err != nil {
+			t.Fatalf("Failed to add to buffer: %v", err)
+		}
+	}
+
+	files, err := b.list()
+	if err != nil {
+		t.Fatalf("Failed to list buffer: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("Expected 2 buffered requests, got %d", len(files))
+	}
+	for i, want := range []string{"second", "third"} {
+		data, _ := os.ReadFile(files[i])
+		if string(data) != want {
+			t.Errorf("Expected buffered request %d to be %q, got %q", i, want, data)
+		}
+	}
+}
+
+func TestRunSendsOnTrigger(t *testing.T) {
+	rc, ts := newReceiver(t)
+	s, _ := newTestSender(t, config.RemoteWriteConfig{URL: ts.URL})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	s.Trigger()
+	deadline := time.Now().Add(5 * time.Second)
+	for rc.accepted() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if rc.accepted() != 1 {
+		t.Errorf("Expected 1 request after a trigger, got %d", rc.accepted())
+	}
+}
@@ -0,0 +1,75 @@
+// Package rootfs resolves paths within alternate root filesystems, such as
+// chroots, unpacked container images and mounted disks.
+package rootfs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxSymlinks bounds the number of symlinks followed while resolving a path.
+const maxSymlinks = 255
+
+// IsHost reports whether root refers to the host root filesystem.
+func IsHost(root string) bool {
+	return root == "" || filepath.Clean(root) == "/"
+}
+
+// Join returns the location of path within root.
+//
+// Symlinks are resolved as if root were the root directory, so an absolute
+// symlink such as /var/run -> /run within an image stays inside the image
+// instead of pointing to the host. Components that do not exist are appended
+// as they are. If root refers to the host, path is returned unchanged.
+func Join(root, path string) string {
+	if IsHost(root) || path == "" {
+		return path
+	}
+	root = filepath.Clean(root)
+
+	// current is the resolved prefix, always absolute and relative to root
+	current := "/"
+	remaining := splitPath(path)
+	links := 0
+	for len(remaining) > 0 {
+		name := remaining[0]
+		remaining = remaining[1:]
+
+		if name == ".." {
+			current = filepath.Dir(current)
+			continue
+		}
+
+		next := filepath.Join(current, name)
+		info, err := os.Lstat(filepath.Join(root, next))
+		if err != nil || info.Mode()&os.ModeSymlink == 0 || links >= maxSymlinks {
+			current = next
+			continue
+		}
+		links++
+
+		target, err := os.Readlink(filepath.Join(root, next))
+		if err != nil {
+			current = next
+			continue
+		}
+		if filepath.IsAbs(target) {
+			current = "/"
+		}
+		remaining = append(splitPath(target), remaining...)
+	}
+
+	return filepath.Join(root, current)
+}
+
+// splitPath splits a path into its components, dropping empty and "." ones.
+func splitPath(path string) []string {
+	var parts []string
+	for _, part := range strings.Split(filepath.ToSlash(path), "/") {
+		if part != "" && part != "." {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
@@ -0,0 +1,53 @@
+package rootfs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJoin(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"run", "etc", "usr/lib", "var"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", dir, err)
+		}
+	}
+	links := map[string]string{
+		"var/run":        "/run",                  // absolute, as on Debian and Ubuntu
+		"etc/os-release": "../usr/lib/os-release", // relative
+		"etc/escape":     "../../../../../../etc", // climbs above the root
+		"etc/loop":       "loop",
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, link)); err != nil {
+			t.Fatalf("Failed to create symlink %s: %v", link, err)
+		}
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/var/run/reboot-required", "/run/reboot-required"},
+		{"/etc/os-release", "/usr/lib/os-release"},
+		{"/etc/escape/passwd", "/etc/passwd"},
+		{"/var/lib/dpkg/status", "/var/lib/dpkg/status"},
+		{"/../../etc/hostname", "/etc/hostname"},
+		{"var/lib/apt/lists", "/var/lib/apt/lists"},
+		{"/etc/loop", "/etc/loop"},
+	}
+	for _, tt := range tests {
+		if got := Join(root, tt.path); got != filepath.Join(root, tt.want) {
+			t.Errorf("Join(%s) = %s, want %s", tt.path, got, filepath.Join(root, tt.want))
+		}
+	}
+}
+
+func TestJoinHost(t *testing.T) {
+	for _, root := range []string{"", "/", "//"} {
+		if got := Join(root, "/var/lib/dpkg/status"); got != "/var/lib/dpkg/status" {
+			t.Errorf("Join(%q) = %s, want the path unchanged", root, got)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+// Package rules generates Prometheus recording and alerting rules for the
+// metrics of the exporter.
+package rules
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ncecere/apt-exporter/internal/metrics"
+	"github.com/prometheus/common/model"
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats.
+const (
+	// FormatRules is a Prometheus rule file, as loaded by rule_files
+	FormatRules = "rules"
+	// FormatPrometheusRule is a PrometheusRule resource of the Prometheus Operator
+	FormatPrometheusRule = "prometheusrule"
+)
+
+// Defaults for the rule options.
+const (
+	DefaultSecurityThreshold = 0
+	DefaultStaleAfter        = 7 * 24 * time.Hour
+	DefaultRebootAfter       = 7 * 24 * time.Hour
+	DefaultName              = "apt-exporter"
+	DefaultJob               = "apt"
+)
+
+// Options configure the generated rules.
+type Options struct {
+	// Prefix and Naming select the metric names, as configured in the exporter
+	Prefix string
+	Naming metrics.Naming
+
+	// SecurityThreshold is the number of pending security updates tolerated
+	SecurityThreshold int
+	// StaleAfter is how old the package lists may get before alerting
+	StaleAfter time.Duration
+	// RebootAfter is how long a reboot may be required before alerting
+	RebootAfter time.Duration
+	// Job is the Prometheus job scraping the exporters, whose up series
+	// tell which exporters are down
+	Job string
+}
+
+// Group is a named group of rules evaluated together.
+type Group struct {
+	Name  string `yaml:"name"`
+	Rules []Rule `yaml:"rules"`
+}
+
+// Rule is a recording rule, with Record set, or an alerting rule, with Alert set.
+type Rule struct {
+	Record      string            `yaml:"record,omitempty"`
+	Alert       string            `yaml:"alert,omitempty"`
+	Expr        string            `yaml:"expr"`
+	For         string            `yaml:"for,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+// metricName returns the name a metric is exposed under. With both naming
+// schemes enabled, rules use the v2 name that dashboards migrate to.
+func metricName(metric any) string {
+	descs := metrics.DescsOf(metric)
+	if len(descs) == 0 {
+		return ""
+	}
+	return descs[len(descs)-1].Name
+}
+
+// duration formats d in the Prometheus duration format, e.g. "1w".
+func duration(d time.Duration) string {
+	return model.Duration(d).String()
+}
+
+// Generate returns the recording and alerting rules for the metrics named
+// as by opts. The metric names are taken from the metric definitions.
+func Generate(opts Options) []Group {
+	m := metrics.NewDescribedMetrics(opts.Prefix, opts.Naming)
+	updates := metricName(m.UpdatesAvailable)
+	security := metricName(m.SecurityUpdatesAvailable)
+	reboot := metricName(m.RebootRequired)
+	success := metricName(m.CollectionSuccess)
+	collectionErrors := metricName(m.CollectionErrors)
+	up := fmt.Sprintf("up{job=%q}", opts.Job)
+
+	// v2 names expose the time of the last update rather than its age
+	var stale string
+	if timestamp := metricName(m.LastUpdateTimestamp); timestamp != "" {
+		stale = fmt.Sprintf("%s > 0 and time() - %s > %d", timestamp, timestamp, int64(opts.StaleAfter.Seconds()))
+	} else {
+		stale = fmt.Sprintf("%s > %d", metricName(m.SecondsSinceLastUpdate), int64(opts.StaleAfter.Seconds()))
+	}
+
+	recording := Group{
+		Name: "apt-exporter.rules",
+		Rules: []Rule{
+			{Record: "job:" + updates + ":sum", Expr: fmt.Sprintf("sum by (job) (%s)", updates)},
+			{Record: "job:" + security + ":sum", Expr: fmt.Sprintf("sum by (job) (%s)", security)},
+			{Record: "job:" + reboot + ":sum", Expr: fmt.Sprintf("sum by (job) (%s)", reboot)},
+		},
+	}
+
+	alerting := Group{
+		Name: "apt-exporter.alerts",
+		Rules: []Rule{
+			{
+				Alert:  "AptSecurityUpdatesPending",
+				Expr:   fmt.Sprintf("%s > %d", security, opts.SecurityThreshold),
+				For:    "1h",
+				Labels: map[string]string{"severity": "warning"},
+				Annotations: map[string]string{
+					"summary":     "Security updates pending on {{ $labels.instance }}",
+					"description": fmt.Sprintf("{{ $value }} security updates are available on {{ $labels.instance }} (threshold %d).", opts.SecurityThreshold),
+				},
+			},
+			{
+				Alert:  "AptListsStale",
+				Expr:   stale,
+				For:    "1h",
+				Labels: map[string]string{"severity": "warning"},
+				Annotations: map[string]string{
+					"summary":     "Package lists are stale on {{ $labels.instance }}",
+					"description": fmt.Sprintf("apt update has not succeeded on {{ $labels.instance }} for more than %s.", duration(opts.StaleAfter)),
+				},
+			},
+			{
+				Alert:  "AptRebootRequired",
+				Expr:   reboot + " == 1",
+				For:    duration(opts.RebootAfter),
+				Labels: map[string]string{"severity": "info"},
+				Annotations: map[string]string{
+					"summary":     "Reboot required on {{ $labels.instance }}",
+					"description": fmt.Sprintf("{{ $labels.instance }} has required a reboot for more than %s.", duration(opts.RebootAfter)),
+				},
+			},
+			{
+				Alert:  "AptCollectorFailing",
+				Expr:   success + " == 0",
+				For:    "30m",
+				Labels: map[string]string{"severity": "warning"},
+				Annotations: map[string]string{
+					"summary":     "APT exporter collection failing on {{ $labels.instance }}",
+					"description": fmt.Sprintf("Collections have failed on {{ $labels.instance }} for 30 minutes; %s shows the failing checks.", collectionErrors),
+				},
+			},
+			{
+				Alert:  "AptExporterDown",
+				Expr:   up + " == 0",
+				For:    "15m",
+				Labels: map[string]string{"severity": "warning"},
+				Annotations: map[string]string{
+					"summary":     "APT exporter down on {{ $labels.instance }}",
+					"description": fmt.Sprintf("Prometheus job %s has failed to scrape {{ $labels.instance }} for 15 minutes.", opts.Job),
+				},
+			},
+			{
+				Alert:  "AptExporterAbsent",
+				Expr:   fmt.Sprintf("absent(%s)", up),
+				For:    "15m",
+				Labels: map[string]string{"severity": "warning"},
+				Annotations: map[string]string{
+					"summary":     "APT exporter job absent",
+					"description": fmt.Sprintf("Prometheus job %s has had no targets for 15 minutes.", opts.Job),
+				},
+			},
+		},
+	}
+
+	return []Group{recording, alerting}
+}
+
+// CheckFormat returns an error if Write does not support format.
+func CheckFormat(format string) error {
+	switch format {
+	case FormatRules, FormatPrometheusRule:
+		return nil
+	default:
+		return fmt.Errorf("unsupported rules format: %s (must be one of: rules, prometheusrule)", format)
+	}
+}
+
+// Write writes groups to w as YAML in format. FormatPrometheusRule wraps
+// them in a PrometheusRule resource called name.
+func Write(w io.Writer, groups []Group, format, name string) error {
+	if err := CheckFormat(format); err != nil {
+		return err
+	}
+
+	var doc any = struct {
+		Groups []Group `yaml:"groups"`
+	}{groups}
+	if format == FormatPrometheusRule {
+		doc = prometheusRule{
+			APIVersion: "monitoring.coreos.com/v1",
+			Kind:       "PrometheusRule",
+			Metadata:   objectMeta{Name: name},
+			Spec:       prometheusRuleSpec{groups},
+		}
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// prometheusRule is the PrometheusRule custom resource.
+type prometheusRule struct {
+	APIVersion string             `yaml:"apiVersion"`
+	Kind       string             `yaml:"kind"`
+	Metadata   objectMeta         `yaml:"metadata"`
+	Spec       prometheusRuleSpec `yaml:"spec"`
+}
+
+type objectMeta struct {
+	Name string `yaml:"name"`
+}
+
+type prometheusRuleSpec struct {
+	Groups []Group `yaml:"groups"`
+}
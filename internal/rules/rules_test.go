@@ -0,0 +1,118 @@
+package rules
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ncecere/apt-exporter/internal/metrics"
+	"gopkg.in/yaml.v3"
+)
+
+// byName indexes the rules of groups by record or alert name.
+func byName(groups []Group) map[string]Rule {
+	rules := make(map[string]Rule)
+	for _, g := range groups {
+		for _, r := range g.Rules {
+			rules[r.Record+r.Alert] = r
+		}
+	}
+	return rules
+}
+
+func TestGenerate(t *testing.T) {
+	opts := Options{
+		Prefix:            "debian",
+		Naming:            metrics.NamingV1,
+		SecurityThreshold: 5,
+		StaleAfter:        3 * 24 * time.Hour,
+		RebootAfter:       14 * 24 * time.Hour,
+		Job:               "node",
+	}
+	rules := byName(Generate(opts))
+
+	tests := []struct {
+		name, expr, duration string
+	}{
+		{"job:debian_security_updates_available:sum", "sum by (job) (debian_security_updates_available)", ""},
+		{"AptSecurityUpdatesPending", "debian_security_updates_available > 5", "1h"},
+		{"AptListsStale", "debian_seconds_since_last_update > 259200", "1h"},
+		{"AptRebootRequired", "debian_reboot_required == 1", "2w"},
+		{"AptCollectorFailing", "debian_collector_success == 0", "30m"},
+		{"AptExporterDown", `up{job="node"} == 0`, "15m"},
+		{"AptExporterAbsent", `absent(up{job="node"})`, "15m"},
+	}
+	for _, tt := range tests {
+		r, ok := rules[tt.name]
+		if !ok {
+			t.Errorf("Expected rule %s", tt.name)
+			continue
+		}
+		if r.Expr != tt.expr || r.For != tt.duration {
+			t.Errorf("Expected %s to be %q for %q, got %q for %q", tt.name, tt.expr, tt.duration, r.Expr, r.For)
+		}
+	}
+}
+
+func TestGenerateV2(t *testing.T) {
+	// Dual naming uses the v2 names dashboards migrate to
+	for _, naming := range []metrics.Naming{metrics.NamingV2, metrics.NamingDual} {
+		rules := byName(Generate(Options{Prefix: "ubuntu", Naming: naming, StaleAfter: DefaultStaleAfter, RebootAfter: DefaultRebootAfter}))
+
+		if got, want := rules["AptListsStale"].Expr, "apt_update_last_success_timestamp_seconds > 0 and time() - apt_update_last_success_timestamp_seconds > 604800"; got != want {
+			t.Errorf("%s: expected %q, got %q", naming, want, got)
+		}
+		if got := rules["AptRebootRequired"].Expr; got != "node_reboot_required == 1" {
+			t.Errorf("%s: expected node_reboot_required, got %q", naming, got)
+		}
+		if _, ok := rules["job:apt_upgrades_pending:sum"]; !ok {
+			t.Errorf("%s: expected the apt_upgrades_pending recording rule", naming)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	groups := Generate(Options{Prefix: "ubuntu", Naming: metrics.NamingV1, StaleAfter: DefaultStaleAfter, RebootAfter: DefaultRebootAfter})
+
+	var buf bytes.Buffer
+	if err := Write(&buf, groups, FormatRules, DefaultName); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	var file struct {
+		Groups []Group `yaml:"groups"`
+	}
+	if err := yaml.Unmarshal(buf.Bytes(), &file); err != nil {
+		t.Fatalf("Failed to parse rule file: %v", err)
+	}
+	if len(file.Groups) != 2 || file.Groups[1].Rules[0].Alert != "AptSecurityUpdatesPending" {
+		t.Errorf("Unexpected rule file %+v", file)
+	}
+
+	buf.Reset()
+	if err := Write(&buf, groups, FormatPrometheusRule, "apt"); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	var resource prometheusRule
+	if err := yaml.Unmarshal(buf.Bytes(), &resource); err != nil {
+		t.Fatalf("Failed to parse PrometheusRule: %v", err)
+	}
+	if resource.Kind != "PrometheusRule" || resource.Metadata.Name != "apt" || len(resource.Spec.Groups) != 2 {
+		t.Errorf("Unexpected PrometheusRule %+v", resource)
+	}
+
+	if err := Write(&buf, groups, "json", DefaultName); err == nil || !strings.Contains(err.Error(), "unsupported") {
+		t.Errorf("Expected an unsupported format error, got %v", err)
+	}
+}
+
+func TestCheckFormat(t *testing.T) {
+	for _, format := range []string{FormatRules, FormatPrometheusRule} {
+		if err := CheckFormat(format); err != nil {
+			t.Errorf("Expected format %s to be supported, got %v", format, err)
+		}
+	}
+	if err := CheckFormat("json"); err == nil {
+		t.Error("Expected an error for an unsupported format, got nil")
+	}
+}
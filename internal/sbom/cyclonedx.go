@@ -0,0 +1,108 @@
+package sbom
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// CycloneDX 1.5 document structure, limited to the fields used here.
+// See https://cyclonedx.org/docs/1.5/json/.
+type (
+	cdxDocument struct {
+		BOMFormat    string         `json:"bomFormat"`
+		SpecVersion  string         `json:"specVersion"`
+		SerialNumber string         `json:"serialNumber"`
+		Version      int            `json:"version"`
+		Metadata     cdxMetadata    `json:"metadata"`
+		Components   []cdxComponent `json:"components"`
+	}
+
+	cdxMetadata struct {
+		Timestamp string        `json:"timestamp"`
+		Tools     cdxTools      `json:"tools"`
+		Component *cdxComponent `json:"component,omitempty"`
+	}
+
+	cdxTools struct {
+		Components []cdxComponent `json:"components"`
+	}
+
+	cdxComponent struct {
+		BOMRef     string        `json:"bom-ref,omitempty"`
+		Type       string        `json:"type"`
+		Name       string        `json:"name"`
+		Version    string        `json:"version,omitempty"`
+		PURL       string        `json:"purl,omitempty"`
+		Licenses   []cdxLicense  `json:"licenses,omitempty"`
+		Properties []cdxProperty `json:"properties,omitempty"`
+	}
+
+	cdxLicense struct {
+		License struct {
+			ID   string `json:"id,omitempty"`
+			Name string `json:"name,omitempty"`
+		} `json:"license"`
+	}
+
+	cdxProperty struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+)
+
+// WriteCycloneDX encodes the SBOM as a CycloneDX 1.5 JSON document.
+func (s *SBOM) WriteCycloneDX(w io.Writer) error {
+	doc := cdxDocument{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: "urn:uuid:" + s.opts.Serial,
+		Version:      1,
+		Metadata: cdxMetadata{
+			Timestamp: s.opts.Timestamp.UTC().Format(time.RFC3339),
+			Tools: cdxTools{Components: []cdxComponent{{
+				Type:    "application",
+				Name:    "apt-exporter",
+				Version: s.opts.ToolVersion,
+			}}},
+		},
+		Components: make([]cdxComponent, 0, len(s.components)),
+	}
+	if id := s.opts.OS.ID(); id != "" {
+		doc.Metadata.Component = &cdxComponent{
+			Type:    "operating-system",
+			Name:    id,
+			Version: s.opts.OS.VersionID(),
+		}
+	}
+
+	for _, c := range s.components {
+		component := cdxComponent{
+			BOMRef:  c.PURL,
+			Type:    "library",
+			Name:    c.Name,
+			Version: c.Version,
+			PURL:    c.PURL,
+			Properties: []cdxProperty{
+				{Name: "apt-exporter:package:architecture", Value: c.Architecture},
+				{Name: "apt-exporter:package:source", Value: c.Source},
+				{Name: "apt-exporter:package:sourceVersion", Value: c.SourceVersion},
+			},
+		}
+		for _, name := range c.Licenses {
+			var l cdxLicense
+			if id, ok := SPDXLicenseID(name); ok {
+				l.License.ID = id
+			} else {
+				l.License.Name = name
+			}
+			component.Licenses = append(component.Licenses, l)
+		}
+		doc.Components = append(doc.Components, component)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(doc)
+}
@@ -0,0 +1,115 @@
+package sbom
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ncecere/apt-exporter/internal/dpkg"
+)
+
+// spdxLicenses maps common Debian license short names to SPDX identifiers.
+var spdxLicenses = map[string]string{
+	"apache-2.0":       "Apache-2.0",
+	"artistic":         "Artistic-1.0-Perl",
+	"artistic-2.0":     "Artistic-2.0",
+	"bsd-2-clause":     "BSD-2-Clause",
+	"bsd-3-clause":     "BSD-3-Clause",
+	"bsd-4-clause":     "BSD-4-Clause",
+	"cc0-1.0":          "CC0-1.0",
+	"expat":            "MIT",
+	"gfdl-1.2+":        "GFDL-1.2-or-later",
+	"gfdl-1.3+":        "GFDL-1.3-or-later",
+	"gpl-1+":           "GPL-1.0-or-later",
+	"gpl-2":            "GPL-2.0-only",
+	"gpl-2+":           "GPL-2.0-or-later",
+	"gpl-3":            "GPL-3.0-only",
+	"gpl-3+":           "GPL-3.0-or-later",
+	"isc":              "ISC",
+	"lgpl-2":           "LGPL-2.0-only",
+	"lgpl-2+":          "LGPL-2.0-or-later",
+	"lgpl-2.1":         "LGPL-2.1-only",
+	"lgpl-2.1+":        "LGPL-2.1-or-later",
+	"lgpl-3":           "LGPL-3.0-only",
+	"lgpl-3+":          "LGPL-3.0-or-later",
+	"mit":              "MIT",
+	"mpl-1.1":          "MPL-1.1",
+	"mpl-2.0":          "MPL-2.0",
+	"openssl":          "OpenSSL",
+	"python-2.0":       "Python-2.0",
+	"zlib":             "Zlib",
+	"zlib/libpng":      "Zlib",
+	"bsl-1.0":          "BSL-1.0",
+	"curl":             "curl",
+	"unicode-dfs-2016": "Unicode-DFS-2016",
+}
+
+// ReadLicenses returns the license short names declared in the
+// machine-readable copyright file of a package, e.g.
+// /usr/share/doc/bash/copyright. Packages without such a file, or whose file
+// does not follow the machine-readable format, have no licenses.
+// See https://www.debian.org/doc/packaging-manuals/copyright-format/1.0/.
+func ReadLicenses(docDir, pkg string) []string {
+	f, err := os.Open(filepath.Join(docDir, pkg, "copyright"))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	seen := make(map[string]bool)
+	first := true
+	machineReadable := false
+	_ = dpkg.ReadParagraphs(f, func(p dpkg.Paragraph) error {
+		// The header paragraph identifies the format
+		if first {
+			first = false
+			machineReadable = strings.Contains(p["Format"], "copyright-format")
+		}
+		if !machineReadable {
+			return errNotMachineReadable
+		}
+
+		// Only Files paragraphs and the header declare licenses that apply;
+		// standalone License paragraphs merely contain the license texts
+		if _, ok := p["Files"]; !ok && p["Format"] == "" {
+			return nil
+		}
+		name, _, _ := strings.Cut(p["License"], "\n")
+		if name = strings.TrimSpace(name); name != "" {
+			seen[name] = true
+		}
+		return nil
+	})
+	if !machineReadable {
+		return nil
+	}
+
+	licenses := make([]string, 0, len(seen))
+	for name := range seen {
+		licenses = append(licenses, name)
+	}
+	sort.Strings(licenses)
+	return licenses
+}
+
+// errNotMachineReadable stops parsing copyright files in free-form format.
+var errNotMachineReadable = errors.New("copyright file is not machine-readable")
+
+// SPDXLicenseID returns the SPDX identifier of a Debian license short name,
+// and whether it is a known SPDX license.
+func SPDXLicenseID(name string) (string, bool) {
+	id, ok := spdxLicenses[strings.ToLower(name)]
+	return id, ok
+}
+
+// spdxLicenseRef returns the license as an SPDX identifier, falling back to a
+// LicenseRef for names without an SPDX equivalent.
+func spdxLicenseRef(name string) string {
+	if id, ok := SPDXLicenseID(name); ok {
+		return id
+	}
+
+	return "LicenseRef-" + spdxIDPart(name)
+}
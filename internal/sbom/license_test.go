@@ -0,0 +1,69 @@
+package sbom
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadLicenses(t *testing.T) {
+	docDir := t.TempDir()
+	files := map[string]string{
+		"bash/copyright": `Format: https://www.debian.org/doc/packaging-manuals/copyright-format/1.0/
+Upstream-Name: bash
+Source: https://ftp.gnu.org/gnu/bash/
+
+Files: *
+Copyright: 1987-2022 Free Software Foundation, Inc.
+License: GPL-3+
+
+Files: examples/loadables/*
+Copyright: 2002 Someone
+License: BSD-3-clause or Expat
+
+License: GPL-3+
+ This program is free software; you can redistribute it and/or modify
+ it under the terms of the GNU General Public License.
+`,
+		"legacy/copyright": `This package was debianized by someone.
+
+It is licensed under the GPL, see /usr/share/common-licenses/GPL.
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(docDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create doc directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	if got, want := ReadLicenses(docDir, "bash"), []string{"BSD-3-clause or Expat", "GPL-3+"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadLicenses(bash) = %v, want %v", got, want)
+	}
+	if got := ReadLicenses(docDir, "legacy"); got != nil {
+		t.Errorf("Expected no licenses for free-form copyright file, got %v", got)
+	}
+	if got := ReadLicenses(docDir, "missing"); got != nil {
+		t.Errorf("Expected no licenses for missing copyright file, got %v", got)
+	}
+}
+
+func TestSPDXLicenseRef(t *testing.T) {
+	tests := map[string]string{
+		"GPL-2+":                "GPL-2.0-or-later",
+		"Expat":                 "MIT",
+		"BSD-3-clause":          "BSD-3-Clause",
+		"public-domain":         "LicenseRef-public-domain",
+		"BSD-3-clause or Expat": "LicenseRef-BSD-3-clause-or-Expat",
+	}
+
+	for name, want := range tests {
+		if got := spdxLicenseRef(name); got != want {
+			t.Errorf("spdxLicenseRef(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+// Package sbom produces software bills of materials for the packages
+// recorded in the dpkg status database, in CycloneDX and SPDX JSON formats.
+package sbom
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ncecere/apt-exporter/internal/dpkg"
+	"github.com/ncecere/apt-exporter/internal/osrelease"
+)
+
+// Supported output formats.
+const (
+	FormatCycloneDX = "cyclonedx"
+	FormatSPDX      = "spdx"
+)
+
+// DefaultDocDir is where packages install their copyright files.
+const DefaultDocDir = "/usr/share/doc"
+
+// Options describe the system an SBOM is generated for.
+type Options struct {
+	// OS identifies the distribution, used for package URLs and metadata
+	OS osrelease.Info
+	// DocDir is searched for machine-readable copyright files; skipped if empty
+	DocDir string
+	// ToolVersion is the version of apt-exporter recorded as the generating tool
+	ToolVersion string
+	// Timestamp defaults to the current time
+	Timestamp time.Time
+	// Serial is a UUID identifying the document; generated if empty
+	Serial string
+}
+
+// Component is an installed package as described in an SBOM.
+type Component struct {
+	dpkg.Package
+	PURL     string
+	Licenses []string
+}
+
+// SBOM is a bill of materials of installed packages.
+type SBOM struct {
+	opts       Options
+	components []Component
+}
+
+// New builds an SBOM from the installed packages.
+func New(packages []dpkg.Package, opts Options) (*SBOM, error) {
+	if opts.Timestamp.IsZero() {
+		opts.Timestamp = time.Now()
+	}
+	if opts.Serial == "" {
+		serial, err := newUUID()
+		if err != nil {
+			return nil, err
+		}
+		opts.Serial = serial
+	}
+
+	s := &SBOM{opts: opts}
+	for _, pkg := range packages {
+		c := Component{
+			Package: pkg,
+			PURL:    PackageURL(pkg, opts.OS),
+		}
+		if opts.DocDir != "" {
+			c.Licenses = ReadLicenses(opts.DocDir, pkg.Name)
+		}
+		s.components = append(s.components, c)
+	}
+
+	sort.Slice(s.components, func(i, j int) bool {
+		if s.components[i].Name != s.components[j].Name {
+			return s.components[i].Name < s.components[j].Name
+		}
+		return s.components[i].Architecture < s.components[j].Architecture
+	})
+	return s, nil
+}
+
+// FromSystem builds an SBOM of the packages recorded in a dpkg status file.
+// A missing os-release file is tolerated, resulting in generic package URLs.
+func FromSystem(statusPath, osReleasePath, docDir, toolVersion string) (*SBOM, error) {
+	packages, err := dpkg.ReadStatus(statusPath)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := osrelease.Read(osReleasePath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	return New(packages, Options{OS: info, DocDir: docDir, ToolVersion: toolVersion})
+}
+
+// Components returns the packages in the SBOM, sorted by name.
+func (s *SBOM) Components() []Component {
+	return s.components
+}
+
+// Write encodes the SBOM in the given format.
+func (s *SBOM) Write(w io.Writer, format string) error {
+	switch format {
+	case FormatCycloneDX:
+		return s.WriteCycloneDX(w)
+	case FormatSPDX:
+		return s.WriteSPDX(w)
+	default:
+		return fmt.Errorf("unsupported SBOM format: %s (must be one of: %s, %s)", format, FormatCycloneDX, FormatSPDX)
+	}
+}
+
+// ContentType returns the media type of an SBOM format.
+func ContentType(format string) string {
+	switch format {
+	case FormatCycloneDX:
+		return "application/vnd.cyclonedx+json; version=1.5"
+	case FormatSPDX:
+		return "application/spdx+json"
+	default:
+		return "application/json"
+	}
+}
+
+// PackageURL returns the package URL of an installed package, e.g.
+// pkg:deb/ubuntu/libssl3@3.0.2-0ubuntu1.10?arch=amd64&distro=ubuntu-22.04&upstream=openssl.
+// See https://github.com/package-url/purl-spec.
+func PackageURL(pkg dpkg.Package, os osrelease.Info) string {
+	namespace := os.ID()
+	if namespace == "" {
+		namespace = "debian"
+	}
+
+	qualifiers := map[string]string{}
+	if pkg.Architecture != "" {
+		qualifiers["arch"] = pkg.Architecture
+	}
+	if os.ID() != "" && os.VersionID() != "" {
+		qualifiers["distro"] = os.ID() + "-" + os.VersionID()
+	}
+	if pkg.Source != "" && pkg.Source != pkg.Name {
+		upstream := pkg.Source
+		if pkg.SourceVersion != "" && pkg.SourceVersion != pkg.Version {
+			upstream += "@" + pkg.SourceVersion
+		}
+		qualifiers["upstream"] = upstream
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "pkg:deb/%s/%s@%s", purlEscape(namespace), purlEscape(pkg.Name), purlEscape(pkg.Version))
+
+	keys := make([]string, 0, len(qualifiers))
+	for k := range qualifiers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for i, k := range keys {
+		sep := "&"
+		if i == 0 {
+			sep = "?"
+		}
+		fmt.Fprintf(&b, "%s%s=%s", sep, k, purlEscape(qualifiers[k]))
+	}
+
+	return b.String()
+}
+
+// purlEscape percent-encodes everything but unreserved characters.
+func purlEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if isLetter(c) || (c >= '0' && c <= '9') || c == '.' || c == '-' || c == '_' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() (string, error) {
+	var u [16]byte
+	if _, err := rand.Read(u[:]); err != nil {
+		return "", fmt.Errorf("failed to generate UUID: %w", err)
+	}
+	u[6] = (u[6] & 0x0f) | 0x40
+	u[8] = (u[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16]), nil
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
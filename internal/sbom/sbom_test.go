@@ -0,0 +1,140 @@
+package sbom
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ncecere/apt-exporter/internal/dpkg"
+	"github.com/ncecere/apt-exporter/internal/osrelease"
+)
+
+var testOS = osrelease.Info{"ID": "ubuntu", "VERSION_ID": "22.04", "PRETTY_NAME": "Ubuntu 22.04.3 LTS"}
+
+var testPackages = []dpkg.Package{
+	{Name: "libssl3", Version: "3.0.2-0ubuntu1.10", Architecture: "amd64", Source: "openssl", SourceVersion: "3.0.2-0ubuntu1.10"},
+	{Name: "bash", Version: "5.1-6ubuntu1", Architecture: "amd64", Source: "bash", SourceVersion: "5.1-6ubuntu1"},
+	{Name: "libstdc++6", Version: "1:12.3.0-1ubuntu1~22.04", Architecture: "amd64", Source: "gcc-12", SourceVersion: "12.3.0-1ubuntu1~22.04"},
+}
+
+func TestPackageURL(t *testing.T) {
+	tests := []struct {
+		pkg  dpkg.Package
+		os   osrelease.Info
+		want string
+	}{
+		{testPackages[0], testOS, "pkg:deb/ubuntu/libssl3@3.0.2-0ubuntu1.10?arch=amd64&distro=ubuntu-22.04&upstream=openssl"},
+		{testPackages[1], testOS, "pkg:deb/ubuntu/bash@5.1-6ubuntu1?arch=amd64&distro=ubuntu-22.04"},
+		{testPackages[2], testOS, "pkg:deb/ubuntu/libstdc%2B%2B6@1%3A12.3.0-1ubuntu1~22.04?arch=amd64&distro=ubuntu-22.04&upstream=gcc-12%4012.3.0-1ubuntu1~22.04"},
+		{testPackages[1], nil, "pkg:deb/debian/bash@5.1-6ubuntu1?arch=amd64"},
+	}
+
+	for _, tt := range tests {
+		if got := PackageURL(tt.pkg, tt.os); got != tt.want {
+			t.Errorf("PackageURL(%s) = %q, want %q", tt.pkg.Name, got, tt.want)
+		}
+	}
+}
+
+// newTestSBOM creates an SBOM of the test packages with a license for bash.
+func newTestSBOM(t *testing.T) *SBOM {
+	t.Helper()
+
+	docDir := t.TempDir()
+	copyright := "Format: https://www.debian.org/doc/packaging-manuals/copyright-format/1.0/\n\nFiles: *\nLicense: GPL-3+\n"
+	if err := os.MkdirAll(filepath.Join(docDir, "bash"), 0755); err != nil {
+		t.Fatalf("Failed to create doc directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(docDir, "bash", "copyright"), []byte(copyright), 0644); err != nil {
+		t.Fatalf("Failed to write copyright file: %v", err)
+	}
+
+	s, err := New(testPackages, Options{
+		OS:          testOS,
+		DocDir:      docDir,
+		ToolVersion: "1.2.3",
+		Timestamp:   time.Date(2025, 3, 2, 12, 0, 0, 0, time.UTC),
+		Serial:      "00000000-0000-4000-8000-000000000000",
+	})
+	if err != nil {
+		t.Fatalf("New() returned error: %v", err)
+	}
+	return s
+}
+
+func TestWriteCycloneDX(t *testing.T) {
+	var buf bytes.Buffer
+	if err := newTestSBOM(t).Write(&buf, FormatCycloneDX); err != nil {
+		t.Fatalf("Write() returned error: %v", err)
+	}
+
+	var doc cdxDocument
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Failed to decode CycloneDX document: %v", err)
+	}
+
+	if doc.BOMFormat != "CycloneDX" || doc.SpecVersion != "1.5" {
+		t.Errorf("Unexpected document header: %s %s", doc.BOMFormat, doc.SpecVersion)
+	}
+	if doc.SerialNumber != "urn:uuid:00000000-0000-4000-8000-000000000000" {
+		t.Errorf("Unexpected serial number %q", doc.SerialNumber)
+	}
+	if doc.Metadata.Component == nil || doc.Metadata.Component.Name != "ubuntu" {
+		t.Errorf("Expected operating system component, got %+v", doc.Metadata.Component)
+	}
+	if len(doc.Components) != 3 {
+		t.Fatalf("Expected 3 components, got %d", len(doc.Components))
+	}
+
+	// Components are sorted by name
+	bash := doc.Components[0]
+	if bash.Name != "bash" || bash.PURL != "pkg:deb/ubuntu/bash@5.1-6ubuntu1?arch=amd64&distro=ubuntu-22.04" {
+		t.Errorf("Unexpected first component %+v", bash)
+	}
+	if len(bash.Licenses) != 1 || bash.Licenses[0].License.ID != "GPL-3.0-or-later" {
+		t.Errorf("Expected bash to be licensed GPL-3.0-or-later, got %+v", bash.Licenses)
+	}
+	if len(doc.Components[1].Licenses) != 0 {
+		t.Errorf("Expected no licenses for %s, got %+v", doc.Components[1].Name, doc.Components[1].Licenses)
+	}
+}
+
+func TestWriteSPDX(t *testing.T) {
+	var buf bytes.Buffer
+	if err := newTestSBOM(t).Write(&buf, FormatSPDX); err != nil {
+		t.Fatalf("Write() returned error: %v", err)
+	}
+
+	var doc spdxDocument
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("Failed to decode SPDX document: %v", err)
+	}
+
+	if doc.SPDXVersion != "SPDX-2.3" || doc.Name != "Ubuntu 22.04.3 LTS" {
+		t.Errorf("Unexpected document header: %s %s", doc.SPDXVersion, doc.Name)
+	}
+	if len(doc.Packages) != 3 || len(doc.Relationships) != 3 {
+		t.Fatalf("Expected 3 packages and relationships, got %d and %d", len(doc.Packages), len(doc.Relationships))
+	}
+
+	stdcxx := doc.Packages[2]
+	if stdcxx.SPDXID != "SPDXRef-Package-3-libstdc--6" {
+		t.Errorf("Unexpected SPDX ID %q", stdcxx.SPDXID)
+	}
+	if stdcxx.LicenseDeclared != "NOASSERTION" {
+		t.Errorf("Expected no license assertion for libstdc++6, got %q", stdcxx.LicenseDeclared)
+	}
+	if stdcxx.SourceInfo != "built package from: gcc-12 12.3.0-1ubuntu1~22.04" {
+		t.Errorf("Unexpected source info %q", stdcxx.SourceInfo)
+	}
+	if doc.Packages[0].LicenseDeclared != "GPL-3.0-or-later" {
+		t.Errorf("Expected bash to declare GPL-3.0-or-later, got %q", doc.Packages[0].LicenseDeclared)
+	}
+
+	if err := newTestSBOM(t).Write(&buf, "xml"); err == nil {
+		t.Error("Expected error for unsupported format, got nil")
+	}
+}
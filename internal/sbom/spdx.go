@@ -0,0 +1,139 @@
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// SPDX 2.3 document structure, limited to the fields used here.
+// See https://spdx.github.io/spdx-spec/v2.3/.
+type (
+	spdxDocument struct {
+		SPDXVersion       string             `json:"spdxVersion"`
+		DataLicense       string             `json:"dataLicense"`
+		SPDXID            string             `json:"SPDXID"`
+		Name              string             `json:"name"`
+		DocumentNamespace string             `json:"documentNamespace"`
+		CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+		Packages          []spdxPackage      `json:"packages"`
+		Relationships     []spdxRelationship `json:"relationships"`
+	}
+
+	spdxCreationInfo struct {
+		Created  string   `json:"created"`
+		Creators []string `json:"creators"`
+	}
+
+	spdxPackage struct {
+		Name             string            `json:"name"`
+		SPDXID           string            `json:"SPDXID"`
+		VersionInfo      string            `json:"versionInfo"`
+		Supplier         string            `json:"supplier"`
+		DownloadLocation string            `json:"downloadLocation"`
+		FilesAnalyzed    bool              `json:"filesAnalyzed"`
+		LicenseConcluded string            `json:"licenseConcluded"`
+		LicenseDeclared  string            `json:"licenseDeclared"`
+		CopyrightText    string            `json:"copyrightText"`
+		SourceInfo       string            `json:"sourceInfo,omitempty"`
+		ExternalRefs     []spdxExternalRef `json:"externalRefs"`
+	}
+
+	spdxExternalRef struct {
+		ReferenceCategory string `json:"referenceCategory"`
+		ReferenceType     string `json:"referenceType"`
+		ReferenceLocator  string `json:"referenceLocator"`
+	}
+
+	spdxRelationship struct {
+		SPDXElementID      string `json:"spdxElementId"`
+		RelationshipType   string `json:"relationshipType"`
+		RelatedSPDXElement string `json:"relatedSpdxElement"`
+	}
+)
+
+// noAssertion marks SPDX fields whose value is not known.
+const noAssertion = "NOASSERTION"
+
+// WriteSPDX encodes the SBOM as an SPDX 2.3 JSON document.
+func (s *SBOM) WriteSPDX(w io.Writer) error {
+	name := "installed-packages"
+	if pretty := s.opts.OS["PRETTY_NAME"]; pretty != "" {
+		name = pretty
+	}
+
+	doc := spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              name,
+		DocumentNamespace: "https://github.com/ncecere/apt-exporter/sbom/" + s.opts.Serial,
+		CreationInfo: spdxCreationInfo{
+			Created:  s.opts.Timestamp.UTC().Format(time.RFC3339),
+			Creators: []string{"Tool: apt-exporter-" + s.opts.ToolVersion},
+		},
+		Packages:      make([]spdxPackage, 0, len(s.components)),
+		Relationships: make([]spdxRelationship, 0, len(s.components)),
+	}
+
+	for i, c := range s.components {
+		id := fmt.Sprintf("SPDXRef-Package-%d-%s", i+1, spdxIDPart(c.Name))
+
+		license := noAssertion
+		if len(c.Licenses) > 0 {
+			refs := make([]string, len(c.Licenses))
+			for j, l := range c.Licenses {
+				refs[j] = spdxLicenseRef(l)
+			}
+			license = strings.Join(refs, " AND ")
+		}
+
+		pkg := spdxPackage{
+			Name:             c.Name,
+			SPDXID:           id,
+			VersionInfo:      c.Version,
+			Supplier:         noAssertion,
+			DownloadLocation: noAssertion,
+			FilesAnalyzed:    false,
+			LicenseConcluded: noAssertion,
+			LicenseDeclared:  license,
+			CopyrightText:    noAssertion,
+			ExternalRefs: []spdxExternalRef{{
+				ReferenceCategory: "PACKAGE-MANAGER",
+				ReferenceType:     "purl",
+				ReferenceLocator:  c.PURL,
+			}},
+		}
+		if c.Source != "" {
+			pkg.SourceInfo = fmt.Sprintf("built package from: %s %s", c.Source, c.SourceVersion)
+		}
+
+		doc.Packages = append(doc.Packages, pkg)
+		doc.Relationships = append(doc.Relationships, spdxRelationship{
+			SPDXElementID:      "SPDXRef-DOCUMENT",
+			RelationshipType:   "DESCRIBES",
+			RelatedSPDXElement: id,
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(doc)
+}
+
+// spdxIDPart replaces characters that are not allowed in SPDX identifiers.
+func spdxIDPart(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if isLetter(c) || (c >= '0' && c <= '9') || c == '.' || c == '-' {
+			b.WriteByte(c)
+		} else {
+			b.WriteByte('-')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+
+	"github.com/ncecere/apt-exporter/internal/collector"
+	"gopkg.in/yaml.v3"
+)
+
+// BuildInfo describes the build of the running exporter.
+type BuildInfo struct {
+	Version string
+	Commit  string
+	Date    string
+}
+
+// landingTemplate renders the exporter's landing page.
+var landingTemplate = template.Must(template.New("landing").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<title>APT Exporter</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; vertical-align: top; }
+.ok { color: #2e7d32; }
+.failed { color: #c62828; }
+pre { background: #f5f5f5; padding: 1em; }
+</style>
+</head>
+<body>
+<h1>APT Exporter</h1>
+<p>Version {{.Build.Version}} (commit {{.Build.Commit}}, built at {{.Build.Date}})</p>
+<ul>
+{{if .MetricsEndpoint}}<li><a href="{{.MetricsEndpoint}}">Metrics</a></li>{{end}}
+<li><a href="/api/v1/status">Status (JSON)</a></li>
+<li><a href="/-/healthy">Health</a></li>
+<li><a href="/-/ready">Readiness</a></li>
+</ul>
+<h2>Last Collection</h2>
+{{if .Status}}
+<p>Started at {{.Status.Timestamp.Format "2006-01-02 15:04:05 MST"}}, took {{printf "%.3f" .Status.DurationSeconds}}s,
+{{if .Status.Success}}<span class="ok">successful</span>{{else}}<span class="failed">failed</span>{{end}}.</p>
+<table>
+<tr><th>Check</th><th>Result</th><th>Duration</th><th>Values</th><th>Error</th></tr>
+{{range .Status.Checks}}
+<tr>
+<td>{{.Name}}</td>
+<td>{{if .Unsupported}}unsupported{{else if .Success}}<span class="ok">ok</span>{{else}}<span class="failed">failed</span>{{end}}</td>
+<td>{{printf "%.3f" .DurationSeconds}}s</td>
+<td>{{range $name, $value := .Values}}{{$name}}: {{$value}}<br>{{end}}</td>
+<td>{{.Error}}</td>
+</tr>
+{{end}}
+</table>
+{{else}}
+<p>No collection has completed yet.</p>
+{{end}}
+<h2>Configuration</h2>
+<pre>{{.Config}}</pre>
+</body>
+</html>
+`))
+
+// handleLanding renders an overview of the exporter's build, configuration and last collection.
+func (s *Server) handleLanding(w http.ResponseWriter, r *http.Request) {
+	cfg, err := yaml.Marshal(s.cfg)
+	if err != nil {
+		http.Error(w, "failed to render configuration: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Build           BuildInfo
+		MetricsEndpoint string
+		Status          *collector.Status
+		Config          string
+	}{
+		Build:  s.build,
+		Config: string(cfg),
+	}
+	if s.cfg.PrometheusEnabled() {
+		data.MetricsEndpoint = s.cfg.MetricsEndpoint
+	}
+	if status, ok := s.collector.LastStatus(); ok {
+		data.Status = &status
+	}
+
+	// Render to a buffer first so template errors result in a proper error response
+	var buf bytes.Buffer
+	if err := landingTemplate.Execute(&buf, data); err != nil {
+		http.Error(w, "failed to render landing page: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = buf.WriteTo(w)
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// getBody performs a GET request and returns the status code and body.
+func getBody(t *testing.T, url string) (int, string) {
+	t.Helper()
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("Failed to get %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read body of %s: %v", url, err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+func TestLandingPage(t *testing.T) {
+	ts, _ := newTestServer(t)
+
+	code, body := getBody(t, ts.URL+"/")
+	if code != http.StatusOK {
+		t.Fatalf("Expected landing page to return 200, got %d", code)
+	}
+	for _, want := range []string{"1.2.3", "abc123", "2025-03-02", `href="/metrics"`, "metrics_endpoint: /metrics", "No collection has completed yet"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected landing page to contain %q", want)
+		}
+	}
+
+	resp, err := http.Post(ts.URL+"/-/collect", "", nil)
+	if err != nil {
+		t.Fatalf("Failed to trigger collection: %v", err)
+	}
+	resp.Body.Close()
+
+	_, body = getBody(t, ts.URL+"/")
+	for _, want := range []string{"successful", "<td>updates</td>", "updates_available: 0"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected landing page to contain %q after collection", want)
+		}
+	}
+
+	// Only the root path serves the landing page
+	if code := get(t, ts.URL+"/unknown"); code != http.StatusNotFound {
+		t.Errorf("Expected unknown path to return 404, got %d", code)
+	}
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/ncecere/apt-exporter/internal/collector"
+	"github.com/ncecere/apt-exporter/internal/config"
+	"github.com/ncecere/apt-exporter/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// probeTarget collects the metrics of a single target on demand.
+// The collector is kept across probes so its caches and state persist.
+type probeTarget struct {
+	// mu ensures each probe serves the metrics of its own collection
+	mu        sync.Mutex
+	collector *collector.Collector
+	handler   http.Handler
+}
+
+// newProbeTarget creates a collector for target, using the main configuration
+// with the target's root filesystem. Persistent state is kept per target.
+func newProbeTarget(cfg *config.Config, target config.Target, build BuildInfo, logger *slog.Logger) *probeTarget {
+	targetCfg := *cfg
+	targetCfg.RootDir = target.RootDir
+	targetCfg.Targets = nil
+	if cfg.StateDir != "" {
+		targetCfg.StateDir = filepath.Join(cfg.StateDir, "targets", target.Name)
+	}
+
+	// Auto labels describe the target rather than the host. A target without
+	// /etc/hostname is labelled with its name.
+	logger = logger.With("target", target.Name)
+	labels, err := targetCfg.ConstLabels()
+	if err != nil {
+		logger.Warn("Some auto labels could not be determined", "error", err)
+	}
+	if _, ok := labels["hostname"]; !ok && slices.Contains(cfg.AutoLabels, config.AutoLabelHostname) {
+		labels["hostname"] = target.Name
+	}
+
+	m := metrics.NewMetricsWithNaming(cfg.MetricPrefix, metrics.Naming(cfg.Naming), metrics.WithConstLabels(metrics.NewPrometheusBackend(), labels))
+	m.SetBuildInfo(build.Version, build.Commit, build.Date)
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(m.GetCollectors()...)
+
+	return &probeTarget{
+		collector: collector.New(&targetCfg, m, logger),
+		handler:   promhttp.HandlerFor(registry, promhttp.HandlerOpts{}),
+	}
+}
+
+// handleProbe collects and returns the metrics of the target named by the
+// target query parameter, in the style of the blackbox exporter.
+func (s *Server) handleProbe(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("target")
+	if name == "" {
+		http.Error(w, "target parameter is missing", http.StatusBadRequest)
+		return
+	}
+	target, ok := s.targets[name]
+	if !ok {
+		http.Error(w, "unknown target "+name, http.StatusNotFound)
+		return
+	}
+
+	target.mu.Lock()
+	defer target.mu.Unlock()
+
+	target.collector.Collect(r.Context())
+	target.handler.ServeHTTP(w, r)
+}
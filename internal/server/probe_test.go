@@ -0,0 +1,89 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ncecere/apt-exporter/internal/collector"
+	"github.com/ncecere/apt-exporter/internal/config"
+	"github.com/ncecere/apt-exporter/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestProbe(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Two guests, only one of which needs a reboot
+	guests := map[string]string{"web-1": "bash", "web-2": "curl"}
+	for name, pkg := range guests {
+		root := filepath.Join(tmpDir, "machines", name)
+		status := "Package: " + pkg + "\nStatus: install ok installed\nPriority: optional\nSection: utils\nArchitecture: amd64\nVersion: 1.0\n"
+		if err := os.MkdirAll(filepath.Join(root, "var/lib/dpkg"), 0755); err != nil {
+			t.Fatalf("Failed to create root of %s: %v", name, err)
+		}
+		if err := os.WriteFile(filepath.Join(root, "var/lib/dpkg/status"), []byte(status), 0644); err != nil {
+			t.Fatalf("Failed to write dpkg status of %s: %v", name, err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(tmpDir, "machines/web-1/run"), 0755); err != nil {
+		t.Fatalf("Failed to create run directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "machines/web-1/run/reboot-required"), nil, 0644); err != nil {
+		t.Fatalf("Failed to create reboot-required file: %v", err)
+	}
+	// Only web-1 has a hostname, web-2 is labelled with its name
+	if err := os.MkdirAll(filepath.Join(tmpDir, "machines/web-1/etc"), 0755); err != nil {
+		t.Fatalf("Failed to create etc directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "machines/web-1/etc/hostname"), []byte("guest-1\n"), 0644); err != nil {
+		t.Fatalf("Failed to write hostname: %v", err)
+	}
+
+	cfg := &config.Config{
+		DpkgStatusPath:        "/var/lib/dpkg/status",
+		UpdateStampPath:       "/var/lib/apt/periodic/update-success-stamp",
+		RebootRequiredFile:    "/run/reboot-required",
+		CommandTimeoutSeconds: 10,
+		MetricsEndpoint:       "/metrics",
+		MetricPrefix:          "test",
+		AutoLabels:            []string{config.AutoLabelHostname},
+		Targets: []config.Target{
+			{Name: "web-1", RootDir: filepath.Join(tmpDir, "machines/web-1")},
+			{Name: "web-2", RootDir: filepath.Join(tmpDir, "machines/web-2")},
+		},
+	}
+	c := collector.New(cfg, metrics.NewTestMetrics(), slog.New(slog.DiscardHandler))
+	s := New(cfg, c, prometheus.NewRegistry(), BuildInfo{}, slog.New(slog.DiscardHandler))
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+
+	code, body := getBody(t, ts.URL+"/probe?target=web-1")
+	if code != http.StatusOK {
+		t.Fatalf("Expected probe to return 200, got %d", code)
+	}
+	for _, want := range []string{
+		`test_reboot_required{hostname="guest-1"} 1`,
+		`test_packages_installed{architecture="amd64",hostname="guest-1",priority="optional",section="utils"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected probe of web-1 to contain %q, got:\n%s", want, body)
+		}
+	}
+
+	_, body = getBody(t, ts.URL+"/probe?target=web-2")
+	if !strings.Contains(body, `test_reboot_required{hostname="web-2"} 0`) {
+		t.Errorf("Expected probe of web-2 to report no reboot required, got:\n%s", body)
+	}
+
+	if code := get(t, ts.URL+"/probe"); code != http.StatusBadRequest {
+		t.Errorf("Expected probe without target to return 400, got %d", code)
+	}
+	if code := get(t, ts.URL+"/probe?target=unknown"); code != http.StatusNotFound {
+		t.Errorf("Expected probe of an unknown target to return 404, got %d", code)
+	}
+}
@@ -0,0 +1,187 @@
+// Package server provides the HTTP endpoints of the APT exporter.
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ncecere/apt-exporter/internal/collector"
+	"github.com/ncecere/apt-exporter/internal/config"
+	"github.com/ncecere/apt-exporter/internal/sbom"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Server serves the metrics endpoint and the exporter's API.
+type Server struct {
+	cfg       *config.Config
+	collector *collector.Collector
+	build     BuildInfo
+	logger    *slog.Logger
+	mux       *http.ServeMux
+	targets   map[string]*probeTarget
+
+	// mu guards lastManualCollect, used to rate limit manual collections
+	mu                sync.Mutex
+	lastManualCollect time.Time
+}
+
+// New creates a Server exposing the metrics gathered from gatherer.
+func New(cfg *config.Config, c *collector.Collector, gatherer prometheus.Gatherer, build BuildInfo, logger *slog.Logger) *Server {
+	s := &Server{
+		cfg:       cfg,
+		collector: c,
+		build:     build,
+		logger:    logger,
+		mux:       http.NewServeMux(),
+		targets:   make(map[string]*probeTarget, len(cfg.Targets)),
+	}
+	for _, target := range cfg.Targets {
+		s.targets[target.Name] = newProbeTarget(cfg, target, build, logger)
+	}
+
+	if cfg.PrometheusEnabled() {
+		s.mux.Handle(cfg.MetricsEndpoint, promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{}))
+	}
+	s.mux.HandleFunc("POST /-/collect", s.handleCollect)
+	s.mux.HandleFunc("GET /api/v1/status", s.handleStatus)
+	s.mux.HandleFunc("GET /api/v1/sbom", s.handleSBOM)
+	s.mux.HandleFunc("GET /probe", s.handleProbe)
+	s.mux.HandleFunc("/-/healthy", s.handleHealthy)
+	s.mux.HandleFunc("/-/ready", s.handleReady)
+	s.mux.HandleFunc("GET /{$}", s.handleLanding)
+
+	return s
+}
+
+// ServeHTTP dispatches requests to the registered endpoints.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// handleCollect runs a collection cycle synchronously and returns its result.
+// Manual collections are limited to one per manual_collect_min_interval_seconds.
+func (s *Server) handleCollect(w http.ResponseWriter, r *http.Request) {
+	if wait := s.reserveManualCollect(); wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		writeError(w, http.StatusTooManyRequests, "manual collection is rate limited, retry in "+wait.Round(time.Second).String())
+		return
+	}
+
+	s.logger.Info("Manual collection requested", "remote_addr", r.RemoteAddr)
+	writeJSON(w, http.StatusOK, s.collector.Collect(r.Context()))
+}
+
+// handleStatus returns the result of the last collection cycle.
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	status, ok := s.collector.LastStatus()
+	if !ok {
+		writeError(w, http.StatusServiceUnavailable, "no collection has completed yet")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, status)
+}
+
+// handleSBOM returns a bill of materials of the installed packages.
+// The format is selected with ?format=cyclonedx (default) or ?format=spdx.
+func (s *Server) handleSBOM(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = sbom.FormatCycloneDX
+	}
+	if format != sbom.FormatCycloneDX && format != sbom.FormatSPDX {
+		writeError(w, http.StatusBadRequest, "unsupported format "+format+", must be one of: cyclonedx, spdx")
+		return
+	}
+
+	bom, err := sbom.FromSystem(s.cfg.Path(s.cfg.DpkgStatusPath), s.cfg.Path(s.cfg.OSReleasePath), s.cfg.Path(sbom.DefaultDocDir), s.build.Version)
+	if err != nil {
+		s.logger.Error("Error generating SBOM", "error", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Encode to a buffer first so errors result in a proper error response
+	var buf bytes.Buffer
+	if err := bom.Write(&buf, format); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", sbom.ContentType(format))
+	_, _ = buf.WriteTo(w)
+}
+
+// handleHealthy reports that the process is up and serving requests.
+func (s *Server) handleHealthy(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintln(w, "APT exporter is Healthy.")
+}
+
+// handleReady reports whether the collector is producing fresh metrics.
+// The exporter is ready once a collection has succeeded within the last
+// ready_max_intervals check intervals, so a wedged collector loop is detected
+// even though the metrics endpoint keeps serving the last values.
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.collector.LastStatus(); !ok {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintln(w, "APT exporter is not ready: first collection has not completed yet.")
+		return
+	}
+
+	lastSuccess, ok := s.collector.LastSuccess()
+	if !ok {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintln(w, "APT exporter is not ready: no collection has succeeded yet.")
+		return
+	}
+
+	maxAge := time.Duration(s.cfg.ReadyMaxIntervals*s.cfg.CheckIntervalSeconds) * time.Second
+	if age := time.Since(lastSuccess); age > maxAge {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintf(w, "APT exporter is not ready: last successful collection was %s ago (limit %s).\n",
+			age.Round(time.Second), maxAge)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintln(w, "APT exporter is Ready.")
+}
+
+// reserveManualCollect records a manual collection if the rate limit allows it.
+// Otherwise it returns how long the caller has to wait.
+func (s *Server) reserveManualCollect() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	minInterval := time.Duration(s.cfg.ManualCollectMinIntervalSeconds) * time.Second
+	if elapsed := time.Since(s.lastManualCollect); elapsed < minInterval {
+		return minInterval - elapsed
+	}
+
+	s.lastManualCollect = time.Now()
+	return 0
+}
+
+// writeJSON writes v as an indented JSON response.
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+// writeError writes a JSON error response.
+func writeError(w http.ResponseWriter, code int, message string) {
+	writeJSON(w, code, map[string]string{"error": message})
+}
@@ -0,0 +1,263 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ncecere/apt-exporter/internal/collector"
+	"github.com/ncecere/apt-exporter/internal/config"
+	"github.com/ncecere/apt-exporter/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// newTestServer creates a server backed by a collector using a mock apt-check.
+func newTestServer(t *testing.T) (*httptest.Server, *config.Config) {
+	t.Helper()
+
+	tmpDir := t.TempDir()
+	aptCheckPath := filepath.Join(tmpDir, "apt-check")
+	if err := os.WriteFile(aptCheckPath, []byte("#!/bin/sh\necho \"0;0\" >&2\n"), 0755); err != nil {
+		t.Fatalf("Failed to create mock apt-check: %v", err)
+	}
+	updateStampPath := filepath.Join(tmpDir, "update-success-stamp")
+	if err := os.WriteFile(updateStampPath, []byte(""), 0644); err != nil {
+		t.Fatalf("Failed to create mock update stamp: %v", err)
+	}
+
+	statusPath := filepath.Join(tmpDir, "status")
+	status := "Package: bash\nStatus: install ok installed\nArchitecture: amd64\nVersion: 5.1-6ubuntu1\n"
+	if err := os.WriteFile(statusPath, []byte(status), 0644); err != nil {
+		t.Fatalf("Failed to create mock dpkg status: %v", err)
+	}
+
+	cfg := &config.Config{
+		AptCheckPath:                    aptCheckPath,
+		DpkgStatusPath:                  statusPath,
+		OSReleasePath:                   filepath.Join(tmpDir, "os-release"),
+		UpdateStampPath:                 updateStampPath,
+		RebootRequiredFile:              filepath.Join(tmpDir, "reboot-required"),
+		CheckIntervalSeconds:            300,
+		CommandTimeoutSeconds:           10,
+		MetricsEndpoint:                 "/metrics",
+		ManualCollectMinIntervalSeconds: 60,
+		ReadyMaxIntervals:               3,
+	}
+	c := collector.New(cfg, metrics.NewTestMetrics(), slog.New(slog.DiscardHandler))
+	build := BuildInfo{Version: "1.2.3", Commit: "abc123", Date: "2025-03-02"}
+	s := New(cfg, c, prometheus.NewRegistry(), build, slog.New(slog.DiscardHandler))
+
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return ts, cfg
+}
+
+// get performs a GET request and returns the status code.
+func get(t *testing.T, url string) int {
+	t.Helper()
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("Failed to get %s: %v", url, err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestStatusAndCollect(t *testing.T) {
+	ts, cfg := newTestServer(t)
+
+	// No status is available before the first collection
+	resp, err := http.Get(ts.URL + "/api/v1/status")
+	if err != nil {
+		t.Fatalf("Failed to get status: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 before first collection, got %d", resp.StatusCode)
+	}
+
+	// Only POST triggers a collection
+	resp, err = http.Get(ts.URL + "/-/collect")
+	if err != nil {
+		t.Fatalf("Failed to get collect endpoint: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405 for GET /-/collect, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Post(ts.URL+"/-/collect", "", nil)
+	if err != nil {
+		t.Fatalf("Failed to trigger collection: %v", err)
+	}
+	var status collector.Status
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		t.Fatalf("Failed to decode collection result: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200 for collection, got %d", resp.StatusCode)
+	}
+	if !status.Success {
+		t.Errorf("Expected collection to succeed, got %+v", status)
+	}
+	if len(status.Checks) == 0 || status.Checks[0].Name != "updates" {
+		t.Errorf("Expected check results starting with updates, got %+v", status.Checks)
+	}
+
+	// A second manual collection within the interval is rate limited
+	resp, err = http.Post(ts.URL+"/-/collect", "", nil)
+	if err != nil {
+		t.Fatalf("Failed to trigger collection: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429 for rate limited collection, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get("Retry-After") == "" {
+		t.Error("Expected Retry-After header on rate limited collection")
+	}
+
+	// The status endpoint now reports the last cycle
+	resp, err = http.Get(ts.URL + "/api/v1/status")
+	if err != nil {
+		t.Fatalf("Failed to get status: %v", err)
+	}
+	var last collector.Status
+	if err := json.NewDecoder(resp.Body).Decode(&last); err != nil {
+		t.Fatalf("Failed to decode status: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if !last.Timestamp.Equal(status.Timestamp) {
+		t.Errorf("Expected status timestamp %v, got %v", status.Timestamp, last.Timestamp)
+	}
+
+	// A minimum interval of 0 disables the rate limit
+	cfg.ManualCollectMinIntervalSeconds = 0
+	resp, err = http.Post(ts.URL+"/-/collect", "", nil)
+	if err != nil {
+		t.Fatalf("Failed to trigger collection: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200 without rate limit, got %d", resp.StatusCode)
+	}
+}
+
+func TestHealthAndReady(t *testing.T) {
+	ts, cfg := newTestServer(t)
+
+	if code := get(t, ts.URL+"/-/healthy"); code != http.StatusOK {
+		t.Errorf("Expected /-/healthy to return 200, got %d", code)
+	}
+
+	// Not ready before the first collection
+	if code := get(t, ts.URL+"/-/ready"); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected /-/ready to return 503 before first collection, got %d", code)
+	}
+
+	resp, err := http.Post(ts.URL+"/-/collect", "", nil)
+	if err != nil {
+		t.Fatalf("Failed to trigger collection: %v", err)
+	}
+	resp.Body.Close()
+
+	if code := get(t, ts.URL+"/-/ready"); code != http.StatusOK {
+		t.Errorf("Expected /-/ready to return 200 after collection, got %d", code)
+	}
+
+	// Once the last success is older than the allowed intervals, the exporter is no longer ready
+	cfg.CheckIntervalSeconds = 1
+	cfg.ReadyMaxIntervals = 1
+	time.Sleep(1100 * time.Millisecond)
+	if code := get(t, ts.URL+"/-/ready"); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected /-/ready to return 503 for a stale collection, got %d", code)
+	}
+
+	// A completed collection with a failing check does not make it ready again
+	cfg.ManualCollectMinIntervalSeconds = 0
+	cfg.AptCheckPath = filepath.Join(t.TempDir(), "missing-apt-check")
+	resp, err = http.Post(ts.URL+"/-/collect", "", nil)
+	if err != nil {
+		t.Fatalf("Failed to trigger collection: %v", err)
+	}
+	resp.Body.Close()
+	if code := get(t, ts.URL+"/-/ready"); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected /-/ready to return 503 after a failed collection, got %d", code)
+	}
+
+	// Health does not depend on the collector
+	if code := get(t, ts.URL+"/-/healthy"); code != http.StatusOK {
+		t.Errorf("Expected /-/healthy to return 200, got %d", code)
+	}
+}
+
+func TestSBOM(t *testing.T) {
+	ts, _ := newTestServer(t)
+
+	resp, err := http.Get(ts.URL + "/api/v1/sbom")
+	if err != nil {
+		t.Fatalf("Failed to get SBOM: %v", err)
+	}
+	var doc struct {
+		BOMFormat  string `json:"bomFormat"`
+		Components []struct {
+			Name string `json:"name"`
+			PURL string `json:"purl"`
+		} `json:"components"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		t.Fatalf("Failed to decode SBOM: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if doc.BOMFormat != "CycloneDX" {
+		t.Errorf("Expected CycloneDX by default, got %q", doc.BOMFormat)
+	}
+	if len(doc.Components) != 1 || doc.Components[0].PURL != "pkg:deb/debian/bash@5.1-6ubuntu1?arch=amd64" {
+		t.Errorf("Unexpected components %+v", doc.Components)
+	}
+
+	code, body := getBody(t, ts.URL+"/api/v1/sbom?format=spdx")
+	if code != http.StatusOK || !strings.Contains(body, `"spdxVersion": "SPDX-2.3"`) {
+		t.Errorf("Expected SPDX document, got %d: %s", code, body)
+	}
+
+	if code := get(t, ts.URL+"/api/v1/sbom?format=xml"); code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for unsupported format, got %d", code)
+	}
+}
+
+func TestMetricsEndpointOTLPOnly(t *testing.T) {
+	cfg := &config.Config{
+		CheckIntervalSeconds:  300,
+		CommandTimeoutSeconds: 10,
+		MetricsEndpoint:       "/metrics",
+		MetricsBackend:        config.MetricsBackendOTLP,
+	}
+	c := collector.New(cfg, metrics.NewTestMetrics(), slog.New(slog.DiscardHandler))
+	s := New(cfg, c, prometheus.NewRegistry(), BuildInfo{}, slog.New(slog.DiscardHandler))
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	// Metrics are only exported over OTLP, so the endpoint is not served
+	if code := get(t, ts.URL+"/metrics"); code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for the metrics endpoint, got %d", code)
+	}
+	if code := get(t, ts.URL+"/-/healthy"); code != http.StatusOK {
+		t.Errorf("Expected status 200 for /-/healthy, got %d", code)
+	}
+}
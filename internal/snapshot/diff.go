@@ -0,0 +1,97 @@
+package snapshot
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/ncecere/apt-exporter/internal/dpkg"
+)
+
+// ChangeType describes how a package changed between two snapshots.
+type ChangeType string
+
+// Kinds of package changes.
+const (
+	Added      ChangeType = "added"
+	Removed    ChangeType = "removed"
+	Upgraded   ChangeType = "upgraded"
+	Downgraded ChangeType = "downgraded"
+)
+
+// Change is a package that differs between two snapshots.
+type Change struct {
+	Type         ChangeType `json:"type"`
+	Name         string     `json:"name"`
+	Architecture string     `json:"architecture"`
+	OldVersion   string     `json:"old_version,omitempty"`
+	NewVersion   string     `json:"new_version,omitempty"`
+}
+
+// Diff lists the packages that were added, removed, upgraded or downgraded
+// going from snapshot a to snapshot b, sorted by name and architecture.
+// Versions are ordered following dpkg, so a change that dpkg considers
+// equal (e.g. "1.0" and "1.0-0") is not reported.
+func Diff(a, b *Snapshot) []Change {
+	before := make(map[string]Package, len(a.Packages))
+	for _, pkg := range a.Packages {
+		before[pkg.key()] = pkg
+	}
+	after := make(map[string]Package, len(b.Packages))
+	for _, pkg := range b.Packages {
+		after[pkg.key()] = pkg
+	}
+
+	var changes []Change
+	for key, old := range before {
+		pkg, ok := after[key]
+		if !ok {
+			changes = append(changes, Change{Type: Removed, Name: old.Name, Architecture: old.Architecture, OldVersion: old.Version})
+			continue
+		}
+
+		change := Change{Name: pkg.Name, Architecture: pkg.Architecture, OldVersion: old.Version, NewVersion: pkg.Version}
+		switch dpkg.CompareVersions(old.Version, pkg.Version) {
+		case -1:
+			change.Type = Upgraded
+		case 1:
+			change.Type = Downgraded
+		default:
+			continue
+		}
+		changes = append(changes, change)
+	}
+	for key, pkg := range after {
+		if _, ok := before[key]; !ok {
+			changes = append(changes, Change{Type: Added, Name: pkg.Name, Architecture: pkg.Architecture, NewVersion: pkg.Version})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Name != changes[j].Name {
+			return changes[i].Name < changes[j].Name
+		}
+		return changes[i].Architecture < changes[j].Architecture
+	})
+	return changes
+}
+
+// WriteText writes one line per change, e.g.
+// "upgraded    openssl:amd64 3.0.2-0ubuntu1.10 -> 3.0.2-0ubuntu1.12".
+func WriteText(w io.Writer, changes []Change) error {
+	for _, c := range changes {
+		var versions string
+		switch c.Type {
+		case Added:
+			versions = c.NewVersion
+		case Removed:
+			versions = c.OldVersion
+		default:
+			versions = c.OldVersion + " -> " + c.NewVersion
+		}
+		if _, err := fmt.Fprintf(w, "%-11s %s:%s %s\n", c.Type, c.Name, c.Architecture, versions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+// Package snapshot records the set of installed packages and compares
+// snapshots taken at different times, e.g. before and after maintenance.
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/ncecere/apt-exporter/internal/dpkg"
+)
+
+// FormatVersion is the version of the snapshot file format.
+const FormatVersion = 1
+
+// Package is an installed package recorded in a snapshot.
+type Package struct {
+	Name         string `json:"name"`
+	Architecture string `json:"architecture"`
+	Version      string `json:"version"`
+}
+
+// key identifies a package independently of its version. Multi-arch packages
+// can be installed for several architectures at once.
+func (p Package) key() string {
+	return p.Name + ":" + p.Architecture
+}
+
+// Snapshot is the set of packages installed at a point in time.
+type Snapshot struct {
+	FormatVersion int       `json:"format_version"`
+	Hostname      string    `json:"hostname,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	Packages      []Package `json:"packages"`
+}
+
+// New creates a snapshot of the given packages, sorted by name and architecture.
+func New(packages []dpkg.Package, hostname string, createdAt time.Time) *Snapshot {
+	s := &Snapshot{
+		FormatVersion: FormatVersion,
+		Hostname:      hostname,
+		CreatedAt:     createdAt,
+		Packages:      make([]Package, 0, len(packages)),
+	}
+	for _, pkg := range packages {
+		s.Packages = append(s.Packages, Package{Name: pkg.Name, Architecture: pkg.Architecture, Version: pkg.Version})
+	}
+	sort.Slice(s.Packages, func(i, j int) bool {
+		return s.Packages[i].key() < s.Packages[j].key()
+	})
+	return s
+}
+
+// FromSystem snapshots the packages installed according to the dpkg status database.
+func FromSystem(statusPath string) (*Snapshot, error) {
+	packages, err := dpkg.ReadStatus(statusPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// The hostname is informational only
+	hostname, _ := os.Hostname()
+	return New(packages, hostname, time.Now().UTC()), nil
+}
+
+// Load reads a snapshot previously written by Write.
+func Load(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	var s Snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot %s: %w", path, err)
+	}
+	if s.FormatVersion != FormatVersion {
+		return nil, fmt.Errorf("unsupported snapshot format version %d in %s", s.FormatVersion, path)
+	}
+	return &s, nil
+}
+
+// Write writes the snapshot as indented JSON.
+func (s *Snapshot) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
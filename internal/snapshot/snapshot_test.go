@@ -0,0 +1,152 @@
+package snapshot
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ncecere/apt-exporter/internal/dpkg"
+)
+
+func TestSaveAndLoad(t *testing.T) {
+	tmpDir := t.TempDir()
+	statusPath := filepath.Join(tmpDir, "status")
+	status := "Package: openssl\nStatus: install ok installed\nArchitecture: amd64\nVersion: 3.0.2-0ubuntu1.10\n\n" +
+		"Package: libc6\nStatus: install ok installed\nArchitecture: i386\nVersion: 2.35-0ubuntu3.6\n\n" +
+		"Package: libc6\nStatus: install ok installed\nArchitecture: amd64\nVersion: 2.35-0ubuntu3.6\n\n" +
+		"Package: removed\nStatus: deinstall ok config-files\nArchitecture: amd64\nVersion: 1.0\n"
+	if err := os.WriteFile(statusPath, []byte(status), 0644); err != nil {
+		t.Fatalf("Failed to write status file: %v", err)
+	}
+
+	s, err := FromSystem(statusPath)
+	if err != nil {
+		t.Fatalf("FromSystem() returned error: %v", err)
+	}
+
+	expected := []Package{
+		{Name: "libc6", Architecture: "amd64", Version: "2.35-0ubuntu3.6"},
+		{Name: "libc6", Architecture: "i386", Version: "2.35-0ubuntu3.6"},
+		{Name: "openssl", Architecture: "amd64", Version: "3.0.2-0ubuntu1.10"},
+	}
+	if len(s.Packages) != len(expected) {
+		t.Fatalf("Expected %d packages, got %+v", len(expected), s.Packages)
+	}
+	for i, pkg := range expected {
+		if s.Packages[i] != pkg {
+			t.Errorf("Expected package %d to be %+v, got %+v", i, pkg, s.Packages[i])
+		}
+	}
+
+	snapshotPath := filepath.Join(tmpDir, "before.json")
+	var buf bytes.Buffer
+	if err := s.Write(&buf); err != nil {
+		t.Fatalf("Write() returned error: %v", err)
+	}
+	if err := os.WriteFile(snapshotPath, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write snapshot: %v", err)
+	}
+
+	loaded, err := Load(snapshotPath)
+	if err != nil {
+		t.Fatalf("Load() returned error: %v", err)
+	}
+	if !loaded.CreatedAt.Equal(s.CreatedAt) || len(loaded.Packages) != len(s.Packages) {
+		t.Errorf("Expected loaded snapshot to match the saved one, got %+v", loaded)
+	}
+	if changes := Diff(s, loaded); len(changes) != 0 {
+		t.Errorf("Expected no changes between a snapshot and itself, got %+v", changes)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tmpDir := t.TempDir()
+	tests := map[string]string{
+		"invalid.json": "{",
+		"future.json":  `{"format_version": 99, "packages": []}`,
+	}
+	for name, content := range tests {
+		path := filepath.Join(tmpDir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		if _, err := Load(path); err == nil {
+			t.Errorf("Expected error loading %s, got nil", name)
+		}
+	}
+
+	if _, err := Load(filepath.Join(tmpDir, "missing.json")); err == nil {
+		t.Error("Expected error loading a missing snapshot, got nil")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	created := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	a := New([]dpkg.Package{
+		{Name: "openssl", Architecture: "amd64", Version: "3.0.2-0ubuntu1.10"},
+		{Name: "curl", Architecture: "amd64", Version: "7.81.0-1ubuntu1.15"},
+		{Name: "tzdata", Architecture: "all", Version: "2024a-0ubuntu0.22.04"},
+		{Name: "nginx", Architecture: "amd64", Version: "1.18.0-6ubuntu14.4"},
+		{Name: "bash", Architecture: "amd64", Version: "5.1-6ubuntu1"},
+	}, "web-1", created)
+	b := New([]dpkg.Package{
+		{Name: "openssl", Architecture: "amd64", Version: "3.0.2-0ubuntu1.12"},
+		{Name: "tzdata", Architecture: "all", Version: "2024a-0ubuntu0.22.04"},
+		{Name: "nginx", Architecture: "amd64", Version: "1.18.0-6ubuntu14.3"},
+		{Name: "bash", Architecture: "amd64", Version: "5.1-6ubuntu1"},
+		{Name: "bash", Architecture: "i386", Version: "5.1-6ubuntu1"},
+		{Name: "htop", Architecture: "amd64", Version: "3.0.5-7build2"},
+	}, "web-1", created.Add(time.Hour))
+
+	expected := []Change{
+		{Type: Added, Name: "bash", Architecture: "i386", NewVersion: "5.1-6ubuntu1"},
+		{Type: Removed, Name: "curl", Architecture: "amd64", OldVersion: "7.81.0-1ubuntu1.15"},
+		{Type: Added, Name: "htop", Architecture: "amd64", NewVersion: "3.0.5-7build2"},
+		{Type: Downgraded, Name: "nginx", Architecture: "amd64", OldVersion: "1.18.0-6ubuntu14.4", NewVersion: "1.18.0-6ubuntu14.3"},
+		{Type: Upgraded, Name: "openssl", Architecture: "amd64", OldVersion: "3.0.2-0ubuntu1.10", NewVersion: "3.0.2-0ubuntu1.12"},
+	}
+
+	changes := Diff(a, b)
+	if len(changes) != len(expected) {
+		t.Fatalf("Expected %d changes, got %+v", len(expected), changes)
+	}
+	for i, change := range expected {
+		if changes[i] != change {
+			t.Errorf("Expected change %d to be %+v, got %+v", i, change, changes[i])
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := WriteText(&buf, changes[3:]); err != nil {
+		t.Fatalf("WriteText() returned error: %v", err)
+	}
+	want := "downgraded  nginx:amd64 1.18.0-6ubuntu14.4 -> 1.18.0-6ubuntu14.3\n" +
+		"upgraded    openssl:amd64 3.0.2-0ubuntu1.10 -> 3.0.2-0ubuntu1.12\n"
+	if buf.String() != want {
+		t.Errorf("Expected text output:\n%s\ngot:\n%s", want, buf.String())
+	}
+}
+
+func TestDiffVersionOrdering(t *testing.T) {
+	// Debian version ordering rather than string ordering decides the direction
+	tests := []struct {
+		from, to string
+		want     ChangeType
+	}{
+		{"1.9", "1.10", Upgraded},
+		{"1.0~rc1", "1.0", Upgraded},
+		{"1:1.0", "2.0", Downgraded},
+		{"2.0-1", "2.0-1+deb12u1", Upgraded},
+	}
+
+	for _, tt := range tests {
+		a := New([]dpkg.Package{{Name: "pkg", Architecture: "amd64", Version: tt.from}}, "", time.Time{})
+		b := New([]dpkg.Package{{Name: "pkg", Architecture: "amd64", Version: tt.to}}, "", time.Time{})
+		changes := Diff(a, b)
+		if len(changes) != 1 || changes[0].Type != tt.want {
+			t.Errorf("Diff(%s -> %s) = %+v, want %s", tt.from, tt.to, changes, tt.want)
+		}
+	}
+}
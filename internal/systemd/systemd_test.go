@@ -0,0 +1,150 @@
+//go:build unix
+
+package systemd
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// fakeNotifySocket listens on a notify socket and sets NOTIFY_SOCKET to it.
+func fakeNotifySocket(t *testing.T) *net.UnixConn {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("Failed to create notify socket: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	t.Setenv("NOTIFY_SOCKET", path)
+	return conn
+}
+
+// receive returns the next state sent to the notify socket.
+func receive(t *testing.T, conn *net.UnixConn) string {
+	t.Helper()
+
+	buf := make([]byte, 4096)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("Failed to read notification: %v", err)
+	}
+	return string(buf[:n])
+}
+
+func TestNotify(t *testing.T) {
+	conn := fakeNotifySocket(t)
+
+	for _, state := range []string{Ready, Status("Last collection succeeded\nin 0.1s"), Watchdog, Stopping} {
+		sent, err := Notify(state)
+		if err != nil || !sent {
+			t.Fatalf("Failed to notify %q: sent %v, error %v", state, sent, err)
+		}
+	}
+
+	for _, want := range []string{"READY=1", "STATUS=Last collection succeeded in 0.1s", "WATCHDOG=1", "STOPPING=1"} {
+		if got := receive(t, conn); got != want {
+			t.Errorf("Expected %q, got %q", want, got)
+		}
+	}
+}
+
+func TestNotifyWithoutSocket(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+
+	sent, err := Notify(Ready)
+	if sent || err != nil {
+		t.Errorf("Expected no notification without a socket, got sent %v, error %v", sent, err)
+	}
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	pid := strconv.Itoa(os.Getpid())
+	tests := []struct {
+		name     string
+		usec     string
+		pid      string
+		interval time.Duration
+		enabled  bool
+	}{
+		{"Disabled", "", "", 0, false},
+		{"Enabled", "30000000", "", 15 * time.Second, true},
+		{"Enabled for this process", "30000000", pid, 15 * time.Second, true},
+		{"Enabled for another process", "30000000", "1", 0, false},
+		{"Invalid", "soon", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("WATCHDOG_USEC", tt.usec)
+			t.Setenv("WATCHDOG_PID", tt.pid)
+
+			interval, enabled := WatchdogInterval()
+			if interval != tt.interval || enabled != tt.enabled {
+				t.Errorf("Expected %v, %v, got %v, %v", tt.interval, tt.enabled, interval, enabled)
+			}
+		})
+	}
+}
+
+func TestListeners(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer l.Close()
+	f, err := l.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatalf("Failed to get listener file: %v", err)
+	}
+	defer f.Close()
+
+	// Pass a descriptor that is not owned by an *os.File, as systemd would
+	fd, err := syscall.Dup(int(f.Fd()))
+	if err != nil {
+		t.Fatalf("Failed to duplicate descriptor: %v", err)
+	}
+	defer func(start int) { listenFDsStart = start }(listenFDsStart)
+	listenFDsStart = fd
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+	t.Setenv("LISTEN_FDS", "1")
+
+	listeners, err := Listeners()
+	if err != nil {
+		t.Fatalf("Listeners failed: %v", err)
+	}
+	if len(listeners) != 1 {
+		t.Fatalf("Expected 1 listener, got %d", len(listeners))
+	}
+	if _, ok := os.LookupEnv("LISTEN_FDS"); ok {
+		t.Error("Expected LISTEN_FDS to be unset")
+	}
+
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})}
+	go srv.Serve(listeners[0])
+	defer srv.Close()
+
+	resp, err := http.Get("http://" + l.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to reach server on passed socket: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestListenersNotActivated(t *testing.T) {
+	t.Setenv("LISTEN_PID", "1")
+	t.Setenv("LISTEN_FDS", "1")
+
+	listeners, err := Listeners()
+	if err != nil || len(listeners) != 0 {
+		t.Errorf("Expected no listeners for another process, got %d, error %v", len(listeners), err)
+	}
+}
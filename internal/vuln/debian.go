@@ -0,0 +1,80 @@
+package vuln
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// debianIssue is a single issue of a source package in the Debian security tracker JSON.
+type debianIssue struct {
+	Releases map[string]struct {
+		Status       string `json:"status"`
+		FixedVersion string `json:"fixed_version"`
+		Urgency      string `json:"urgency"`
+	} `json:"releases"`
+}
+
+// LoadDebianTracker loads the advisories for a release (e.g. "bookworm") from
+// the Debian security tracker JSON, as served at
+// https://security-tracker.debian.org/tracker/data/json.
+// Only resolved CVEs are included, since unfixed issues have no version to upgrade to.
+func LoadDebianTracker(path, release string) (*Database, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open Debian security tracker data: %w", err)
+	}
+	defer f.Close()
+
+	db := NewDatabase(nil)
+
+	// The file is large, so decode it one source package at a time
+	dec := json.NewDecoder(f)
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, fmt.Errorf("invalid Debian security tracker data in %s: %w", path, err)
+	}
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("invalid Debian security tracker data in %s: %w", path, err)
+		}
+		pkg, _ := token.(string)
+
+		var issues map[string]debianIssue
+		if err := dec.Decode(&issues); err != nil {
+			return nil, fmt.Errorf("invalid Debian security tracker data for %s in %s: %w", pkg, path, err)
+		}
+
+		for id, issue := range issues {
+			if !strings.HasPrefix(id, "CVE-") {
+				continue
+			}
+			r, ok := issue.Releases[release]
+			// A fixed version of "0" means the release was never affected
+			if !ok || r.Status != "resolved" || r.FixedVersion == "" || r.FixedVersion == "0" {
+				continue
+			}
+			db.add(Advisory{
+				Package:      pkg,
+				ID:           id,
+				Severity:     NormalizeSeverity(r.Urgency),
+				FixedVersion: r.FixedVersion,
+			})
+		}
+	}
+
+	return db, nil
+}
+
+// expectDelim consumes the next token and checks that it is the given delimiter.
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := token.(json.Delim); !ok || d != delim {
+		return fmt.Errorf("expected %q, got %v", delim, token)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package vuln
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testDebianTracker = `{
+  "openssl": {
+    "CVE-2023-0464": {
+      "description": "A security vulnerability has been identified in all supported versions of OpenSSL",
+      "scope": "remote",
+      "releases": {
+        "bookworm": {"status": "resolved", "repositories": {"bookworm": "3.0.11-1~deb12u2"}, "fixed_version": "3.0.8-1", "urgency": "low"},
+        "bullseye": {"status": "resolved", "repositories": {"bullseye": "1.1.1w-0+deb11u1"}, "fixed_version": "1.1.1n-0+deb11u5", "urgency": "not yet assigned"}
+      }
+    },
+    "CVE-2023-9999": {
+      "releases": {
+        "bookworm": {"status": "open", "repositories": {"bookworm": "3.0.11-1~deb12u2"}, "urgency": "high"}
+      }
+    },
+    "TEMP-0000000-ABCDEF": {
+      "releases": {
+        "bookworm": {"status": "resolved", "fixed_version": "3.0.9-1", "urgency": "low"}
+      }
+    }
+  },
+  "bash": {
+    "CVE-2019-18276": {
+      "releases": {
+        "bookworm": {"status": "resolved", "fixed_version": "0", "urgency": "unimportant"}
+      }
+    }
+  }
+}`
+
+func TestLoadDebianTracker(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "debian.json")
+	if err := os.WriteFile(path, []byte(testDebianTracker), 0644); err != nil {
+		t.Fatalf("Failed to write tracker data: %v", err)
+	}
+
+	db, err := LoadDebianTracker(path, "bookworm")
+	if err != nil {
+		t.Fatalf("LoadDebianTracker() returned error: %v", err)
+	}
+
+	// Only the resolved CVE with a real fixed version is relevant for bookworm
+	if db.Len() != 1 {
+		t.Fatalf("Expected 1 advisory, got %d", db.Len())
+	}
+	a := db.advisories["openssl"][0]
+	if a.ID != "CVE-2023-0464" || a.FixedVersion != "3.0.8-1" || a.Severity != "low" {
+		t.Errorf("Unexpected advisory %+v", a)
+	}
+
+	db, err = LoadDebianTracker(path, "bullseye")
+	if err != nil {
+		t.Fatalf("LoadDebianTracker() returned error: %v", err)
+	}
+	if a := db.advisories["openssl"][0]; a.Severity != "unknown" || a.FixedVersion != "1.1.1n-0+deb11u5" {
+		t.Errorf("Unexpected bullseye advisory %+v", a)
+	}
+
+	if err := os.WriteFile(path, []byte(`["not", "an", "object"]`), 0644); err != nil {
+		t.Fatalf("Failed to write tracker data: %v", err)
+	}
+	if _, err := LoadDebianTracker(path, "bookworm"); err == nil {
+		t.Error("Expected error for invalid tracker data, got nil")
+	}
+}
@@ -0,0 +1,134 @@
+package vuln
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// osvRecord is the subset of the OSV schema used for matching.
+// See https://ossf.github.io/osv-schema/.
+type osvRecord struct {
+	ID       string `json:"id"`
+	Severity []struct {
+		Type  string `json:"type"`
+		Score string `json:"score"`
+	} `json:"severity"`
+	Affected []struct {
+		Package struct {
+			Ecosystem string `json:"ecosystem"`
+			Name      string `json:"name"`
+		} `json:"package"`
+		Ranges []struct {
+			Type   string `json:"type"`
+			Events []struct {
+				Introduced string `json:"introduced"`
+				Fixed      string `json:"fixed"`
+			} `json:"events"`
+		} `json:"ranges"`
+		EcosystemSpecific struct {
+			Urgency string `json:"urgency"`
+		} `json:"ecosystem_specific"`
+	} `json:"affected"`
+	DatabaseSpecific struct {
+		Severity string `json:"severity"`
+	} `json:"database_specific"`
+}
+
+// LoadOSV loads advisories from a directory of OSV JSON files, such as the
+// Ubuntu or Debian exports of https://osv.dev. Only packages of the given
+// ecosystem (e.g. "Ubuntu:22.04" or "Debian:12") are included; ecosystems with
+// additional qualifiers such as "Ubuntu:22.04:LTS" match as well.
+//
+// Records are expected to describe a single CVE. Advisory bundles such as
+// USN, DSA or DLA records, which repeat the per-CVE data, are skipped.
+func LoadOSV(dir, ecosystem string) (*Database, error) {
+	db := NewDatabase(nil)
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var record osvRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			return fmt.Errorf("invalid OSV record %s: %w", path, err)
+		}
+
+		for _, a := range record.advisories(ecosystem) {
+			db.add(a)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load OSV records from %s: %w", dir, err)
+	}
+
+	return db, nil
+}
+
+// advisories converts the record into advisories for the given ecosystem.
+func (r osvRecord) advisories(ecosystem string) []Advisory {
+	// Per-CVE records are named e.g. UBUNTU-CVE-2023-0464 or DEBIAN-CVE-2023-0464
+	id := strings.TrimPrefix(strings.TrimPrefix(r.ID, "UBUNTU-"), "DEBIAN-")
+	if !strings.HasPrefix(id, "CVE-") {
+		return nil
+	}
+
+	var advisories []Advisory
+	for _, affected := range r.Affected {
+		eco := affected.Package.Ecosystem
+		if eco != ecosystem && !strings.HasPrefix(eco, ecosystem+":") {
+			continue
+		}
+
+		severity := r.severity(affected.EcosystemSpecific.Urgency)
+		for _, rng := range affected.Ranges {
+			if rng.Type != "ECOSYSTEM" {
+				continue
+			}
+
+			introduced := ""
+			for _, event := range rng.Events {
+				if event.Introduced != "" {
+					introduced = event.Introduced
+				}
+				if event.Fixed != "" {
+					advisories = append(advisories, Advisory{
+						Package:      affected.Package.Name,
+						ID:           id,
+						Severity:     severity,
+						Introduced:   introduced,
+						FixedVersion: event.Fixed,
+					})
+				}
+			}
+		}
+	}
+
+	return advisories
+}
+
+// severity determines the distribution severity of the record, preferring the
+// Ubuntu priority, then the Debian urgency and finally the database severity.
+func (r osvRecord) severity(urgency string) string {
+	for _, s := range r.Severity {
+		if s.Type == "Ubuntu" {
+			return NormalizeSeverity(s.Score)
+		}
+	}
+	if urgency != "" {
+		return NormalizeSeverity(urgency)
+	}
+	return NormalizeSeverity(r.DatabaseSpecific.Severity)
+}
@@ -0,0 +1,84 @@
+package vuln
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadOSV(t *testing.T) {
+	dir := t.TempDir()
+	records := map[string]string{
+		"UBUNTU-CVE-2023-0464.json": `{
+  "id": "UBUNTU-CVE-2023-0464",
+  "upstream": ["CVE-2023-0464"],
+  "severity": [{"type": "Ubuntu", "score": "low"}, {"type": "CVSS_V3", "score": "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:N/I:N/A:H"}],
+  "affected": [
+    {
+      "package": {"ecosystem": "Ubuntu:22.04:LTS", "name": "openssl"},
+      "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "3.0.2-0ubuntu1.9"}]}]
+    },
+    {
+      "package": {"ecosystem": "Ubuntu:20.04:LTS", "name": "openssl"},
+      "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "1.1.1f-1ubuntu2.18"}]}]
+    }
+  ]
+}`,
+		"nested/UBUNTU-CVE-2023-1111.json": `{
+  "id": "UBUNTU-CVE-2023-1111",
+  "affected": [
+    {
+      "package": {"ecosystem": "Ubuntu:Pro:22.04:LTS", "name": "curl"},
+      "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "7.81.0-1ubuntu1.14"}]}]
+    },
+    {
+      "package": {"ecosystem": "Ubuntu:22.04:LTS", "name": "curl"},
+      "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}]}]
+    }
+  ],
+  "database_specific": {"severity": "HIGH"}
+}`,
+		"USN-6039-1.json": `{
+  "id": "USN-6039-1",
+  "affected": [
+    {
+      "package": {"ecosystem": "Ubuntu:22.04:LTS", "name": "openssl"},
+      "ranges": [{"type": "ECOSYSTEM", "events": [{"introduced": "0"}, {"fixed": "3.0.2-0ubuntu1.9"}]}]
+    }
+  ]
+}`,
+		"README.md": "not a record",
+	}
+	for name, content := range records {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write record: %v", err)
+		}
+	}
+
+	db, err := LoadOSV(dir, "Ubuntu:22.04")
+	if err != nil {
+		t.Fatalf("LoadOSV() returned error: %v", err)
+	}
+
+	// The USN bundle, other releases, Ubuntu Pro and unfixed ranges are skipped
+	if db.Len() != 1 {
+		t.Fatalf("Expected 1 advisory, got %d: %+v", db.Len(), db.advisories)
+	}
+	a := db.advisories["openssl"][0]
+	want := Advisory{Package: "openssl", ID: "CVE-2023-0464", Severity: "low", Introduced: "0", FixedVersion: "3.0.2-0ubuntu1.9"}
+	if a != want {
+		t.Errorf("Advisory = %+v, want %+v", a, want)
+	}
+
+	db, err = LoadOSV(dir, "Ubuntu:Pro:22.04")
+	if err != nil {
+		t.Fatalf("LoadOSV() returned error: %v", err)
+	}
+	if a := db.advisories["curl"]; len(a) != 1 || a[0].Severity != "high" {
+		t.Errorf("Expected one high severity curl advisory for Ubuntu Pro, got %+v", a)
+	}
+}
@@ -0,0 +1,120 @@
+// Package vuln matches installed packages against locally synced security
+// tracker data, so that vulnerabilities can be reported without network access.
+package vuln
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/ncecere/apt-exporter/internal/dpkg"
+)
+
+// Severities lists the normalized severities in ascending order.
+var Severities = []string{"unknown", "negligible", "low", "medium", "high", "critical"}
+
+// Advisory describes a vulnerability of a source package that is fixed in FixedVersion.
+type Advisory struct {
+	Package  string
+	ID       string
+	Severity string
+
+	// Introduced is the first affected version; empty means all versions before FixedVersion
+	Introduced   string
+	FixedVersion string
+}
+
+// affects reports whether the given version of the package is vulnerable.
+func (a Advisory) affects(version string) bool {
+	if a.Introduced != "" && a.Introduced != "0" && dpkg.CompareVersions(version, a.Introduced) < 0 {
+		return false
+	}
+	return dpkg.CompareVersions(version, a.FixedVersion) < 0
+}
+
+// Finding is a vulnerability affecting an installed source package.
+type Finding struct {
+	Package          string `json:"package"`
+	InstalledVersion string `json:"installed_version"`
+	ID               string `json:"id"`
+	Severity         string `json:"severity"`
+	FixedVersion     string `json:"fixed_version"`
+}
+
+// Database holds advisories indexed by source package name.
+type Database struct {
+	advisories map[string][]Advisory
+}
+
+// NewDatabase creates a database from a list of advisories.
+func NewDatabase(advisories []Advisory) *Database {
+	db := &Database{advisories: make(map[string][]Advisory)}
+	for _, a := range advisories {
+		db.add(a)
+	}
+	return db
+}
+
+// add indexes an advisory by its source package.
+func (db *Database) add(a Advisory) {
+	db.advisories[a.Package] = append(db.advisories[a.Package], a)
+}
+
+// Len returns the number of advisories in the database.
+func (db *Database) Len() int {
+	n := 0
+	for _, advisories := range db.advisories {
+		n += len(advisories)
+	}
+	return n
+}
+
+// Match returns the vulnerabilities affecting the installed packages.
+// Packages are matched by source package and source version, and each
+// vulnerability is reported once per source package even if several binary
+// packages or advisory ranges match.
+func (db *Database) Match(packages []dpkg.Package) []Finding {
+	seen := make(map[string]bool)
+	var findings []Finding
+
+	for _, pkg := range packages {
+		for _, a := range db.advisories[pkg.Source] {
+			key := pkg.Source + "\x00" + pkg.SourceVersion + "\x00" + a.ID
+			if seen[key] || !a.affects(pkg.SourceVersion) {
+				continue
+			}
+			seen[key] = true
+			findings = append(findings, Finding{
+				Package:          pkg.Source,
+				InstalledVersion: pkg.SourceVersion,
+				ID:               a.ID,
+				Severity:         a.Severity,
+				FixedVersion:     a.FixedVersion,
+			})
+		}
+	}
+
+	sort.Slice(findings, func(i, j int) bool {
+		if findings[i].Package != findings[j].Package {
+			return findings[i].Package < findings[j].Package
+		}
+		return findings[i].ID < findings[j].ID
+	})
+	return findings
+}
+
+// NormalizeSeverity maps tracker specific severities onto Severities.
+func NormalizeSeverity(severity string) string {
+	s := strings.ToLower(strings.TrimSpace(strings.TrimRight(severity, "*")))
+	switch s {
+	case "negligible", "unimportant":
+		return "negligible"
+	case "low", "medium", "high", "critical":
+		return s
+	case "moderate":
+		return "medium"
+	case "important":
+		return "high"
+	default:
+		return "unknown"
+	}
+}
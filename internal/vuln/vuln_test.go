@@ -0,0 +1,63 @@
+package vuln
+
+import (
+	"testing"
+
+	"github.com/ncecere/apt-exporter/internal/dpkg"
+)
+
+func TestMatch(t *testing.T) {
+	db := NewDatabase([]Advisory{
+		{Package: "openssl", ID: "CVE-2023-0001", Severity: "high", FixedVersion: "3.0.2-0ubuntu1.11"},
+		{Package: "openssl", ID: "CVE-2022-0002", Severity: "low", FixedVersion: "3.0.2-0ubuntu1.1"},
+		{Package: "openssl", ID: "CVE-2023-0003", Severity: "medium", Introduced: "3.0.5-1", FixedVersion: "3.0.5-2"},
+		{Package: "bash", ID: "CVE-2023-0004", Severity: "medium", FixedVersion: "5.2-1"},
+	})
+
+	packages := []dpkg.Package{
+		// Two binaries of the same source must only be reported once
+		{Name: "libssl3", Source: "openssl", SourceVersion: "3.0.2-0ubuntu1.10"},
+		{Name: "openssl", Source: "openssl", SourceVersion: "3.0.2-0ubuntu1.10"},
+		{Name: "curl", Source: "curl", SourceVersion: "7.81.0-1"},
+	}
+
+	findings := db.Match(packages)
+	if len(findings) != 1 {
+		t.Fatalf("Expected 1 finding, got %d: %+v", len(findings), findings)
+	}
+
+	want := Finding{
+		Package:          "openssl",
+		InstalledVersion: "3.0.2-0ubuntu1.10",
+		ID:               "CVE-2023-0001",
+		Severity:         "high",
+		FixedVersion:     "3.0.2-0ubuntu1.11",
+	}
+	if findings[0] != want {
+		t.Errorf("Finding = %+v, want %+v", findings[0], want)
+	}
+
+	if db.Len() != 4 {
+		t.Errorf("Expected database to hold 4 advisories, got %d", db.Len())
+	}
+}
+
+func TestNormalizeSeverity(t *testing.T) {
+	tests := map[string]string{
+		"high":             "high",
+		"Medium":           "medium",
+		"low**":            "low",
+		"unimportant":      "negligible",
+		"negligible":       "negligible",
+		"not yet assigned": "unknown",
+		"":                 "unknown",
+		"important":        "high",
+		"critical":         "critical",
+	}
+
+	for in, want := range tests {
+		if got := NormalizeSeverity(in); got != want {
+			t.Errorf("NormalizeSeverity(%q) = %q, want %q", in, got, want)
+		}
+	}
+}